@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/logging"
+	"github.com/alchemmist/lazy-tmux/internal/memory"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+)
+
+func runDaemon(base config.Config, args []string, stdout io.Writer) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return runDaemonControl(base, args, stdout)
+	}
+
+	cfg, err := parseDaemonFlags(base, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	logger, closeLog, err := openLog(cfg.Log)
+	if err != nil {
+		return err
+	}
+
+	defer closeLog()
+
+	a := app.New(cfg)
+	a.SetLogger(logger)
+
+	// On SIGHUP the config file is re-read and the same flags applied on top.
+	// Log settings only change on restart.
+	a.SetReloader(func() (config.Config, error) {
+		fresh, err := config.Load(config.Path())
+		if err != nil {
+			return config.Config{}, err
+		}
+
+		return parseDaemonFlags(fresh, args)
+	})
+
+	if err := a.RunDaemon(cfg.SaveInterval); err != nil {
+		logger.Error("daemon failed", "error", err)
+		return fmt.Errorf("run daemon: %w", err)
+	}
+
+	return nil
+}
+
+// openLog opens the rotating log file, or stderr for "-".
+func openLog(cfg config.LogConfig) (*slog.Logger, func(), error) {
+	format, err := logging.ParseFormat(cfg.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	level, err := logging.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.File == "-" {
+		return logging.New(os.Stderr, format, level), func() {}, nil
+	}
+
+	file, err := logging.OpenRotatingFile(cfg.File, cfg.MaxSize, cfg.MaxFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return logging.New(file, format, level), func() { _ = file.Close() }, nil
+}
+
+func parseDaemonFlags(base config.Config, args []string) (config.Config, error) {
+	daemonFlags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	daemonFlags.SetOutput(io.Discard)
+	interval := daemonFlags.Duration("interval", base.SaveInterval, "autosave interval")
+	scrollback := daemonFlags.Bool("scrollback", base.Scrollback.Enabled, "capture shell pane scrollback")
+	scrollbackLines := daemonFlags.Int(
+		"scrollback-lines",
+		base.Scrollback.Lines,
+		"max shell scrollback lines per pane",
+	)
+	compression := daemonFlags.String(
+		"scrollback-compression",
+		base.Scrollback.Compression,
+		"scrollback file codec: gzip|none",
+	)
+	sleepIdle := daemonFlags.Duration(
+		"sleep-idle",
+		base.AutoSleep.IdleAfter,
+		"put detached sessions idle this long to sleep (0 disables)",
+	)
+	sleepExclude := daemonFlags.String(
+		"sleep-exclude",
+		strings.Join(base.AutoSleep.Exclude, ","),
+		"comma-separated session globs never put to sleep",
+	)
+	sleepMem := daemonFlags.String(
+		"sleep-min-available",
+		base.AutoSleep.MinAvailable.String(),
+		"sleep least recently used sessions while available memory is below this (e.g. 10% or 1G)",
+	)
+	logFile := daemonFlags.String("log-file", base.Log.File, "daemon log file (- for stderr)")
+	logFormat := daemonFlags.String("log-format", base.Log.Format, "log format: text|json")
+	logLevel := daemonFlags.String("log-level", base.Log.Level, "log level: debug|info|warn|error")
+	shared := addSharedFlags(daemonFlags, base, true)
+
+	if err := daemonFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			daemonFlags.SetOutput(os.Stdout)
+			daemonFlags.Usage()
+
+			return config.Config{}, err
+		}
+
+		return config.Config{}, fmt.Errorf("parse daemon flags: %w", err)
+	}
+
+	if *scrollback && *scrollbackLines <= 0 {
+		return config.Config{}, fmt.Errorf("daemon requires --scrollback-lines > 0 when --scrollback is enabled")
+	}
+
+	codec, err := store.ParseCodec(*compression)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("parse daemon flags: %w", err)
+	}
+
+	if *sleepIdle < 0 {
+		return config.Config{}, fmt.Errorf("daemon requires --sleep-idle >= 0")
+	}
+
+	minAvailable, err := memory.ParseThreshold(*sleepMem)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("parse daemon flags: --sleep-min-available: %w", err)
+	}
+
+	exclude := splitGlobs(*sleepExclude)
+	if err := config.ValidateSessionGlobs(exclude); err != nil {
+		return config.Config{}, fmt.Errorf("parse daemon flags: --sleep-exclude %w", err)
+	}
+
+	cfg := shared.apply(base)
+	cfg.SaveInterval = *interval
+	cfg.Scrollback.Enabled = *scrollback
+	cfg.Scrollback.Lines = *scrollbackLines
+	cfg.Scrollback.Compression = codec
+	cfg.AutoSleep.IdleAfter = *sleepIdle
+	cfg.AutoSleep.Exclude = exclude
+	cfg.AutoSleep.MinAvailable = minAvailable
+	cfg.Log.File = *logFile
+	cfg.Log.Format = *logFormat
+	cfg.Log.Level = *logLevel
+
+	return cfg, nil
+}
+
+func runDaemonControl(base config.Config, args []string, stdout io.Writer) error {
+	command := args[0]
+
+	controlFlags := flag.NewFlagSet("daemon "+command, flag.ContinueOnError)
+	controlFlags.SetOutput(io.Discard)
+	shared := addSharedFlags(controlFlags, base, true)
+
+	if err := controlFlags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			controlFlags.SetOutput(os.Stdout)
+			controlFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse daemon flags: %w", err)
+	}
+
+	status, err := app.New(shared.apply(base)).ControlDaemon(command)
+	if err != nil {
+		return fmt.Errorf("daemon %s: %w", command, err)
+	}
+
+	switch command {
+	case app.DaemonCmdStatus:
+		printDaemonStatus(stdout, status)
+	case app.DaemonCmdSaveNow:
+		fmt.Fprintf(stdout, "saved %d sessions\n", len(status.Sessions))
+	case app.DaemonCmdPause:
+		fmt.Fprintln(stdout, "daemon paused")
+	case app.DaemonCmdResume:
+		fmt.Fprintln(stdout, "daemon resumed")
+	case app.DaemonCmdStop:
+		fmt.Fprintln(stdout, "daemon stopped")
+	}
+
+	return nil
+}
+
+func printDaemonStatus(w io.Writer, status app.DaemonStatus) {
+	state := "running"
+	if status.Paused {
+		state = "paused"
+	}
+
+	lastSave := "never"
+	if !status.LastSave.IsZero() {
+		lastSave = status.LastSave.Local().Format(time.RFC3339) + " ok"
+		if status.LastError != "" {
+			lastSave = status.LastSave.Local().Format(time.RFC3339) + " error: " + status.LastError
+		}
+	}
+
+	fmt.Fprintf(w, "pid\t%d\n", status.PID)
+	fmt.Fprintf(w, "state\t%s\n", state)
+	fmt.Fprintf(w, "uptime\t%s\n", time.Since(status.StartedAt).Round(time.Second))
+	fmt.Fprintf(w, "interval\t%s\n", status.Interval)
+	fmt.Fprintf(w, "last save\t%s\n", lastSave)
+
+	if status.Total > 0 {
+		fmt.Fprintf(w, "failures\t%d in a row, %d total\n", status.Failures, status.Total)
+	}
+
+	if !status.NextRetry.IsZero() {
+		fmt.Fprintf(w, "next retry\t%s\n", status.NextRetry.Local().Format(time.RFC3339))
+	}
+
+	autoSleep := "off"
+
+	var triggers []string
+	if status.SleepIdle > 0 {
+		triggers = append(triggers, fmt.Sprintf("after %s idle", status.SleepIdle))
+	}
+
+	if status.SleepMem != "" {
+		triggers = append(triggers, "below "+status.SleepMem+" available memory")
+	}
+
+	if len(triggers) > 0 {
+		autoSleep = fmt.Sprintf("%s, %d sessions slept", strings.Join(triggers, " or "), status.Slept)
+	}
+
+	fmt.Fprintf(w, "auto-sleep\t%s\n", autoSleep)
+
+	for _, s := range status.Sessions {
+		result := "ok"
+		if s.Error != "" {
+			result = fmt.Sprintf("%s x%d error: %s", s.Class, s.Failures, s.Error)
+		}
+
+		fmt.Fprintf(
+			w,
+			"session\t%s\t%s\t%s\t%s\n",
+			s.Session,
+			s.SavedAt.Local().Format(time.RFC3339),
+			s.Duration.Round(time.Millisecond),
+			result,
+		)
+	}
+}
+
+// splitGlobs splits the comma separated session globs of --sleep-exclude.
+func splitGlobs(expr string) []string {
+	out := make([]string, 0)
+
+	for _, item := range strings.Split(expr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
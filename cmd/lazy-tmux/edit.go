@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+)
+
+func runEdit(base config.Config, args []string, stdout io.Writer) error {
+	editFlags := flag.NewFlagSet("edit", flag.ContinueOnError)
+	editFlags.SetOutput(io.Discard)
+	session := editFlags.String("session", "", "session to edit")
+	shared := addSharedFlags(editFlags, base, true)
+
+	if err := editFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			editFlags.SetOutput(os.Stdout)
+			editFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse edit flags: %w", err)
+	}
+
+	name := strings.TrimSpace(*session)
+	if name == "" {
+		return errors.New("edit requires --session")
+	}
+
+	a := app.New(shared.apply(base))
+
+	doc, err := a.SessionYAML(name)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "lazy-tmux-edit-*.yaml")
+	if err != nil {
+		return fmt.Errorf("create edit file: %w", err)
+	}
+
+	path := file.Name()
+	defer os.Remove(path)
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close edit file: %w", err)
+	}
+
+	opened := append([]byte(editHeader(name, nil)), doc...)
+
+	for {
+		if err := os.WriteFile(path, opened, 0o600); err != nil {
+			return fmt.Errorf("write edit file: %w", err)
+		}
+
+		if err := runEditor(path); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read edit file: %w", err)
+		}
+
+		if bytes.Equal(edited, opened) {
+			fmt.Fprintf(stdout, "edit of %s cancelled, nothing changed\n", name)
+
+			return nil
+		}
+
+		warnings, err := a.SaveSessionYAML(name, edited)
+		if err != nil {
+			// Reopen the edit with the problems on top; saving it unchanged
+			// cancels.
+			opened = append([]byte(editHeader(name, err)), stripEditHeader(edited)...)
+
+			continue
+		}
+
+		for _, warning := range warnings {
+			fmt.Fprintf(stdout, "warning: %s\n", warning)
+		}
+
+		fmt.Fprintf(stdout, "saved %s\n", name)
+
+		return nil
+	}
+}
+
+// editHeader is the comment block on top of an edited session. Lines that
+// start with "#!" belong to it and are replaced when the file is reopened.
+func editHeader(session string, problem error) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "#! Editing saved session %s. Scrollback is kept for panes whose\n", session)
+	b.WriteString("#! window and pane index do not change. Quit without saving to cancel.\n")
+
+	if problem != nil {
+		b.WriteString("#!\n#! The edit was not saved:\n")
+
+		for line := range strings.SplitSeq(problem.Error(), "\n") {
+			fmt.Fprintf(&b, "#!   %s\n", line)
+		}
+	}
+
+	return b.String()
+}
+
+func stripEditHeader(data []byte) []byte {
+	var out []byte
+
+	for line := range bytes.SplitAfterSeq(data, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("#!")) {
+			out = append(out, line...)
+		}
+	}
+
+	return out
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi. The
+// variable may hold arguments, like "code --wait".
+func runEditor(path string) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+
+	if editor == "" {
+		editor = "vi"
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "lazy-tmux", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run editor %q: %w", editor, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/search"
+)
+
+// errNoMatches makes grep exit with status 1 and no message, like grep(1).
+var errNoMatches = errors.New("no matches")
+
+// errSkipped makes grep exit with status 2 once the sessions it could not
+// read were reported, like grep(1) for unreadable files, so scripts can tell
+// an incomplete search from one without matches.
+var errSkipped = errors.New("some sessions could not be searched")
+
+func runGrep(base config.Config, args []string, stdout, stderr io.Writer) error {
+	grepFlags := flag.NewFlagSet("grep", flag.ContinueOnError)
+	grepFlags.SetOutput(io.Discard)
+	session := grepFlags.String("session", "", "only search this session")
+	contextLines := grepFlags.Int("context", 2, "lines of context around each match")
+	ignoreCase := grepFlags.Bool("i", false, "ignore case")
+	shared := addSharedFlags(grepFlags, base, true)
+
+	// The pattern may come before or after the flags.
+	var pattern string
+
+	err := grepFlags.Parse(args)
+	if err == nil && grepFlags.NArg() > 0 {
+		pattern = grepFlags.Arg(0)
+		err = grepFlags.Parse(grepFlags.Args()[1:])
+	}
+
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			grepFlags.SetOutput(os.Stdout)
+			grepFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse grep flags: %w", err)
+	}
+
+	if pattern == "" || grepFlags.NArg() > 0 {
+		return fmt.Errorf("grep requires exactly one PATTERN")
+	}
+
+	match, err := search.Compile(pattern, *ignoreCase)
+	if err != nil {
+		return err
+	}
+
+	matches, skipped, err := app.New(shared.apply(base)).GrepScrollback(strings.TrimSpace(*session), match, *contextLines)
+	if err != nil {
+		return fmt.Errorf("grep scrollback: %w", err)
+	}
+
+	for _, err := range skipped {
+		_, _ = fmt.Fprintf(stderr, "lazy-tmux: grep: %v\n", err)
+	}
+
+	printGrepMatches(stdout, matches, *contextLines > 0)
+
+	switch {
+	case len(skipped) > 0:
+		return errSkipped
+	case len(matches) == 0:
+		return errNoMatches
+	}
+
+	return nil
+}
+
+// printGrepMatches prints matches like grep -n over several files, naming
+// each pane session:window.pane; context lines use "-" instead of ":".
+func printGrepMatches(w io.Writer, matches []search.Match, separate bool) {
+	last, lastPane := 0, ""
+
+	for _, m := range matches {
+		pane := fmt.Sprintf("%s:%d.%d", m.Session, m.WindowIndex, m.PaneIndex)
+		if separate && lastPane != "" && (pane != lastPane || m.First() > last+1) {
+			fmt.Fprintln(w, "--")
+		}
+
+		for i, line := range m.Before {
+			fmt.Fprintf(w, "%s-%d-%s\n", pane, m.First()+i, line)
+		}
+
+		fmt.Fprintf(w, "%s:%d:%s\n", pane, m.Line, m.Text)
+
+		for i, line := range m.After {
+			fmt.Fprintf(w, "%s-%d-%s\n", pane, m.Line+1+i, line)
+		}
+
+		last, lastPane = m.Last(), pane
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+)
+
+func runList(base config.Config, args []string, stdout io.Writer) error {
+	listFlags := flag.NewFlagSet("list", flag.ContinueOnError)
+	listFlags.SetOutput(io.Discard)
+	format := listFlags.String("format", "", "output format: text, json, tsv or template")
+	tmplText := listFlags.String("template", "", "Go template executed for each session (implies --format template)")
+	sortExpr := listFlags.String("sort", "", "session sort (field[:asc|desc],...) as in the picker")
+	live := listFlags.Bool("live", false, "only running sessions")
+	sleeping := listFlags.Bool("sleeping", false, "only saved sessions that are not running")
+	match := listFlags.String("match", "", "only sessions whose name matches this glob")
+	olderThan := listFlags.Duration("older-than", 0, "only sessions last used longer ago than this")
+	shared := addSharedFlags(listFlags, base, true)
+
+	if err := listFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			listFlags.SetOutput(os.Stdout)
+			listFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse list flags: %w", err)
+	}
+
+	if *live && *sleeping {
+		return errors.New("list: --live and --sleeping are mutually exclusive")
+	}
+
+	if *format == "" {
+		*format = "text"
+		if *tmplText != "" {
+			*format = "template"
+		}
+	}
+
+	var tmpl *template.Template
+
+	switch *format {
+	case "text", "json", "tsv":
+	case "template":
+		if *tmplText == "" {
+			return errors.New("list: --format template requires --template")
+		}
+
+		var err error
+
+		tmpl, err = template.New("list").Funcs(template.FuncMap{"join": strings.Join}).Parse(*tmplText)
+		if err != nil {
+			return fmt.Errorf("parse list template: %w", err)
+		}
+	default:
+		return fmt.Errorf("list: unknown format %q (want text, json, tsv or template)", *format)
+	}
+
+	var keys []app.SessionSortKey
+
+	if strings.TrimSpace(*sortExpr) != "" {
+		opts, err := app.ParsePickerSortOptions(*sortExpr, "")
+		if err != nil {
+			return err
+		}
+
+		keys = opts.Session
+	}
+
+	a := app.New(shared.apply(base))
+
+	sessions, err := a.ListSessions(app.ListFilter{
+		Live:      *live,
+		Sleeping:  *sleeping,
+		Match:     *match,
+		OlderThan: *olderThan,
+	}, keys)
+	if err != nil {
+		return fmt.Errorf("list sessions: %w", err)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(sessions); err != nil {
+			return fmt.Errorf("encode sessions: %w", err)
+		}
+	case "tsv":
+		printListTSV(stdout, sessions)
+	case "template":
+		for _, s := range sessions {
+			if err := tmpl.Execute(stdout, s); err != nil {
+				return fmt.Errorf("execute list template: %w", err)
+			}
+
+			fmt.Fprintln(stdout)
+		}
+	default:
+		for _, record := range sessions {
+			fmt.Fprintf(
+				stdout,
+				"%s\t%s\t%dw/%dp\n",
+				record.SessionName,
+				record.CapturedAt.Local().Format(time.RFC3339),
+				record.Windows,
+				record.Panes,
+			)
+		}
+	}
+
+	return nil
+}
+
+// printListTSV prints one row per session under a header. Times are RFC 3339
+// in UTC; last_accessed is empty for sessions never accessed.
+func printListTSV(w io.Writer, sessions []app.ListedSession) {
+	fmt.Fprintln(w, "name\tlive\tcaptured\tlast_accessed\twindows\tpanes\tpinned\ttags\tfile")
+
+	for _, s := range sessions {
+		accessed := ""
+		if !s.LastAccessed.IsZero() {
+			accessed = s.LastAccessed.UTC().Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%d\t%d\t%t\t%s\t%s\n",
+			s.SessionName,
+			s.Live,
+			s.CapturedAt.UTC().Format(time.RFC3339),
+			accessed,
+			s.Windows,
+			s.Panes,
+			s.Pinned,
+			strings.Join(s.Tags, ","),
+			s.File,
+		)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/logging"
+)
+
+func runLogs(base config.Config, args []string, stdout io.Writer) error {
+	logsFlags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	logsFlags.SetOutput(io.Discard)
+	file := logsFlags.String("file", base.Log.File, "daemon log file")
+	lines := logsFlags.Int("n", 50, "number of lines to print")
+	follow := logsFlags.Bool("f", false, "keep printing new lines")
+
+	if err := logsFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			logsFlags.SetOutput(os.Stdout)
+			logsFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse logs flags: %w", err)
+	}
+
+	tail, err := logging.Tail(*file, *lines)
+	if err != nil && !(*follow && errors.Is(err, os.ErrNotExist)) {
+		return err
+	}
+
+	for _, line := range tail {
+		fmt.Fprintln(stdout, line)
+	}
+
+	if !*follow {
+		return nil
+	}
+
+	var offset int64
+	if info, err := os.Stat(*file); err == nil {
+		offset = info.Size()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return logging.Follow(ctx, *file, offset, stdout, 500*time.Millisecond)
+}
@@ -7,10 +7,11 @@ import (
 	"io"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/alchemmist/lazy-tmux/internal/app"
 	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
 )
 
 var (
@@ -19,8 +20,10 @@ var (
 )
 
 type sharedFlags struct {
-	dataDir *string
-	tmuxBin *string
+	dataDir    *string
+	tmuxBin    *string
+	socketName *string
+	socketPath *string
 }
 
 func main() {
@@ -33,7 +36,10 @@ func runCLI(args []string, stdout, stderr io.Writer) int {
 		return 2
 	}
 
-	cfg := config.Default()
+	cfg, err := loadConfig(args[0], stderr)
+	if err != nil {
+		return writeFatalErr(stderr, err)
+	}
 
 	switch args[0] {
 	case "save":
@@ -61,7 +67,7 @@ func runCLI(args []string, stdout, stderr io.Writer) int {
 
 		return 0
 	case "daemon":
-		if err := runDaemon(cfg, args[1:]); err != nil {
+		if err := runDaemon(cfg, args[1:], stdout); err != nil {
 			return writeFatalErr(stderr, err)
 		}
 
@@ -76,13 +82,86 @@ func runCLI(args []string, stdout, stderr io.Writer) int {
 		setupConfigTo(stdout)
 		return 0
 	case "wakeup":
-		if err := runWakeup(cfg, args[1:]); err != nil {
+		if err := runWakeup(cfg, args[1:], stdout); err != nil {
 			return writeFatalErr(stderr, err)
 		}
 
 		return 0
 	case "sleep":
-		if err := runSleep(cfg, args[1:]); err != nil {
+		if err := runSleep(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "pin":
+		if err := runPin(cfg, args[1:]); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "tag":
+		if err := runTag(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "note":
+		if err := runNote(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "show":
+		if err := runShow(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "edit":
+		if err := runEdit(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "session":
+		if err := runSession(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "window":
+		if err := runWindow(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "grep":
+		if err := runGrep(cfg, args[1:], stdout, stderr); err != nil {
+			switch {
+			case errors.Is(err, errNoMatches):
+				return 1
+			case errors.Is(err, errSkipped):
+				return 2
+			}
+
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "logs":
+		if err := runLogs(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "trash":
+		if err := runTrash(cfg, args[1:], stdout); err != nil {
+			return writeFatalErr(stderr, err)
+		}
+
+		return 0
+	case "migrate":
+		if err := runMigrate(cfg, args[1:], stdout); err != nil {
 			return writeFatalErr(stderr, err)
 		}
 
@@ -95,6 +174,38 @@ func runCLI(args []string, stdout, stderr io.Writer) int {
 	}
 }
 
+// writingCommands change saved sessions or tmux state. They refuse to run on
+// a broken config file, since the defaults could for example save scrollback
+// unencrypted or into another data dir.
+var writingCommands = map[string]bool{
+	"save": true, "picker": true, "bootstrap": true, "daemon": true,
+	"wakeup": true, "sleep": true, "pin": true, "tag": true, "note": true,
+	"edit": true, "session": true, "window": true, "trash": true,
+	"migrate": true,
+}
+
+// loadConfig reads the config file for command. Commands that only read fall
+// back to the defaults, with a warning, when the file is broken.
+func loadConfig(command string, stderr io.Writer) (config.Config, error) {
+	switch command {
+	case "setup", "help", "-h", "--help":
+		return config.Default(), nil
+	}
+
+	cfg, err := config.Load(config.Path())
+	if err == nil {
+		return cfg, nil
+	}
+
+	if writingCommands[command] {
+		return cfg, err
+	}
+
+	_, _ = fmt.Fprintf(stderr, "lazy-tmux: %v; using defaults\n", err)
+
+	return config.Default(), nil
+}
+
 func runSave(base config.Config, args []string) error {
 	saveFlags := flag.NewFlagSet("save", flag.ContinueOnError)
 	saveFlags.SetOutput(io.Discard)
@@ -106,6 +217,11 @@ func runSave(base config.Config, args []string) error {
 		base.Scrollback.Lines,
 		"max shell scrollback lines per pane",
 	)
+	compression := saveFlags.String(
+		"scrollback-compression",
+		base.Scrollback.Compression,
+		"scrollback file codec: gzip|none",
+	)
 	shared := addSharedFlags(saveFlags, base, true)
 
 	if err := saveFlags.Parse(args); err != nil {
@@ -123,13 +239,17 @@ func runSave(base config.Config, args []string) error {
 		return fmt.Errorf("save requires --scrollback-lines > 0 when --scrollback is enabled")
 	}
 
+	codec, err := store.ParseCodec(*compression)
+	if err != nil {
+		return fmt.Errorf("parse save flags: %w", err)
+	}
+
 	cfg := shared.apply(base)
 	cfg.Scrollback.Enabled = *scrollback
 	cfg.Scrollback.Lines = *scrollbackLines
+	cfg.Scrollback.Compression = codec
 	tmuxApp := app.New(cfg)
 
-	var err error
-
 	switch {
 	case *all:
 		err = tmuxApp.SaveAll()
@@ -151,6 +271,7 @@ func runRestore(base config.Config, args []string) error {
 	restoreFlags.SetOutput(io.Discard)
 	session := restoreFlags.String("session", "", "session to restore")
 	switchClient := restoreFlags.Bool("switch", true, "switch active client to restored session")
+	fromHost := restoreFlags.String("from-host", "", "copy the session from another host's namespace first")
 	shared := addSharedFlags(restoreFlags, base, true)
 
 	if err := restoreFlags.Parse(args); err != nil {
@@ -169,8 +290,12 @@ func runRestore(base config.Config, args []string) error {
 	}
 
 	tmuxApp := app.New(shared.apply(base))
+	target := app.PickerTarget{
+		SessionName: strings.TrimSpace(*session),
+		Host:        strings.TrimSpace(*fromHost),
+	}
 
-	if err := tmuxApp.Restore(strings.TrimSpace(*session), *switchClient); err != nil {
+	if err := tmuxApp.RestoreTarget(target, *switchClient); err != nil {
 		return fmt.Errorf("restore session: %w", err)
 	}
 
@@ -184,13 +309,14 @@ func runPicker(base config.Config, args []string) error {
 	sessionSort := pickerFlags.String(
 		"session-sort",
 		"",
-		"session sort keys: field[:asc|desc],... (fields: last-used,captured,name,windows,panes)",
+		"session sort keys: field[:asc|desc],... (fields: last-used,captured,name,windows,panes,pinned,tags)",
 	)
 	windowSort := pickerFlags.String(
 		"window-sort",
 		"",
 		"window sort keys: field[:asc|desc],... (fields: index,name,panes,cmd)",
 	)
+	exportDir := pickerFlags.String("export-dir", base.ExportDir, "directory for sessions exported with Alt-e")
 	shared := addSharedFlags(pickerFlags, base, true)
 
 	if err := pickerFlags.Parse(args); err != nil {
@@ -204,13 +330,23 @@ func runPicker(base config.Config, args []string) error {
 		return fmt.Errorf("parse picker flags: %w", err)
 	}
 
-	tmuxApp := app.New(shared.apply(base))
+	cfg := shared.apply(base)
+	cfg.ExportDir = *exportDir
+	tmuxApp := app.New(cfg)
 
 	sortOpts, err := app.ParsePickerSortOptions(*sessionSort, *windowSort)
 	if err != nil {
 		return fmt.Errorf("parse sort options: %w", err)
 	}
 
+	// Sessions the picker has to skip are logged; without a log file the
+	// picker still runs.
+	if logger, closeLog, err := openLog(cfg.Log); err == nil {
+		defer closeLog()
+
+		tmuxApp.SetLogger(logger)
+	}
+
 	var (
 		target app.PickerTarget
 		selErr error
@@ -261,143 +397,6 @@ func runBootstrap(base config.Config, args []string) error {
 	return nil
 }
 
-func runDaemon(base config.Config, args []string) error {
-	daemonFlags := flag.NewFlagSet("daemon", flag.ContinueOnError)
-	daemonFlags.SetOutput(io.Discard)
-	interval := daemonFlags.Duration("interval", base.SaveInterval, "autosave interval")
-	scrollback := daemonFlags.Bool("scrollback", base.Scrollback.Enabled, "capture shell pane scrollback")
-	scrollbackLines := daemonFlags.Int(
-		"scrollback-lines",
-		base.Scrollback.Lines,
-		"max shell scrollback lines per pane",
-	)
-	shared := addSharedFlags(daemonFlags, base, true)
-
-	if err := daemonFlags.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			daemonFlags.SetOutput(os.Stdout)
-			daemonFlags.Usage()
-
-			return nil
-		}
-
-		return fmt.Errorf("parse daemon flags: %w", err)
-	}
-
-	if *scrollback && *scrollbackLines <= 0 {
-		return fmt.Errorf("daemon requires --scrollback-lines > 0 when --scrollback is enabled")
-	}
-
-	cfg := shared.apply(base)
-	cfg.SaveInterval = *interval
-	cfg.Scrollback.Enabled = *scrollback
-	cfg.Scrollback.Lines = *scrollbackLines
-	a := app.New(cfg)
-
-	if err := a.RunDaemon(*interval); err != nil {
-		return fmt.Errorf("run daemon: %w", err)
-	}
-
-	return nil
-}
-
-func runList(base config.Config, args []string, stdout io.Writer) error {
-	listFlags := flag.NewFlagSet("list", flag.ContinueOnError)
-	listFlags.SetOutput(io.Discard)
-	shared := addSharedFlags(listFlags, base, false)
-
-	if err := listFlags.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			listFlags.SetOutput(os.Stdout)
-			listFlags.Usage()
-
-			return nil
-		}
-
-		return fmt.Errorf("parse list flags: %w", err)
-	}
-
-	a := app.New(shared.apply(base))
-
-	recs, err := a.ListRecords()
-	if err != nil {
-		return fmt.Errorf("list records: %w", err)
-	}
-
-	for _, record := range recs {
-		fmt.Fprintf(
-			stdout,
-			"%s\t%s\t%dw/%dp\n",
-			record.SessionName,
-			record.CapturedAt.Local().Format(time.RFC3339),
-			record.Windows,
-			record.Panes,
-		)
-	}
-
-	return nil
-}
-
-func runWakeup(base config.Config, args []string) error {
-	wakeupFlags := flag.NewFlagSet("wakeup", flag.ContinueOnError)
-	wakeupFlags.SetOutput(io.Discard)
-	session := wakeupFlags.String("session", "", "session to wakeup")
-	shared := addSharedFlags(wakeupFlags, base, true)
-
-	if err := wakeupFlags.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			wakeupFlags.SetOutput(os.Stdout)
-			wakeupFlags.Usage()
-
-			return nil
-		}
-
-		return fmt.Errorf("parse wakeup flags: %w", err)
-	}
-
-	if strings.TrimSpace(*session) == "" {
-		return fmt.Errorf("wakeup requires --session")
-	}
-
-	a := app.New(shared.apply(base))
-
-	if err := a.Wakeup(strings.TrimSpace(*session)); err != nil {
-		return fmt.Errorf("wakeup session: %w", err)
-	}
-
-	return nil
-}
-
-func runSleep(base config.Config, args []string) error {
-	sleepFlags := flag.NewFlagSet("sleep", flag.ContinueOnError)
-	sleepFlags.SetOutput(io.Discard)
-	session := sleepFlags.String("session", "", "session to sleep")
-	shared := addSharedFlags(sleepFlags, base, true)
-
-	if err := sleepFlags.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			sleepFlags.SetOutput(os.Stdout)
-			sleepFlags.Usage()
-
-			return nil
-		}
-
-		return fmt.Errorf("parse sleep flags: %w", err)
-	}
-
-	if strings.TrimSpace(*session) == "" {
-		return fmt.Errorf("sleep requires --session")
-	}
-
-	a := app.New(shared.apply(base))
-
-	if err := a.Sleep(strings.TrimSpace(*session)); err != nil {
-		return fmt.Errorf("sleep session: %w", err)
-	}
-
-	return nil
-}
-
 func usage() {
 	usageTo(os.Stdout)
 }
@@ -415,18 +414,83 @@ Commands:
   sleep      Save and close a running session
   picker     Open session picker and restore selected session (default: TUI)
   bootstrap  Restore one session at tmux startup (default: last)
-  daemon     Periodically save all sessions
-  list       List saved sessions
+  daemon     Periodically save all sessions (status|save-now|pause|resume|stop to control it)
+  list       List saved sessions (--format text|json|tsv|template, --live, --sleeping, --match, --sort)
   setup      Print config keybinds for tmux
+  pin        Pin a session (--off to unpin)
+  tag        Add or remove session tags (--add a,b --remove c --clear)
+  note       Show or set a short session note (--text)
+  session    Rename, delete or create a session (rename|delete|new)
+  window     Rename, delete or create a window (rename|delete|new)
+  trash      List, restore or empty deleted sessions and windows (list|restore|empty)
+  show       Print a saved session as a tree (--session NAME [--json], --pane S:W.P --scrollback)
+  edit       Edit a saved session as YAML in $EDITOR (--session NAME)
+  grep       Search saved scrollback: grep PATTERN [--session NAME] [--context N] [-i]
+  logs       Print the daemon log (-n N lines, -f to follow)
+  migrate    Move snapshots saved before host namespaces into this host's (the daemon does it on start)
+
+Server flags (all commands):
+  --socket-name NAME       tmux server socket name, like tmux -L (default: the server in $TMUX)
+  --socket-path PATH       tmux server socket path, like tmux -S
+                           Snapshots of non-default servers live in DATA_DIR/servers/<server>.
+
+Restore flags:
+  --from-host HOST         Copy a session saved on another host (DATA_DIR/hosts/HOST) and
+                           restore it here; pane paths are rewritten with host.path_map
 
 Picker flags:
   --fzf-engine             Use fzf backend instead of built-in TUI
-  --session-sort EXPR      Session sort (field[:asc|desc],...) fields: last-used,captured,name,windows,panes
+  --session-sort EXPR      Session sort (field[:asc|desc],...) fields: last-used,captured,name,windows,panes,pinned,tags
   --window-sort EXPR       Window sort (field[:asc|desc],...) fields: index,name,panes,cmd
+  --export-dir DIR         Where Alt-e exports sessions as JSON (default: export_dir, or the current directory)
+
+List flags:
+  --format FORMAT          text (default), json, tsv or template
+  --template TPL           Go template per session, e.g. '{{.SessionName}} {{.Live}} {{join .Tags ","}}'
+                           Fields: SessionName File CapturedAt LastAccessed LastUsed Windows Panes
+                           Pinned Tags Note Live
+  --live, --sleeping       Only running, or only saved but not running, sessions
+  --match GLOB             Only sessions whose name matches GLOB
+  --older-than DURATION    Only sessions last used longer ago than DURATION
+  --sort EXPR              Session sort, same fields as --session-sort
+
+Bulk flags (sleep, wakeup, session delete):
+  --all                    Every running (sleep), saved (wakeup) or known (delete) session
+  --match GLOB             Sessions whose name matches GLOB
+  --tag TAG                Sessions tagged TAG
+  --except-attached        Leave out sessions with a client attached
+                           Each session is tried; failures are listed and make the exit status 1.
+
+Session and window flags:
+  --session NAME           Session to change
+  --window N               Window index (window rename|delete)
+  --name NAME              New name (rename, new); a new window without --name gets a default name
+
+Trash flags:
+  --id ID                  Entry to restore (default: latest, or latest of --session)
+  --older-than DURATION    Only empty entries older than DURATION
+
+Configuration:
+  Defaults are read from $LAZY_TMUX_CONFIG or $XDG_CONFIG_HOME/lazy-tmux/config.json
+  (JSON); command-line flags override it. The daemon re-reads it on SIGHUP and
+  saves once more before exiting on SIGTERM/SIGINT.
 
 Save/daemon flags:
   --scrollback             Capture shell pane scrollback (opt-in)
   --scrollback-lines N     Max captured lines per shell pane (default: 5000)
+  --scrollback-compression Scrollback file codec: gzip|none (default: gzip)
+
+Daemon auto-sleep flags:
+  --sleep-idle DURATION    Sleep detached sessions idle this long (default: 0, off)
+  --sleep-min-available N  Sleep least recently used detached sessions while
+                           available memory is below N (10% or 1G; default: off)
+  --sleep-exclude GLOBS    Comma-separated session globs never put to sleep
+                           (pinned and attached sessions are always kept)
+
+Daemon log flags:
+  --log-file PATH          Log file, rotated by size (default: $XDG_STATE_HOME/lazy-tmux/lazy-tmux.log, - for stderr)
+  --log-format FORMAT      text|json (default: text)
+  --log-level LEVEL        debug|info|warn|error (default: info)
 `)
 }
 
@@ -437,7 +501,7 @@ func setupConfig() {
 func setupConfigTo(w io.Writer) {
 	fmt.Fprint(
 		w,
-		`run-shell -b 'lazy-tmux daemon --interval 3m --scrollback>/tmp/lazy-tmux.log 2>&1 `+
+		`run-shell -b 'lazy-tmux daemon --interval 3m --scrollback 2>/dev/null `+
 			`|| tmux display-message "lazy-tmux daemon already running"'
 bind-key f display-popup -w 75% -h 85% -E 'lazy-tmux picker'
 bind-key C-s run-shell 'lazy-tmux save --all --scrollback && tmux display-message "All sessions saved successfully!"'
@@ -469,14 +533,52 @@ func writeFatalErr(writer io.Writer, err error) int {
 	return 1
 }
 
+func flagPassed(fs *flag.FlagSet, name string) bool {
+	passed := false
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+
+	return passed
+}
+
 func addSharedFlags(fs *flag.FlagSet, base config.Config, withTmux bool) sharedFlags {
+	socketName, socketPath := base.TmuxSocketName, base.TmuxSocketPath
 	flags := sharedFlags{
-		dataDir: fs.String("data-dir", base.DataDir, "snapshot directory"),
+		dataDir:    fs.String("data-dir", base.DataDir, "snapshot directory"),
+		socketName: &socketName,
+		socketPath: &socketPath,
 	}
 	if withTmux {
 		flags.tmuxBin = fs.String("tmux-bin", base.TmuxBin, "tmux binary")
 	}
 
+	// The server also selects where its snapshots are stored, so every
+	// command accepts it. The last of the two flags wins.
+	fs.Func("socket-name", "tmux server socket name (like tmux -L)", func(v string) error {
+		server, err := tmux.NewServer(v, "")
+		if err != nil {
+			return err
+		}
+
+		socketName, socketPath = server.SocketName, ""
+
+		return nil
+	})
+	fs.Func("socket-path", "tmux server socket path (like tmux -S)", func(v string) error {
+		server, err := tmux.NewServer("", v)
+		if err != nil {
+			return err
+		}
+
+		socketName, socketPath = "", server.SocketPath
+
+		return nil
+	})
+
 	return flags
 }
 
@@ -490,5 +592,10 @@ func (f sharedFlags) apply(base config.Config) config.Config {
 		cfg.TmuxBin = *f.tmuxBin
 	}
 
+	if f.socketName != nil && f.socketPath != nil {
+		cfg.TmuxSocketName = *f.socketName
+		cfg.TmuxSocketPath = *f.socketPath
+	}
+
 	return cfg
 }
@@ -2,12 +2,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 	"github.com/alchemmist/lazy-tmux/internal/store"
 )
@@ -71,6 +75,21 @@ func TestRunSaveValidatesScrollbackLines(t *testing.T) {
 	}
 }
 
+func TestRunSaveRejectsUnknownCompression(t *testing.T) {
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"save", "--scrollback-compression", "lz4"}, &out, &errOut)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+
+	if !strings.Contains(errOut.String(), `unsupported scrollback codec "lz4"`) {
+		t.Fatalf("unexpected stderr: %s", errOut.String())
+	}
+}
+
 func TestRunSaveAllSuccess(t *testing.T) {
 	dataDir := t.TempDir()
 	fake := writeFakeTmuxCLI(t, `
@@ -247,6 +266,7 @@ exit 0
 	}
 
 	t.Setenv("PATH", fakeFzfDir+":"+os.Getenv("PATH"))
+	t.Setenv("LAZY_TMUX_LOG_FILE", filepath.Join(t.TempDir(), "lazy-tmux.log"))
 
 	var out bytes.Buffer
 
@@ -278,6 +298,70 @@ func TestRunPickerRejectsBadSort(t *testing.T) {
 	}
 }
 
+func TestRunDaemonStatusWithoutDaemon(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	fake := writeFakeTmuxCLI(t, `
+if [ "$1" = "display-message" ]; then
+  echo "/tmp/no-daemon.sock"
+  exit 0
+fi
+exit 0
+`)
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"daemon", "status", "--tmux-bin", fake}, &out, &errOut)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+
+	if !strings.Contains(errOut.String(), "daemon status: daemon not running") {
+		t.Fatalf("unexpected stderr: %s", errOut.String())
+	}
+}
+
+func TestPrintDaemonStatus(t *testing.T) {
+	var out bytes.Buffer
+
+	printDaemonStatus(&out, app.DaemonStatus{
+		PID:       42,
+		StartedAt: time.Now().Add(-time.Minute),
+		Interval:  3 * time.Minute,
+		Paused:    true,
+		LastSave:  time.Now(),
+		LastError: "boom",
+		Failures:  2,
+		Total:     5,
+		NextRetry: time.Now().Add(time.Second),
+		SleepIdle: 2 * time.Hour,
+		SleepMem:  "10%",
+		Slept:     3,
+		Sessions: []app.DaemonSessionStatus{
+			{Session: "alpha", SavedAt: time.Now(), Duration: 12 * time.Millisecond},
+			{Session: "beta", SavedAt: time.Now(), Error: "capture session: gone", Class: "unreachable", Failures: 2},
+		},
+	})
+
+	for _, want := range []string{
+		"pid\t42\n",
+		"state\tpaused\n",
+		"interval\t3m0s\n",
+		"error: boom",
+		"session\talpha\t",
+		"12ms\tok\n",
+		"failures\t2 in a row, 5 total\n",
+		"next retry\t",
+		"auto-sleep\tafter 2h0m0s idle or below 10% available memory, 3 sessions slept\n",
+		"unreachable x2 error: capture session: gone",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in status output:\n%s", want, out.String())
+		}
+	}
+}
+
 func TestRunDaemonValidatesScrollbackLines(t *testing.T) {
 	var out bytes.Buffer
 
@@ -353,6 +437,459 @@ exit 0
 	}
 }
 
+func TestRunTrashListAndRestore(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Name: "main", Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	entry, err := s.TrashSession("demo")
+	if err != nil {
+		t.Fatalf("trash session: %v", err)
+	}
+
+	fake := writeFakeTmuxCLI(t, `
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+exit 0
+`)
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"trash", "list", "--data-dir", dataDir, "--tmux-bin", fake}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if !strings.Contains(out.String(), entry.ID+"\tsession\tdemo") {
+		t.Fatalf("unexpected list output: %q", out.String())
+	}
+
+	out.Reset()
+
+	code = runCLI([]string{"trash", "restore", "--session", "demo", "--data-dir", dataDir, "--tmux-bin", fake}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if _, err := s.LoadSession("demo"); err != nil {
+		t.Fatalf("expected session to be restored, got %v", err)
+	}
+}
+
+func TestRunTagPinAndNote(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Name: "main", Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	fake := writeFakeTmuxCLI(t, `
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+exit 0
+`)
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	shared := []string{"--session", "demo", "--data-dir", dataDir, "--tmux-bin", fake}
+
+	if code := runCLI(append([]string{"tag", "--add", "work,client-x"}, shared...), &out, &errOut); code != 0 {
+		t.Fatalf("tag: expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if strings.TrimSpace(out.String()) != "client-x,work" {
+		t.Fatalf("unexpected tag output: %q", out.String())
+	}
+
+	out.Reset()
+
+	if code := runCLI(append([]string{"tag", "--clear", "--add", "ops, ops"}, shared...), &out, &errOut); code != 0 {
+		t.Fatalf("tag --clear: expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if strings.TrimSpace(out.String()) != "ops" {
+		t.Fatalf("expected the tags to be replaced, got %q", out.String())
+	}
+
+	if code := runCLI(append([]string{"pin"}, shared...), &out, &errOut); code != 0 {
+		t.Fatalf("pin: expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if code := runCLI(append([]string{"note", "--text", "ship it"}, shared...), &out, &errOut); code != 0 {
+		t.Fatalf("note: expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	out.Reset()
+
+	if code := runCLI(append([]string{"note"}, shared...), &out, &errOut); code != 0 {
+		t.Fatalf("note show: expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if strings.TrimSpace(out.String()) != "ship it" {
+		t.Fatalf("unexpected note output: %q", out.String())
+	}
+
+	rec, err := s.Record("demo")
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	if !rec.Pinned {
+		t.Fatal("expected session to be pinned")
+	}
+}
+
+func TestRunGrepPrintsMatchesWithContext(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{{Index: 1, Name: "build", Panes: []snapshot.Pane{{
+			Index:      0,
+			Scrollback: &snapshot.ScrollbackRef{Content: "$ make\ncc main.c\n\x1b[31merror: missing ;\x1b[0m\nmake: *** failed\n"},
+		}}}},
+	}); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"grep", "ERROR", "-i", "--context", "1", "--data-dir", dataDir}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	want := "demo:1.0-2-cc main.c\ndemo:1.0:3:error: missing ;\ndemo:1.0-4-make: *** failed\n"
+	if out.String() != want {
+		t.Fatalf("unexpected grep output:\n%s", out.String())
+	}
+
+	errOut.Reset()
+
+	code = runCLI([]string{"grep", "--session", "demo", "nothing-here", "--data-dir", dataDir}, &out, &errOut)
+	if code != 1 || errOut.Len() != 0 {
+		t.Fatalf("expected a silent exit status 1, got code %d, stderr=%s", code, errOut.String())
+	}
+}
+
+func TestRunGrepSkipsSessionsThatFailToLoad(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	for _, name := range []string{"broken", "demo"} {
+		if err := s.SaveSession(snapshot.SessionSnapshot{
+			Version:     snapshot.FormatVersion,
+			SessionName: name,
+			CapturedAt:  time.Now().UTC(),
+			Windows: []snapshot.Window{{Index: 0, Name: "sh", Panes: []snapshot.Pane{{
+				Index:      0,
+				Scrollback: &snapshot.ScrollbackRef{Content: "deploy done\n"},
+			}}}},
+		}); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	rec, err := s.Record("broken")
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	if err := os.WriteFile(rec.File, []byte("{broken"), 0o600); err != nil {
+		t.Fatalf("corrupt snapshot: %v", err)
+	}
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"grep", "deploy", "--context", "0", "--data-dir", dataDir}, &out, &errOut)
+	if code != 2 {
+		t.Fatalf("expected exit code 2 for an incomplete search, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if out.String() != "demo:0.0:1:deploy done\n" {
+		t.Fatalf("unexpected grep output:\n%s", out.String())
+	}
+
+	if !strings.Contains(errOut.String(), "load session broken") {
+		t.Fatalf("expected the broken session to be reported, got stderr=%s", errOut.String())
+	}
+
+	out.Reset()
+
+	if code := runCLI([]string{"grep", "missing", "--data-dir", dataDir}, &out, &errOut); code != 2 || out.Len() != 0 {
+		t.Fatalf("expected exit code 2 without matches, got %d, stdout=%s", code, out.String())
+	}
+}
+
+func TestRunShowPrintsTreeJSONAndScrollback(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{
+			{Index: 1, Name: "build", Layout: "b25d,80x24,0,0,1", IsActive: true, Panes: []snapshot.Pane{
+				{Index: 0, CurrentPath: "/src", CurrentCmd: "zsh", IsActive: true, Scrollback: &snapshot.ScrollbackRef{Content: "$ make\nok\n"}},
+				{Index: 1, CurrentPath: "/src", CurrentCmd: "nvim", RestoreCmd: "nvim ."},
+			}},
+			{Index: 2, Name: "logs", Panes: []snapshot.Pane{{Index: 0, CurrentPath: "/var/log", CurrentCmd: "tail"}}},
+		},
+	}); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+
+		var out, errOut bytes.Buffer
+
+		if code := runCLI(append([]string{"show", "--data-dir", dataDir}, args...), &out, &errOut); code != 0 {
+			t.Fatalf("show %v: exit %d, stderr=%s", args, code, errOut.String())
+		}
+
+		return out.String()
+	}
+
+	tree := run("--session", "demo")
+	for _, want := range []string{
+		"demo (captured ",
+		"├── window 1: build [active]\n│   │   layout: b25d,80x24,0,0,1\n",
+		"│   ├── pane 0: zsh in /src [active]\n│   │   scrollback: 3 lines, 10B\n",
+		"│   └── pane 1: nvim in /src\n│       restore: nvim .\n",
+		"└── window 2: logs\n",
+	} {
+		if !strings.Contains(tree, want) {
+			t.Fatalf("tree missing %q:\n%s", want, tree)
+		}
+	}
+
+	var doc snapshot.SessionSnapshot
+	if err := json.Unmarshal([]byte(run("--session", "demo", "--json")), &doc); err != nil || len(doc.Windows) != 2 {
+		t.Fatalf("unexpected json: %+v %v", doc, err)
+	}
+
+	if got := run("--pane", "demo:1.0", "--scrollback"); got != "$ make\nok\n" {
+		t.Fatalf("unexpected scrollback: %q", got)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runCLI([]string{"show", "--data-dir", dataDir, "--pane", "demo:3.0", "--scrollback"}, &out, &errOut); code != 1 ||
+		!strings.Contains(errOut.String(), "window 3 not found") {
+		t.Fatalf("expected missing window error, got %d %s", code, errOut.String())
+	}
+}
+
+func TestRunEditReopensOnErrorsAndSaves(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{{Index: 0, Name: "edit", Panes: []snapshot.Pane{
+			{Index: 0, CurrentPath: dataDir, RestoreCmd: "nvim"},
+		}}},
+	}); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	// The first pass breaks the layout, the second one sees the error in the
+	// header and fixes the restore command instead.
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "editor")
+	script := "#!/bin/sh\nset -eu\n" +
+		"if grep -q 'was not saved' \"$1\"; then\n" +
+		"  cp \"$1\" " + filepath.Join(dir, "second") + "\n" +
+		"  sed -i -e 's/restore: nvim$/restore: nvim -S/' -e '/layout:/d' \"$1\"\n" +
+		"else\n" +
+		"  sed -i 's/name: edit/name: edit\\n    layout: tiled-ish/' \"$1\"\n" +
+		"fi\n"
+
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatalf("write editor: %v", err)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	fake := writeFakeTmuxCLI(t, "exit 1")
+
+	var out, errOut bytes.Buffer
+
+	code := runCLI([]string{"edit", "--session", "demo", "--data-dir", dataDir, "--tmux-bin", fake}, &out, &errOut)
+	if code != 0 || !strings.Contains(out.String(), "saved demo") {
+		t.Fatalf("expected save, got %d stdout=%s stderr=%s", code, out.String(), errOut.String())
+	}
+
+	second, err := os.ReadFile(filepath.Join(dir, "second"))
+	if err != nil || !strings.Contains(string(second), "invalid layout") {
+		t.Fatalf("expected the error in the reopened file, got %q %v", second, err)
+	}
+
+	snap, err := s.LoadSession("demo")
+	if err != nil || snap.Windows[0].Panes[0].RestoreCmd != "nvim -S" {
+		t.Fatalf("unexpected snapshot after edit: %+v %v", snap, err)
+	}
+}
+
+func TestRunSessionAndWindowSubcommandsOnSavedSession(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Name: "edit", Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	// No session is running, so every change applies to the snapshot.
+	fake := writeFakeTmuxCLI(t, "exit 1")
+
+	run := func(args ...string) string {
+		t.Helper()
+
+		var out, errOut bytes.Buffer
+
+		args = append(args, "--data-dir", dataDir, "--tmux-bin", fake)
+		if code := runCLI(args, &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d, stderr=%s", args, code, errOut.String())
+		}
+
+		return out.String()
+	}
+
+	if got := run("session", "rename", "--session", "demo", "--name", "work"); got != "renamed session demo to work\n" {
+		t.Fatalf("unexpected rename output: %q", got)
+	}
+
+	run("window", "new", "--session", "work", "--name", "logs")
+	run("window", "rename", "--session", "work", "--window", "1", "--name", "tail")
+	run("window", "delete", "--session", "work", "--window", "0")
+
+	snap, err := s.LoadSession("work")
+	if err != nil {
+		t.Fatalf("load renamed session: %v", err)
+	}
+
+	if len(snap.Windows) != 1 || snap.Windows[0].Index != 1 || snap.Windows[0].Name != "tail" {
+		t.Fatalf("unexpected windows: %+v", snap.Windows)
+	}
+
+	run("session", "delete", "--session", "work")
+
+	if exists, _ := s.SessionExists("work"); exists {
+		t.Fatal("expected work to be deleted")
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runCLI([]string{"window", "rename", "--session", "work", "--name", "x"}, &out, &errOut); code != 1 ||
+		!strings.Contains(errOut.String(), "requires --window") {
+		t.Fatalf("expected missing --window error, got %d %s", code, errOut.String())
+	}
+}
+
+func TestRunBulkReportsPartialFailure(t *testing.T) {
+	var out bytes.Buffer
+
+	err := runBulk(&out, "slept", []string{"a", "b", "c"}, func(session string) error {
+		if session == "b" {
+			return errors.New("session \"b\" is not running")
+		}
+
+		return nil
+	})
+	if err == nil || err.Error() != "1 of 3 sessions failed" {
+		t.Fatalf("expected partial failure error, got %v", err)
+	}
+
+	want := "slept a\nfailed b: session \"b\" is not running\nslept c\nslept 2 of 3 sessions\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunSessionDeleteByMatch(t *testing.T) {
+	dataDir := t.TempDir()
+
+	s := store.New(dataDir)
+	for _, name := range []string{"api-1", "api-2", "web"} {
+		if err := s.SaveSession(snapshot.SessionSnapshot{
+			Version:     snapshot.FormatVersion,
+			SessionName: name,
+			CapturedAt:  time.Now().UTC(),
+			Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+		}); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	fake := writeFakeTmuxCLI(t, `
+if [ "$1" = "list-sessions" ]; then
+  echo "no server running on /tmp/tmux" >&2
+fi
+exit 1
+`)
+
+	var out, errOut bytes.Buffer
+
+	code := runCLI([]string{"session", "delete", "--match", "api-*", "--data-dir", dataDir, "--tmux-bin", fake}, &out, &errOut)
+	if code != 0 || !strings.HasSuffix(out.String(), "deleted 2 of 2 sessions\n") {
+		t.Fatalf("unexpected result %d: stdout=%s stderr=%s", code, out.String(), errOut.String())
+	}
+
+	records, err := s.ListRecords()
+	if err != nil || len(records) != 1 || records[0].SessionName != "web" {
+		t.Fatalf("unexpected records: %+v %v", records, err)
+	}
+}
+
+func TestRunTrashRequiresSubcommand(t *testing.T) {
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	if code := runCLI([]string{"trash"}, &out, &errOut); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+
+	if code := runCLI([]string{"trash", "bogus"}, &out, &errOut); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+}
+
 func writeFakeTmuxCLI(t *testing.T, body string) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -365,3 +902,207 @@ func writeFakeTmuxCLI(t *testing.T, body string) string {
 
 	return path
 }
+
+func TestRunCLIReadsConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := store.New(dir).SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "from-config",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"data_dir": "`+dir+`"}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	t.Setenv("LAZY_TMUX_CONFIG", configPath)
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	if code := runCLI([]string{"list"}, &out, &errOut); code != 0 {
+		t.Fatalf("expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if !strings.Contains(out.String(), "from-config") {
+		t.Fatalf("expected session from configured data dir, got %q", out.String())
+	}
+}
+
+func TestRunCLIInvalidConfigFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"save_interval": 5}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	t.Setenv("LAZY_TMUX_CONFIG", configPath)
+
+	var out, errOut bytes.Buffer
+
+	// Commands that only read fall back to the defaults.
+	if code := runCLI([]string{"list", "--data-dir", t.TempDir()}, &out, &errOut); code != 0 {
+		t.Fatalf("expected list to run with defaults, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if !strings.Contains(errOut.String(), "parse config file") || !strings.Contains(errOut.String(), "using defaults") {
+		t.Fatalf("expected a warning about the config file, got %q", errOut.String())
+	}
+
+	errOut.Reset()
+
+	if code := runCLI([]string{"save", "--all", "--data-dir", t.TempDir()}, &out, &errOut); code != 1 {
+		t.Fatalf("expected save to refuse a broken config, got %d", code)
+	}
+
+	if !strings.Contains(errOut.String(), "parse config file") {
+		t.Fatalf("unexpected stderr: %s", errOut.String())
+	}
+}
+
+func TestRunLogsPrintsLastLines(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "lazy-tmux.log")
+	if err := os.WriteFile(logPath, []byte("one\ntwo\nthree\n"), 0o600); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	if code := runCLI([]string{"logs", "--file", logPath, "-n", "2"}, &out, &errOut); code != 0 {
+		t.Fatalf("expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if out.String() != "two\nthree\n" {
+		t.Fatalf("unexpected logs output: %q", out.String())
+	}
+}
+
+func TestRunLogsMissingFile(t *testing.T) {
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"logs", "--file", filepath.Join(t.TempDir(), "none.log")}, &out, &errOut)
+	if code != 1 || !strings.Contains(errOut.String(), "not found") {
+		t.Fatalf("expected not found error, got code=%d stderr=%s", code, errOut.String())
+	}
+}
+
+func TestRunDaemonRejectsUnknownLogFormat(t *testing.T) {
+	var out bytes.Buffer
+
+	var errOut bytes.Buffer
+
+	code := runCLI([]string{"daemon", "--log-file", "-", "--log-format", "xml"}, &out, &errOut)
+	if code != 1 || !strings.Contains(errOut.String(), `unsupported log format "xml"`) {
+		t.Fatalf("expected log format error, got code=%d stderr=%s", code, errOut.String())
+	}
+}
+
+func TestParseDaemonFlagsAutoSleep(t *testing.T) {
+	base := config.Default()
+	base.AutoSleep.Exclude = []string{"main"}
+
+	cfg, err := parseDaemonFlags(base, []string{"--sleep-idle", "90m", "--sleep-min-available", "1G"})
+	if err != nil {
+		t.Fatalf("parseDaemonFlags error: %v", err)
+	}
+
+	if cfg.AutoSleep.IdleAfter != 90*time.Minute || strings.Join(cfg.AutoSleep.Exclude, ",") != "main" {
+		t.Fatalf("unexpected auto sleep config: %+v", cfg.AutoSleep)
+	}
+
+	if cfg.AutoSleep.MinAvailable.Bytes != 1<<30 {
+		t.Fatalf("unexpected memory threshold: %+v", cfg.AutoSleep.MinAvailable)
+	}
+
+	if _, err := parseDaemonFlags(base, []string{"--sleep-exclude", "ok,[bad"}); err == nil ||
+		!strings.Contains(err.Error(), "--sleep-exclude") {
+		t.Fatalf("expected invalid glob error, got %v", err)
+	}
+}
+
+func TestRunSaveSessionOnOtherServerUsesItsNamespace(t *testing.T) {
+	dataDir := t.TempDir()
+	fake := writeFakeTmuxCLI(t, `
+if [ "$1" != "-L" ] || [ "$2" != "work" ]; then
+  echo "unexpected server: $*" >&2
+  exit 1
+fi
+shift 2
+if [ "$1" = "has-session" ]; then
+  exit 0
+fi
+if [ "$1" = "display-message" ]; then
+  printf "0\0370\n"
+  exit 0
+fi
+if [ "$1" = "list-windows" ]; then
+  printf "0\037main\037layout\0371\n"
+  exit 0
+fi
+if [ "$1" = "list-panes" ]; then
+  printf "0\037/tmp\037zsh\0371\037111\037\n"
+  exit 0
+fi
+exit 0
+`)
+
+	var out, errOut bytes.Buffer
+
+	code := runCLI([]string{
+		"save", "--session", "demo",
+		"--socket-path", "/tmp/ignored.sock", "--socket-name", "work",
+		"--data-dir", dataDir, "--tmux-bin", fake,
+	}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d, stderr=%s", code, errOut.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, "servers", "work", "sessions", "demo.json")); err != nil {
+		t.Fatalf("expected snapshot in the server namespace: %v", err)
+	}
+
+	code = runCLI([]string{"save", "--session", "demo", "--socket-name", "a/b"}, &out, &errOut)
+	if code != 1 || !strings.Contains(errOut.String(), "invalid tmux socket name") {
+		t.Fatalf("expected invalid socket name error, got %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunMigrateMovesLegacySnapshotsOnce(t *testing.T) {
+	dataDir := t.TempDir()
+
+	if err := store.New(dataDir).SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "legacy",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	t.Setenv("LAZY_TMUX_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	t.Setenv("LAZY_TMUX_PROFILE", "laptop")
+
+	for _, want := range []string{"moved snapshots into the host namespace", "nothing to migrate"} {
+		var out, errOut bytes.Buffer
+
+		if code := runCLI([]string{"migrate", "--data-dir", dataDir}, &out, &errOut); code != 0 {
+			t.Fatalf("migrate: exit %d, stderr=%s", code, errOut.String())
+		}
+
+		if strings.TrimSpace(out.String()) != want {
+			t.Fatalf("expected %q, got %q", want, out.String())
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, "hosts", "laptop", "sessions", "legacy.json")); err != nil {
+		t.Fatalf("expected the session in the host namespace: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +12,13 @@ import (
 	"github.com/alchemmist/lazy-tmux/internal/store"
 )
 
+// TestMain detaches the tests from any tmux server they are run in, which
+// would otherwise namespace the snapshots they inspect.
+func TestMain(m *testing.M) {
+	os.Unsetenv("TMUX")
+	os.Exit(m.Run())
+}
+
 func TestRunNoArgs(t *testing.T) {
 	var out bytes.Buffer
 
@@ -128,6 +137,76 @@ func TestRunListPrintsSavedRecords(t *testing.T) {
 	}
 }
 
+func TestRunListFormats(t *testing.T) {
+	dir := t.TempDir()
+	st := store.New(dir)
+
+	for _, name := range []string{"alpha", "beta"} {
+		if err := st.SaveSession(snapshot.SessionSnapshot{
+			Version:     snapshot.FormatVersion,
+			SessionName: name,
+			CapturedAt:  time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+			Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+		}); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	if err := st.SetSessionMeta("beta", snapshot.SessionMeta{Tags: []string{"work", "api"}}); err != nil {
+		t.Fatalf("set meta: %v", err)
+	}
+
+	fake := writeFakeTmuxCLI(t, `
+if [ "$1" = "list-sessions" ]; then
+  echo "beta"
+fi
+exit 0
+`)
+
+	run := func(args ...string) string {
+		t.Helper()
+
+		var out, errOut bytes.Buffer
+
+		args = append([]string{"list", "--data-dir", dir, "--tmux-bin", fake}, args...)
+		if code := runCLI(args, &out, &errOut); code != 0 {
+			t.Fatalf("list %v: exit %d, stderr=%s", args, code, errOut.String())
+		}
+
+		return out.String()
+	}
+
+	var listed []map[string]any
+	if err := json.Unmarshal([]byte(run("--format", "json", "--sort", "name")), &listed); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+
+	if len(listed) != 2 || listed[0]["session_name"] != "alpha" || listed[0]["live"] != false ||
+		listed[1]["live"] != true || listed[1]["file"] == "" {
+		t.Fatalf("unexpected json: %+v", listed)
+	}
+
+	tsv := strings.Split(strings.TrimSpace(run("--format", "tsv", "--live")), "\n")
+	if len(tsv) != 2 || !strings.HasPrefix(tsv[0], "name\tlive\t") || !strings.HasPrefix(tsv[1], "beta\ttrue\t") ||
+		!strings.Contains(tsv[1], "\tapi,work\t") {
+		t.Fatalf("unexpected tsv: %q", tsv)
+	}
+
+	if got := run("--template", `{{.SessionName}}:{{.Live}}:{{join .Tags "+"}}`, "--match", "b*"); got != "beta:true:api+work\n" {
+		t.Fatalf("unexpected template output: %q", got)
+	}
+
+	if got := run("--sleeping"); !strings.HasPrefix(got, "alpha\t") || strings.Contains(got, "beta") {
+		t.Fatalf("unexpected sleeping output: %q", got)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runCLI([]string{"list", "--data-dir", dir, "--format", "xml"}, &out, &errOut); code != 1 ||
+		!strings.Contains(errOut.String(), "unknown format") {
+		t.Fatalf("expected unknown format error, got %d %s", code, errOut.String())
+	}
+}
+
 func TestRunWakeupRequiresSession(t *testing.T) {
 	var out bytes.Buffer
 
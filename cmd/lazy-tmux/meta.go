@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+)
+
+func runPin(base config.Config, args []string) error {
+	pinFlags := flag.NewFlagSet("pin", flag.ContinueOnError)
+	pinFlags.SetOutput(io.Discard)
+	session := pinFlags.String("session", "", "session to pin")
+	off := pinFlags.Bool("off", false, "unpin the session")
+	shared := addSharedFlags(pinFlags, base, true)
+
+	if err := pinFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			pinFlags.SetOutput(os.Stdout)
+			pinFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse pin flags: %w", err)
+	}
+
+	if strings.TrimSpace(*session) == "" {
+		return fmt.Errorf("pin requires --session")
+	}
+
+	a := app.New(shared.apply(base))
+
+	if err := a.PinSession(strings.TrimSpace(*session), !*off); err != nil {
+		return fmt.Errorf("pin session: %w", err)
+	}
+
+	return nil
+}
+
+func runTag(base config.Config, args []string, stdout io.Writer) error {
+	tagFlags := flag.NewFlagSet("tag", flag.ContinueOnError)
+	tagFlags.SetOutput(io.Discard)
+	session := tagFlags.String("session", "", "session to tag")
+	addTags := tagFlags.String("add", "", "comma separated tags to add")
+	removeTags := tagFlags.String("remove", "", "comma separated tags to remove")
+	clearTags := tagFlags.Bool("clear", false, "remove all tags before adding")
+	shared := addSharedFlags(tagFlags, base, true)
+
+	if err := tagFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			tagFlags.SetOutput(os.Stdout)
+			tagFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse tag flags: %w", err)
+	}
+
+	if strings.TrimSpace(*session) == "" {
+		return fmt.Errorf("tag requires --session")
+	}
+
+	a := app.New(shared.apply(base))
+	name := strings.TrimSpace(*session)
+
+	add, remove := store.ParseTags(*addTags), store.ParseTags(*removeTags)
+
+	if *clearTags {
+		// One write replaces the tags, so a failure cannot leave none.
+		tags := slices.DeleteFunc(add, func(tag string) bool { return slices.Contains(remove, tag) })
+		if err := a.SetSessionTags(name, tags); err != nil {
+			return fmt.Errorf("tag session: %w", err)
+		}
+
+		fmt.Fprintln(stdout, strings.Join(tags, ","))
+
+		return nil
+	}
+
+	tags, err := a.TagSession(name, add, remove)
+	if err != nil {
+		return fmt.Errorf("tag session: %w", err)
+	}
+
+	fmt.Fprintln(stdout, strings.Join(tags, ","))
+
+	return nil
+}
+
+func runNote(base config.Config, args []string, stdout io.Writer) error {
+	noteFlags := flag.NewFlagSet("note", flag.ContinueOnError)
+	noteFlags.SetOutput(io.Discard)
+	session := noteFlags.String("session", "", "session to annotate")
+	text := noteFlags.String("text", "", "note text (empty clears the note)")
+	shared := addSharedFlags(noteFlags, base, true)
+
+	if err := noteFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			noteFlags.SetOutput(os.Stdout)
+			noteFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse note flags: %w", err)
+	}
+
+	if strings.TrimSpace(*session) == "" {
+		return fmt.Errorf("note requires --session")
+	}
+
+	a := app.New(shared.apply(base))
+	name := strings.TrimSpace(*session)
+
+	if !flagPassed(noteFlags, "text") {
+		meta, err := a.SessionMeta(name)
+		if err != nil {
+			return err
+		}
+
+		if meta.Note != "" {
+			fmt.Fprintln(stdout, meta.Note)
+		}
+
+		return nil
+	}
+
+	if err := a.SetSessionNote(name, *text); err != nil {
+		return fmt.Errorf("set note: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+)
+
+func runMigrate(base config.Config, args []string, stdout io.Writer) error {
+	migrateFlags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	migrateFlags.SetOutput(io.Discard)
+	shared := addSharedFlags(migrateFlags, base, false)
+
+	if err := migrateFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			migrateFlags.SetOutput(os.Stdout)
+			migrateFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse migrate flags: %w", err)
+	}
+
+	a := app.New(shared.apply(base))
+
+	moved, err := a.MigrateHostData()
+	if err != nil {
+		return err
+	}
+
+	if moved {
+		_, _ = fmt.Fprintln(stdout, "moved snapshots into the host namespace")
+	} else {
+		_, _ = fmt.Fprintln(stdout, "nothing to migrate")
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+)
+
+func runSession(base config.Config, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("session requires a subcommand: rename, delete or new")
+	}
+
+	sessionFlags := flag.NewFlagSet("session "+args[0], flag.ContinueOnError)
+	sessionFlags.SetOutput(io.Discard)
+	session := sessionFlags.String("session", "", "session to rename or delete")
+	name := sessionFlags.String("name", "", "new session name (rename, new)")
+	sel := addSelectorFlags(sessionFlags)
+	shared := addSharedFlags(sessionFlags, base, true)
+
+	if err := sessionFlags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			sessionFlags.SetOutput(os.Stdout)
+			sessionFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse session flags: %w", err)
+	}
+
+	a := app.New(shared.apply(base))
+	target := strings.TrimSpace(*session)
+	newName := strings.TrimSpace(*name)
+
+	switch args[0] {
+	case "rename":
+		if target == "" || newName == "" {
+			return errors.New("session rename requires --session and --name")
+		}
+
+		if err := a.RenameSession(target, newName); err != nil {
+			return fmt.Errorf("rename session %s: %w", target, err)
+		}
+
+		fmt.Fprintf(stdout, "renamed session %s to %s\n", target, newName)
+	case "delete":
+		if !sel.IsZero() {
+			if target != "" {
+				return errors.New("session delete: use either --session or --all/--match/--tag")
+			}
+
+			sessions, err := a.SelectSessions(*sel, true, true)
+			if err != nil {
+				return err
+			}
+
+			return runBulk(stdout, "deleted", sessions, a.DeleteSession)
+		}
+
+		if target == "" {
+			return errors.New("session delete requires --session")
+		}
+
+		if err := a.DeleteSession(target); err != nil {
+			return fmt.Errorf("delete session %s: %w", target, err)
+		}
+
+		fmt.Fprintf(stdout, "deleted session %s\n", target)
+	case "new":
+		if newName == "" {
+			newName = target
+		}
+
+		if newName == "" {
+			return errors.New("session new requires --name")
+		}
+
+		if err := a.NewSession(newName); err != nil {
+			return fmt.Errorf("new session %s: %w", newName, err)
+		}
+
+		fmt.Fprintf(stdout, "created session %s\n", newName)
+	default:
+		return fmt.Errorf("unknown session subcommand: %s", args[0])
+	}
+
+	return nil
+}
+
+func runWindow(base config.Config, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("window requires a subcommand: rename, delete or new")
+	}
+
+	windowFlags := flag.NewFlagSet("window "+args[0], flag.ContinueOnError)
+	windowFlags.SetOutput(io.Discard)
+	session := windowFlags.String("session", "", "session of the window")
+	index := windowFlags.Int("window", -1, "window index (rename, delete)")
+	name := windowFlags.String("name", "", "window name (rename, new)")
+	shared := addSharedFlags(windowFlags, base, true)
+
+	if err := windowFlags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			windowFlags.SetOutput(os.Stdout)
+			windowFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse window flags: %w", err)
+	}
+
+	target := strings.TrimSpace(*session)
+	if target == "" {
+		return fmt.Errorf("window %s requires --session", args[0])
+	}
+
+	a := app.New(shared.apply(base))
+
+	switch args[0] {
+	case "rename":
+		if *index < 0 || strings.TrimSpace(*name) == "" {
+			return errors.New("window rename requires --window and --name")
+		}
+
+		if err := a.RenameWindow(target, *index, *name); err != nil {
+			return fmt.Errorf("rename window %s:%d: %w", target, *index, err)
+		}
+
+		fmt.Fprintf(stdout, "renamed window %s:%d to %s\n", target, *index, *name)
+	case "delete":
+		if *index < 0 {
+			return errors.New("window delete requires --window")
+		}
+
+		if err := a.DeleteWindow(target, *index); err != nil {
+			return fmt.Errorf("delete window %s:%d: %w", target, *index, err)
+		}
+
+		fmt.Fprintf(stdout, "deleted window %s:%d\n", target, *index)
+	case "new":
+		if err := a.NewWindow(target, *name); err != nil {
+			return fmt.Errorf("new window in %s: %w", target, err)
+		}
+
+		fmt.Fprintf(stdout, "created window in %s\n", target)
+	default:
+		return fmt.Errorf("unknown window subcommand: %s", args[0])
+	}
+
+	return nil
+}
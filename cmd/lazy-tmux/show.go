@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/memory"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func runShow(base config.Config, args []string, stdout io.Writer) error {
+	showFlags := flag.NewFlagSet("show", flag.ContinueOnError)
+	showFlags.SetOutput(io.Discard)
+	session := showFlags.String("session", "", "session to show")
+	asJSON := showFlags.Bool("json", false, "print the snapshot as JSON, scrollback included")
+	paneTarget := showFlags.String("pane", "", "pane to show as session:window.pane")
+	scrollback := showFlags.Bool("scrollback", false, "print the saved scrollback of --pane")
+	shared := addSharedFlags(showFlags, base, false)
+
+	if err := showFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			showFlags.SetOutput(os.Stdout)
+			showFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse show flags: %w", err)
+	}
+
+	a := app.New(shared.apply(base))
+
+	if *scrollback {
+		if *paneTarget == "" {
+			return errors.New("show --scrollback requires --pane session:window.pane")
+		}
+
+		name, windowIndex, paneIndex, err := parsePaneTarget(*paneTarget)
+		if err != nil {
+			return err
+		}
+
+		content, err := a.PaneScrollback(name, windowIndex, paneIndex)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(stdout, content); err != nil {
+			return fmt.Errorf("write scrollback: %w", err)
+		}
+
+		return nil
+	}
+
+	name := strings.TrimSpace(*session)
+	if name == "" && *paneTarget != "" {
+		pane, _, _, err := parsePaneTarget(*paneTarget)
+		if err != nil {
+			return err
+		}
+
+		name = pane
+	}
+
+	if name == "" {
+		return errors.New("show requires --session")
+	}
+
+	snap, err := a.LoadSession(name)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		data, err := app.SnapshotJSON(snap)
+		if err != nil {
+			return err
+		}
+
+		_, err = stdout.Write(data)
+
+		return err
+	}
+
+	printSnapshotTree(stdout, snap)
+
+	return nil
+}
+
+// parsePaneTarget splits "session:window.pane". The session name may itself
+// contain colons; the last one separates the window.
+func parsePaneTarget(target string) (string, int, int, error) {
+	colon := strings.LastIndex(target, ":")
+	if colon <= 0 {
+		return "", 0, 0, fmt.Errorf("invalid pane %q: want session:window.pane", target)
+	}
+
+	windowPart, panePart, ok := strings.Cut(target[colon+1:], ".")
+	if !ok {
+		return "", 0, 0, fmt.Errorf("invalid pane %q: want session:window.pane", target)
+	}
+
+	windowIndex, err := strconv.Atoi(windowPart)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid window index in %q: %w", target, err)
+	}
+
+	paneIndex, err := strconv.Atoi(panePart)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid pane index in %q: %w", target, err)
+	}
+
+	return target[:colon], windowIndex, paneIndex, nil
+}
+
+func printSnapshotTree(w io.Writer, snap snapshot.SessionSnapshot) {
+	panes := 0
+	for _, win := range snap.Windows {
+		panes += len(win.Panes)
+	}
+
+	fmt.Fprintf(w, "%s (captured %s, %dw/%dp)\n",
+		snap.SessionName, snap.CapturedAt.Local().Format(time.RFC3339), len(snap.Windows), panes)
+
+	for wi, win := range snap.Windows {
+		branch, indent := treeBranch(wi == len(snap.Windows)-1, "")
+
+		fmt.Fprintf(w, "%swindow %d: %s%s\n", branch, win.Index, win.Name, treeFlags(win.IsActive, win.Zoomed))
+
+		if win.Layout != "" {
+			fmt.Fprintf(w, "%s│   layout: %s\n", indent, win.Layout)
+		}
+
+		for pi, pane := range win.Panes {
+			paneBranch, paneIndent := treeBranch(pi == len(win.Panes)-1, indent)
+
+			fmt.Fprintf(w, "%spane %d: %s in %s%s\n",
+				paneBranch, pane.Index, pane.CurrentCmd, pane.CurrentPath, treeFlags(pane.IsActive, false))
+
+			if pane.Title != "" {
+				fmt.Fprintf(w, "%stitle: %s\n", paneIndent, pane.Title)
+			}
+
+			if pane.CommandRedacted {
+				fmt.Fprintf(w, "%srestore: %s (redacted, not run)\n", paneIndent, pane.RestoreCmd)
+			} else if pane.RestoreCmd != "" {
+				fmt.Fprintf(w, "%srestore: %s\n", paneIndent, pane.RestoreCmd)
+			}
+
+			if pane.Scrollback != nil && pane.Scrollback.Content != "" {
+				fmt.Fprintf(w, "%sscrollback: %s\n", paneIndent, scrollbackSummary(pane))
+			}
+		}
+	}
+}
+
+func treeBranch(last bool, indent string) (string, string) {
+	if last {
+		return indent + "└── ", indent + "    "
+	}
+
+	return indent + "├── ", indent + "│   "
+}
+
+func treeFlags(active, zoomed bool) string {
+	var flags []string
+	if active {
+		flags = append(flags, "active")
+	}
+
+	if zoomed {
+		flags = append(flags, "zoomed")
+	}
+
+	if len(flags) == 0 {
+		return ""
+	}
+
+	return " [" + strings.Join(flags, ", ") + "]"
+}
+
+func scrollbackSummary(pane snapshot.Pane) string {
+	ref := pane.Scrollback
+
+	lines := ref.Lines
+	if lines == 0 {
+		lines = strings.Count(strings.TrimRight(ref.Content, "\n"), "\n") + 1
+	}
+
+	size := ref.Bytes
+	if size == 0 {
+		size = len(ref.Content)
+	}
+
+	summary := fmt.Sprintf("%d lines, %s", lines, memory.FormatBytes(uint64(size)))
+	if ref.Encrypted {
+		summary += ", encrypted"
+	}
+
+	if pane.Redactions > 0 {
+		summary += fmt.Sprintf(", %d redacted", pane.Redactions)
+	}
+
+	return summary
+}
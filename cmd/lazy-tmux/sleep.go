@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+)
+
+func runWakeup(base config.Config, args []string, stdout io.Writer) error {
+	wakeupFlags := flag.NewFlagSet("wakeup", flag.ContinueOnError)
+	wakeupFlags.SetOutput(io.Discard)
+	session := wakeupFlags.String("session", "", "session to wakeup")
+	sel := addSelectorFlags(wakeupFlags)
+	shared := addSharedFlags(wakeupFlags, base, true)
+
+	if err := wakeupFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			wakeupFlags.SetOutput(os.Stdout)
+			wakeupFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse wakeup flags: %w", err)
+	}
+
+	a := app.New(shared.apply(base))
+
+	if !sel.IsZero() {
+		if strings.TrimSpace(*session) != "" {
+			return errors.New("wakeup: use either --session or --all/--match/--tag")
+		}
+
+		sessions, err := a.SelectSessions(*sel, false, true)
+		if err != nil {
+			return err
+		}
+
+		return runBulk(stdout, "woke up", sessions, a.Wakeup)
+	}
+
+	if strings.TrimSpace(*session) == "" {
+		return fmt.Errorf("wakeup requires --session")
+	}
+
+	if err := a.Wakeup(strings.TrimSpace(*session)); err != nil {
+		return fmt.Errorf("wakeup session: %w", err)
+	}
+
+	return nil
+}
+
+func runSleep(base config.Config, args []string, stdout io.Writer) error {
+	sleepFlags := flag.NewFlagSet("sleep", flag.ContinueOnError)
+	sleepFlags.SetOutput(io.Discard)
+	session := sleepFlags.String("session", "", "session to sleep")
+	sel := addSelectorFlags(sleepFlags)
+	shared := addSharedFlags(sleepFlags, base, true)
+
+	if err := sleepFlags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			sleepFlags.SetOutput(os.Stdout)
+			sleepFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse sleep flags: %w", err)
+	}
+
+	a := app.New(shared.apply(base))
+
+	if !sel.IsZero() {
+		if strings.TrimSpace(*session) != "" {
+			return errors.New("sleep: use either --session or --all/--match/--tag")
+		}
+
+		sessions, err := a.SelectSessions(*sel, true, false)
+		if err != nil {
+			return err
+		}
+
+		return runBulk(stdout, "slept", sessions, a.Sleep)
+	}
+
+	if strings.TrimSpace(*session) == "" {
+		return fmt.Errorf("sleep requires --session")
+	}
+
+	if err := a.Sleep(strings.TrimSpace(*session)); err != nil {
+		return fmt.Errorf("sleep session: %w", err)
+	}
+
+	return nil
+}
+
+// addSelectorFlags registers the flags that select sessions for a bulk
+// command.
+func addSelectorFlags(fs *flag.FlagSet) *app.SessionSelector {
+	sel := &app.SessionSelector{}
+
+	fs.BoolVar(&sel.All, "all", false, "every session")
+	fs.StringVar(&sel.Match, "match", "", "sessions whose name matches this glob")
+	fs.StringVar(&sel.Tag, "tag", "", "sessions with this tag")
+	fs.BoolVar(&sel.ExceptAttached, "except-attached", false, "leave out sessions with a client attached")
+
+	return sel
+}
+
+// runBulk runs op for every session and prints a line per session and a
+// summary. Failures do not stop the others; the returned error counts them.
+func runBulk(stdout io.Writer, done string, sessions []string, op func(string) error) error {
+	if len(sessions) == 0 {
+		fmt.Fprintln(stdout, "no sessions selected")
+
+		return nil
+	}
+
+	failed := 0
+
+	for _, result := range app.Bulk(sessions, op) {
+		if result.Err != nil {
+			failed++
+
+			fmt.Fprintf(stdout, "failed %s: %v\n", result.Session, result.Err)
+
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s %s\n", done, result.Session)
+	}
+
+	fmt.Fprintf(stdout, "%s %d of %d sessions\n", done, len(sessions)-failed, len(sessions))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sessions failed", failed, len(sessions))
+	}
+
+	return nil
+}
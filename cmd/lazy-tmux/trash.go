@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/app"
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func runTrash(base config.Config, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("trash requires a subcommand: list, restore or empty")
+	}
+
+	trashFlags := flag.NewFlagSet("trash "+args[0], flag.ContinueOnError)
+	trashFlags.SetOutput(io.Discard)
+	id := trashFlags.String("id", "", "trash entry id (restore)")
+	session := trashFlags.String("session", "", "restore the latest entry of this session")
+	olderThan := trashFlags.Duration("older-than", 0, "only remove entries older than this (empty)")
+	shared := addSharedFlags(trashFlags, base, true)
+
+	if err := trashFlags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			trashFlags.SetOutput(os.Stdout)
+			trashFlags.Usage()
+
+			return nil
+		}
+
+		return fmt.Errorf("parse trash flags: %w", err)
+	}
+
+	a := app.New(shared.apply(base))
+
+	switch args[0] {
+	case "list":
+		entries, err := a.ListTrash()
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			target := entry.SessionName
+			if entry.Kind == snapshot.TrashKindWindow {
+				target = fmt.Sprintf("%s:%d %s", entry.SessionName, entry.WindowIndex, entry.WindowName)
+			}
+
+			fmt.Fprintf(
+				stdout,
+				"%s\t%s\t%s\t%s\n",
+				entry.ID,
+				entry.Kind,
+				strings.TrimSpace(target),
+				entry.DeletedAt.Local().Format(time.RFC3339),
+			)
+		}
+
+		return nil
+	case "restore":
+		target := strings.TrimSpace(*id)
+		if target == "" {
+			latest, err := a.LatestTrashID(*session)
+			if err != nil {
+				return err
+			}
+
+			target = latest
+		}
+
+		entry, err := a.RestoreTrash(target)
+		if err != nil {
+			return fmt.Errorf("restore trash entry: %w", err)
+		}
+
+		fmt.Fprintf(stdout, "restored %s %s\n", entry.Kind, entry.SessionName)
+
+		return nil
+	case "empty":
+		removed, err := a.EmptyTrash(*olderThan)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stdout, "removed %d trash entries\n", removed)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown trash subcommand: %s", args[0])
+	}
+}
@@ -3,12 +3,16 @@ package app
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/logging"
+	"github.com/alchemmist/lazy-tmux/internal/memory"
+	"github.com/alchemmist/lazy-tmux/internal/redact"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 	"github.com/alchemmist/lazy-tmux/internal/store"
 	"github.com/alchemmist/lazy-tmux/internal/tmux"
@@ -18,35 +22,156 @@ type App struct {
 	cfg       config.Config
 	store     *store.Store
 	tmux      *tmux.Client
+	server    tmux.Server
+	host      string
 	saveAllFn func() error
+	reload    func() (config.Config, error)
+	logger    *slog.Logger
+	mem       memory.Sampler
+	// redactor masks secrets in captured snapshots; nil when disabled.
+	redactor *redact.Redactor
+	// hostDataMoved is set once this App moved legacy snapshots into its host
+	// namespace.
+	hostDataMoved bool
 }
 
 func New(cfg config.Config) *App {
-	return &App{
-		cfg:   cfg,
-		store: store.New(cfg.DataDir),
-		tmux:  tmux.NewClient(cfg.TmuxBin),
+	a := &App{}
+	a.applyConfig(cfg)
+
+	return a
+}
+
+// SetReloader sets how the daemon re-reads its configuration on SIGHUP.
+func (a *App) SetReloader(reload func() (config.Config, error)) {
+	a.reload = reload
+}
+
+// SetLogger sets the logger used by the app and its store and tmux client.
+func (a *App) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+
+	if a.store != nil {
+		a.store.SetLogger(a.log().With("component", "store"))
+	}
+
+	if a.tmux != nil {
+		a.tmux.SetLogger(a.log().With("component", "tmux"))
 	}
 }
 
+func (a *App) log() *slog.Logger {
+	return logging.Or(a.logger)
+}
+
+func (a *App) applyConfig(cfg config.Config) {
+	a.cfg = cfg
+	a.server = serverFor(cfg)
+	a.host = hostFor(cfg)
+
+	root := hostDataDir(cfg.DataDir, a.host)
+
+	a.store = store.NewWithOptions(serverDataDir(root, a.server), store.Options{
+		Codec:      cfg.Scrollback.Compression,
+		Logger:     a.log().With("component", "store"),
+		Encryption: storeEncryption(cfg.Encryption),
+	})
+	// A server taken from $TMUX needs no flags: tmux talks to it already.
+	a.tmux = tmux.NewClientForServer(cfg.TmuxBin, configuredServer(cfg))
+	a.tmux.SetOptionNames(cfg.CaptureOptions)
+	a.tmux.SetEnvironment(cfg.Environment)
+	a.tmux.SetScrollbackReplay(cfg.Scrollback.Replay)
+	a.tmux.SetLogger(a.log().With("component", "tmux"))
+
+	a.redactor = nil
+	if cfg.Redact.Enabled {
+		redactor, err := redact.New(cfg.Redact.Patterns)
+		if err != nil {
+			a.log().Warn("ignoring redact patterns", "error", err)
+			redactor, _ = redact.New(nil)
+		}
+
+		a.redactor = redactor
+	}
+
+	// Move legacy snapshots before any command reads or writes the host
+	// namespace. A failed move leaves no marker, so the daemon and `lazy-tmux
+	// migrate` try again and report the error.
+	a.hostDataMoved = false
+	if _, err := a.MigrateHostData(); err != nil {
+		a.log().Warn("moving snapshots into host namespace failed", "host", a.host, "error", err)
+	}
+}
+
+// storeEncryption returns the store settings for cfg. Without a key source,
+// reading encrypted data fails with store.ErrNoKey.
+func storeEncryption(cfg config.EncryptionConfig) store.Encryption {
+	enc := store.Encryption{Scrollback: cfg.Scrollback, Sessions: cfg.Sessions}
+	if cfg.HasKey() {
+		enc.Key = func() ([]byte, error) {
+			return store.LoadKey(cfg.KeyFile, cfg.KeyEnv, cfg.KeyCommand)
+		}
+	}
+
+	return enc
+}
+
 func (a *App) SaveAll() error {
+	return a.saveAll(nil)
+}
+
+// saveAll saves every running session, reporting each result to observe. A
+// failed session does not stop the others; sessions closed while they were
+// captured are skipped, and once tmux is unreachable the rest are not tried.
+func (a *App) saveAll(observe func(session string, took time.Duration, err error)) error {
 	sessions, err := a.tmux.ListSessions()
 	if err != nil {
 		return fmt.Errorf("list sessions: %w", err)
 	}
 
+	var errs []error
+
 	for _, name := range sessions {
-		if err := a.SaveSession(name); err != nil {
-			return err
+		started := time.Now()
+		err := a.SaveSession(name)
+
+		if observe != nil {
+			observe(name, time.Since(started), err)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		class := classifySaveError(err)
+		if class == saveErrorVanished {
+			a.log().Debug("session vanished during save", "session", name, "error", err)
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("session %s: %w", name, err))
+
+		if class == saveErrorUnreachable {
+			break
 		}
 	}
 
-	return nil
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return fmt.Errorf("%d of %d sessions failed to save: %w", len(errs), len(sessions), errors.Join(errs...))
+	}
 }
 
 func (a *App) SaveSession(session string) error {
+	started := time.Now()
+
 	snap, err := a.tmux.CaptureSession(session)
 	if err != nil {
+		a.log().Warn("session capture failed", "session", session, "error", err)
 		return fmt.Errorf("capture session: %w", err)
 	}
 
@@ -54,13 +179,74 @@ func (a *App) SaveSession(session string) error {
 		a.captureShellScrollback(&snap)
 	}
 
+	a.redactSnapshot(&snap)
+
 	if err := a.store.SaveSession(snap); err != nil {
+		a.log().Error("session save failed", "session", session, "error", err)
 		return fmt.Errorf("save session: %w", err)
 	}
 
+	a.log().Info(
+		"session saved",
+		"session", session,
+		"windows", len(snap.Windows),
+		"duration", time.Since(started),
+	)
+
 	return nil
 }
 
+// redactSnapshot masks secrets before the snapshot reaches the disk and logs
+// how many were found in each pane.
+func (a *App) redactSnapshot(snap *snapshot.SessionSnapshot) {
+	if a.redactor == nil {
+		return
+	}
+
+	rest := a.redactor.Session(snap)
+
+	for _, w := range snap.Windows {
+		for _, p := range w.Panes {
+			if p.Redactions > 0 {
+				a.log().Info("secrets redacted", "session", snap.SessionName, "window", w.Index, "pane", p.Index,
+					"count", p.Redactions)
+
+				rest -= p.Redactions
+			}
+		}
+	}
+
+	if rest > 0 {
+		a.log().Info("secrets redacted", "session", snap.SessionName, "environment", rest)
+	}
+}
+
+// reportRedactedCommands tells the attached clients about restored panes left
+// at their shell because their command had secrets masked.
+func (a *App) reportRedactedCommands(session string, windows []snapshot.Window) {
+	skipped := 0
+
+	for _, w := range windows {
+		for _, p := range w.Panes {
+			if p.CommandRedacted {
+				a.log().Warn("restore command not run, it was redacted", "session", session, "window", w.Index,
+					"pane", p.Index, "command", p.RestoreCmd)
+
+				skipped++
+			}
+		}
+	}
+
+	if skipped == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("lazy-tmux: %s: %d pane(s) left at the shell, their commands held redacted secrets", session, skipped)
+	if err := a.tmux.DisplayMessage(msg); err != nil {
+		a.log().Warn("redacted command notification failed", "session", session, "error", err)
+	}
+}
+
 func (a *App) SaveCurrent() error {
 	name, err := a.tmux.CurrentSession()
 	if err != nil {
@@ -70,12 +256,12 @@ func (a *App) SaveCurrent() error {
 	return a.SaveSession(name)
 }
 
-func (a *App) runDaemonSaveAll() error {
+func (a *App) runDaemonSaveAll(observe func(session string, took time.Duration, err error)) error {
 	if a.saveAllFn != nil {
 		return a.saveAllFn()
 	}
 
-	return a.SaveAll()
+	return a.saveAll(observe)
 }
 
 func (a *App) Restore(session string, switchClient bool) error {
@@ -88,16 +274,36 @@ func (a *App) RestoreTarget(target PickerTarget, switchClient bool) error {
 		return fmt.Errorf("empty session name")
 	}
 
+	if target.Host != "" && target.Host != a.host {
+		if err := a.importFromHost(target); err != nil {
+			return err
+		}
+
+		target.Host, target.Server = "", ""
+	}
+
+	if server := tmux.ParseServer(target.Server); target.Server != "" && !a.isCurrentServer(server) {
+		return a.restoreOnServer(server, target, switchClient)
+	}
+
 	snap, err := a.store.LoadSession(session)
 	if err != nil {
 		return fmt.Errorf("load session: %w", err)
 	}
 
+	started := time.Now()
+
 	err = a.tmux.RestoreSession(snap)
 	if err != nil && err != tmux.ErrSessionExists {
+		a.log().Error("session restore failed", "session", session, "error", err)
 		return fmt.Errorf("restore session: %w", err)
 	}
 
+	if err == nil {
+		a.log().Info("session restored", "session", session, "duration", time.Since(started))
+		a.reportRedactedCommands(session, snap.Windows)
+	}
+
 	if switchClient {
 		switchTarget := session
 		if target.WindowIndex != nil {
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+)
+
+// SessionSelector picks the sessions of a bulk command. Match and Tag both
+// have to hold when set; All selects every candidate.
+type SessionSelector struct {
+	All bool
+	// Match is a glob matched against the session name, as in path.Match.
+	Match string
+	// Tag selects saved sessions with this tag.
+	Tag string
+	// ExceptAttached leaves out running sessions with a client attached.
+	ExceptAttached bool
+}
+
+// IsZero reports whether sel selects nothing.
+func (sel SessionSelector) IsZero() bool {
+	return !sel.All && sel.Match == "" && sel.Tag == ""
+}
+
+// SelectSessions returns the names, sorted, of the sessions sel selects among
+// the running ones (live) and the saved ones that are not running (sleeping).
+func (a *App) SelectSessions(sel SessionSelector, live, sleeping bool) ([]string, error) {
+	if sel.IsZero() {
+		return nil, errors.New("select sessions with --all, --match or --tag")
+	}
+
+	if sel.Match != "" {
+		if _, err := path.Match(sel.Match, ""); err != nil {
+			return nil, fmt.Errorf("invalid match pattern %q: %w", sel.Match, err)
+		}
+	}
+
+	infos, err := a.tmux.ListSessionInfo()
+	if err != nil && classifySaveError(err) != saveErrorUnreachable {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	records, err := a.ListRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string][]string, len(records))
+	for _, rec := range records {
+		tags[rec.SessionName] = rec.Tags
+	}
+
+	running := make(map[string]bool, len(infos))
+	selected := make([]string, 0)
+
+	keep := func(name string) bool {
+		if sel.Match != "" {
+			if ok, _ := path.Match(sel.Match, name); !ok {
+				return false
+			}
+		}
+
+		return sel.Tag == "" || slices.Contains(tags[name], sel.Tag)
+	}
+
+	for _, info := range infos {
+		running[info.Name] = true
+
+		if live && keep(info.Name) && !(sel.ExceptAttached && info.Attached > 0) {
+			selected = append(selected, info.Name)
+		}
+	}
+
+	if sleeping {
+		for _, rec := range records {
+			if !running[rec.SessionName] && keep(rec.SessionName) {
+				selected = append(selected, rec.SessionName)
+			}
+		}
+	}
+
+	sort.Strings(selected)
+
+	return selected, nil
+}
+
+// BulkResult is the outcome of a bulk command for one session.
+type BulkResult struct {
+	Session string
+	Err     error
+}
+
+// Bulk runs op for every session, going on after failures.
+func Bulk(sessions []string, op func(session string) error) []BulkResult {
+	results := make([]BulkResult, 0, len(sessions))
+
+	for _, session := range sessions {
+		results = append(results, BulkResult{Session: session, Err: op(session)})
+	}
+
+	return results
+}
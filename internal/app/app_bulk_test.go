@@ -0,0 +1,82 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestSelectSessions(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "list-sessions" ]; then
+  printf 'api-1\0371700000000\0371\n'
+  printf 'api-2\0371700000000\0370\n'
+  printf 'scratch\0371700000000\0370\n'
+  exit 0
+fi
+exit 0
+`)
+
+	app := New(config.Config{DataDir: t.TempDir(), TmuxBin: fake})
+	app.tmux = tmux.NewClient(fake)
+
+	for _, name := range []string{"api-1", "api-3", "web"} {
+		if err := app.store.SaveSession(snapshot.SessionSnapshot{
+			Version:     snapshot.FormatVersion,
+			SessionName: name,
+			CapturedAt:  time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC),
+			Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+		}); err != nil {
+			t.Fatalf("save session %q: %v", name, err)
+		}
+	}
+
+	if _, err := app.TagSession("web", []string{"front"}, nil); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name           string
+		sel            SessionSelector
+		live, sleeping bool
+		want           []string
+	}{
+		{name: "all live", sel: SessionSelector{All: true}, live: true, want: []string{"api-1", "api-2", "scratch"}},
+		{name: "except attached", sel: SessionSelector{All: true, ExceptAttached: true}, live: true, want: []string{"api-2", "scratch"}},
+		{name: "match sleeping", sel: SessionSelector{Match: "api-*"}, sleeping: true, want: []string{"api-3"}},
+		{name: "match both", sel: SessionSelector{Match: "api-*"}, live: true, sleeping: true, want: []string{"api-1", "api-2", "api-3"}},
+		{name: "tag", sel: SessionSelector{Tag: "front"}, live: true, sleeping: true, want: []string{"web"}},
+	} {
+		got, err := app.SelectSessions(tc.sel, tc.live, tc.sleeping)
+		if err != nil {
+			t.Fatalf("%s: SelectSessions: %v", tc.name, err)
+		}
+
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := app.SelectSessions(SessionSelector{}, true, true); err == nil {
+		t.Fatal("expected error for an empty selector")
+	}
+}
+
+func TestBulkGoesOnAfterFailures(t *testing.T) {
+	results := Bulk([]string{"a", "b", "c"}, func(session string) error {
+		if session == "b" {
+			return errors.New("boom")
+		}
+
+		return nil
+	})
+
+	if len(results) != 3 || results[0].Err != nil || results[1].Err == nil || results[2].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
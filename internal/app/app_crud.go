@@ -11,32 +11,46 @@ import (
 )
 
 func (a *App) DeleteWindow(session string, windowIndex int) error {
+	_, err := a.deleteWindow(session, windowIndex)
+	return err
+}
+
+func (a *App) deleteWindow(session string, windowIndex int) (snapshot.TrashEntry, error) {
 	if a.tmux.SessionExists(session) {
+		entry, err := a.trashLiveWindow(session, windowIndex)
+		if err != nil {
+			return snapshot.TrashEntry{}, err
+		}
+
 		if err := a.tmux.KillWindow(session, windowIndex); err != nil {
+			if entry.ID != "" {
+				_ = a.store.RemoveTrash(entry.ID)
+			}
+
 			var exitErr *exec.ExitError
 			if !errors.As(err, &exitErr) {
-				return fmt.Errorf("kill window: %w", err)
+				return snapshot.TrashEntry{}, fmt.Errorf("kill window: %w", err)
 			}
 		} else {
 			if !a.tmux.SessionExists(session) {
 				if err := a.store.DeleteSession(session); err != nil {
-					return fmt.Errorf("delete session: %w", err)
+					return entry, fmt.Errorf("delete session: %w", err)
 				}
 
-				return nil
+				return entry, nil
 			}
 
-			return a.SaveSession(session)
+			return entry, a.SaveSession(session)
 		}
 	}
 
 	snap, err := a.store.LoadSession(session)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("session %q not found: %w", session, os.ErrNotExist)
+			return snapshot.TrashEntry{}, fmt.Errorf("session %q not found: %w", session, os.ErrNotExist)
 		}
 
-		return fmt.Errorf("load session: %w", err)
+		return snapshot.TrashEntry{}, fmt.Errorf("load session: %w", err)
 	}
 
 	windows := make([]snapshot.Window, 0, len(snap.Windows))
@@ -52,38 +66,77 @@ func (a *App) DeleteWindow(session string, windowIndex int) error {
 	}
 
 	if !removed {
-		return fmt.Errorf("window not found in snapshot")
+		return snapshot.TrashEntry{}, fmt.Errorf("window not found in snapshot")
+	}
+
+	entry, err := a.store.TrashWindow(snap, windowIndex)
+	if err != nil {
+		return snapshot.TrashEntry{}, fmt.Errorf("trash window: %w", err)
 	}
 
 	if len(windows) == 0 {
 		if err := a.store.DeleteSession(session); err != nil {
-			return fmt.Errorf("delete session: %w", err)
+			return entry, fmt.Errorf("delete session: %w", err)
 		}
 
-		return nil
+		return entry, nil
 	}
 
 	snap.Windows = windows
 
 	if err := a.store.SaveSession(snap); err != nil {
-		return fmt.Errorf("save session: %w", err)
+		return entry, fmt.Errorf("save session: %w", err)
 	}
 
-	return nil
+	return entry, nil
+}
+
+func (a *App) trashLiveWindow(session string, windowIndex int) (snapshot.TrashEntry, error) {
+	// Capture the live state first so the trash entry matches what gets killed.
+	_ = a.SaveSession(session)
+
+	snap, err := a.store.LoadSession(session)
+	if err != nil || !hasWindow(snap.Windows, windowIndex) {
+		return snapshot.TrashEntry{}, nil
+	}
+
+	entry, err := a.store.TrashWindow(snap, windowIndex)
+	if err != nil {
+		return snapshot.TrashEntry{}, fmt.Errorf("trash window: %w", err)
+	}
+
+	return entry, nil
 }
 
 func (a *App) DeleteSession(session string) error {
+	_, err := a.deleteSession(session)
+	return err
+}
+
+func (a *App) deleteSession(session string) (snapshot.TrashEntry, error) {
 	if a.tmux.SessionExists(session) {
+		// Capture the live state first so the trash entry matches what gets killed.
+		_ = a.SaveSession(session)
+
 		if err := a.tmux.KillSession(session); err != nil {
-			return fmt.Errorf("kill session: %w", err)
+			return snapshot.TrashEntry{}, fmt.Errorf("kill session: %w", err)
 		}
 	}
 
-	if err := a.store.DeleteSession(session); err != nil {
-		return fmt.Errorf("delete session: %w", err)
+	entry, err := a.store.TrashSession(session)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return snapshot.TrashEntry{}, fmt.Errorf("trash session: %w", err)
+		}
+
+		if err := a.store.DeleteSession(session); err != nil {
+			return snapshot.TrashEntry{}, fmt.Errorf("delete session: %w", err)
+		}
+
+		return snapshot.TrashEntry{}, nil
 	}
 
-	return nil
+	return entry, nil
 }
 
 func (a *App) RenameWindow(session string, windowIndex int, name string) error {
@@ -167,6 +220,11 @@ func (a *App) RenameSession(session, name string) error {
 
 	snap.SessionName = name
 
+	rec, err := a.store.Record(session)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("get session record: %w", err)
+	}
+
 	if a.tmux.SessionExists(session) {
 		if err := a.tmux.RenameSession(session, name); err != nil {
 			return fmt.Errorf("rename tmux session: %w", err)
@@ -177,6 +235,10 @@ func (a *App) RenameSession(session, name string) error {
 		return fmt.Errorf("save session: %w", err)
 	}
 
+	if err := a.restoreRecordMeta(name, rec); err != nil {
+		return err
+	}
+
 	if err := a.store.DeleteSession(session); err != nil {
 		return fmt.Errorf("delete old session: %w", err)
 	}
@@ -261,6 +323,16 @@ func (a *App) NewWindow(session, name string) error {
 	return nil
 }
 
+func hasWindow(windows []snapshot.Window, windowIndex int) bool {
+	for _, w := range windows {
+		if w.Index == windowIndex {
+			return true
+		}
+	}
+
+	return false
+}
+
 func nextWindowIndex(windows []snapshot.Window) int {
 	maxIdx := -1
 	for _, w := range windows {
@@ -301,5 +373,7 @@ func (a *App) Sleep(session string) error {
 		return fmt.Errorf("kill session: %w", err)
 	}
 
+	a.log().Info("session put to sleep", "session", session)
+
 	return nil
 }
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/alchemmist/lazy-tmux/internal/redact"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// sessionDocument is the editable YAML form of a snapshot. Scrollback and
+// other captured details are not part of it; they are kept from the saved
+// snapshot for panes whose window and pane index are unchanged.
+type sessionDocument struct {
+	Session     string            `yaml:"session"`
+	Windows     []windowDocument  `yaml:"windows"`
+	Options     map[string]string `yaml:"options,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+}
+
+type windowDocument struct {
+	Index   int               `yaml:"index"`
+	Name    string            `yaml:"name"`
+	Layout  string            `yaml:"layout,omitempty"`
+	Active  bool              `yaml:"active,omitempty"`
+	Zoomed  bool              `yaml:"zoomed,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+	Panes   []paneDocument    `yaml:"panes"`
+}
+
+type paneDocument struct {
+	Index   int    `yaml:"index"`
+	Path    string `yaml:"path"`
+	Command string `yaml:"command,omitempty"`
+	Restore string `yaml:"restore,omitempty"`
+	Title   string `yaml:"title,omitempty"`
+	Active  bool   `yaml:"active,omitempty"`
+}
+
+// SessionYAML returns the editable YAML form of a saved session.
+func (a *App) SessionYAML(session string) ([]byte, error) {
+	snap, err := a.LoadSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := sessionDocument{
+		Session:     snap.SessionName,
+		Options:     snap.Options,
+		Environment: snap.Environment,
+	}
+
+	for _, w := range snap.Windows {
+		wd := windowDocument{
+			Index:   w.Index,
+			Name:    w.Name,
+			Layout:  w.Layout,
+			Active:  w.IsActive,
+			Zoomed:  w.Zoomed,
+			Options: w.Options,
+		}
+
+		for _, p := range w.Panes {
+			wd.Panes = append(wd.Panes, paneDocument{
+				Index:   p.Index,
+				Path:    p.CurrentPath,
+				Command: p.CurrentCmd,
+				Restore: p.RestoreCmd,
+				Title:   p.Title,
+				Active:  p.IsActive,
+			})
+		}
+
+		doc.Windows = append(doc.Windows, wd)
+	}
+
+	var out bytes.Buffer
+
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("marshal session %s: %w", session, err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// SaveSessionYAML validates an edited YAML form of a session and saves it
+// over the snapshot. Problems that do not stop a restore, such as missing
+// directories, are returned as warnings.
+func (a *App) SaveSessionYAML(session string, data []byte) ([]string, error) {
+	var doc sessionDocument
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse yaml: %w", err)
+	}
+
+	saved, err := a.LoadSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Session != saved.SessionName {
+		return nil, fmt.Errorf("session name cannot be changed by edit (%q -> %q)", saved.SessionName, doc.Session)
+	}
+
+	snap, warnings, err := applySessionDocument(saved, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.tmux.SessionExists(session) {
+		warnings = append(warnings, "session is running; its next save overwrites these edits")
+	}
+
+	if err := a.store.SaveSession(snap); err != nil {
+		return nil, fmt.Errorf("save session %s: %w", session, err)
+	}
+
+	return warnings, nil
+}
+
+// applySessionDocument builds the snapshot described by doc on top of saved.
+// Every problem that makes the snapshot invalid is reported in one error.
+func applySessionDocument(saved snapshot.SessionSnapshot, doc sessionDocument) (snapshot.SessionSnapshot, []string, error) {
+	type paneKey struct{ window, pane int }
+
+	previous := make(map[paneKey]snapshot.Pane)
+
+	for _, w := range saved.Windows {
+		for _, p := range w.Panes {
+			previous[paneKey{w.Index, p.Index}] = p
+		}
+	}
+
+	snap := saved
+	snap.Windows = nil
+	snap.Options = doc.Options
+	snap.Environment = doc.Environment
+
+	var (
+		errs     []error
+		warnings []string
+	)
+
+	if len(doc.Windows) == 0 {
+		errs = append(errs, errors.New("session has no windows"))
+	}
+
+	windowSeen := make(map[int]bool)
+	activeWindows := 0
+
+	for _, wd := range doc.Windows {
+		where := fmt.Sprintf("window %d", wd.Index)
+
+		switch {
+		case wd.Index < 0:
+			errs = append(errs, fmt.Errorf("%s: index must not be negative", where))
+		case windowSeen[wd.Index]:
+			errs = append(errs, fmt.Errorf("%s: duplicate window index", where))
+		}
+
+		windowSeen[wd.Index] = true
+
+		if len(wd.Panes) == 0 {
+			errs = append(errs, fmt.Errorf("%s: window has no panes", where))
+		}
+
+		if err := tmux.ValidateLayout(wd.Layout, len(wd.Panes)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+
+		w := snapshot.Window{
+			Index:    wd.Index,
+			Name:     wd.Name,
+			Layout:   wd.Layout,
+			IsActive: wd.Active,
+			Zoomed:   wd.Zoomed,
+			Options:  wd.Options,
+		}
+
+		if wd.Active {
+			activeWindows++
+			snap.CurrentWin = wd.Index
+		}
+
+		paneSeen := make(map[int]bool)
+		activePanes := 0
+
+		for _, pd := range wd.Panes {
+			if pd.Index < 0 || paneSeen[pd.Index] {
+				errs = append(errs, fmt.Errorf("%s: pane %d: duplicate or negative pane index", where, pd.Index))
+			}
+
+			paneSeen[pd.Index] = true
+
+			if pd.Path != "" {
+				if info, err := os.Stat(pd.Path); err != nil || !info.IsDir() {
+					warnings = append(warnings, fmt.Sprintf("%s: pane %d: directory %s does not exist", where, pd.Index, pd.Path))
+				}
+			}
+
+			p := previous[paneKey{wd.Index, pd.Index}]
+			p.Index = pd.Index
+			p.CurrentPath = pd.Path
+			p.CurrentCmd = pd.Command
+			p.Title = pd.Title
+			p.IsActive = pd.Active
+
+			if restore := strings.TrimSpace(pd.Restore); restore != p.RestoreCmd {
+				// A command typed over the masked one is run again on restore.
+				p.RestoreCmd = restore
+				p.CommandRedacted = redact.Masked(restore)
+			}
+
+			if pd.Active {
+				activePanes++
+				w.ActivePane = pd.Index
+			}
+
+			w.Panes = append(w.Panes, p)
+		}
+
+		if activePanes > 1 {
+			errs = append(errs, fmt.Errorf("%s: %d panes are marked active, want one", where, activePanes))
+		}
+
+		if activePanes == 0 && len(w.Panes) > 0 {
+			w.ActivePane = w.Panes[0].Index
+			w.Panes[0].IsActive = true
+		}
+
+		snap.Windows = append(snap.Windows, w)
+	}
+
+	if activeWindows > 1 {
+		errs = append(errs, fmt.Errorf("%d windows are marked active, want one", activeWindows))
+	}
+
+	if activeWindows == 0 && len(snap.Windows) > 0 {
+		snap.Windows[0].IsActive = true
+		snap.CurrentWin = snap.Windows[0].Index
+	}
+
+	for _, w := range snap.Windows {
+		if w.Index == snap.CurrentWin {
+			snap.CurrentPane = w.ActivePane
+		}
+	}
+
+	if len(errs) > 0 {
+		return saved, nil, errors.Join(errs...)
+	}
+
+	return snap, warnings, nil
+}
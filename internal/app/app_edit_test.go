@@ -0,0 +1,145 @@
+package app
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestSaveSessionYAMLValidatesAndKeepsScrollback(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `exit 1`)
+
+	app := New(config.Config{DataDir: t.TempDir(), TmuxBin: fake})
+	app.tmux = tmux.NewClient(fake)
+
+	dir := t.TempDir()
+
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC),
+		Windows: []snapshot.Window{{Index: 0, Name: "edit", IsActive: true, Panes: []snapshot.Pane{{
+			Index:       0,
+			CurrentPath: dir,
+			CurrentCmd:  "nvim",
+			RestoreCmd:  "nvim",
+			IsActive:    true,
+			Scrollback:  &snapshot.ScrollbackRef{Content: "old output\n"},
+		}}}},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	doc, err := app.SessionYAML("demo")
+	if err != nil {
+		t.Fatalf("SessionYAML: %v", err)
+	}
+
+	if !strings.Contains(string(doc), "restore: nvim\n") {
+		t.Fatalf("unexpected document:\n%s", doc)
+	}
+
+	edited := strings.Replace(string(doc), "restore: nvim\n", "restore: nvim -S Session.vim\n", 1) +
+		"  - index: 1\n    name: logs\n    panes:\n      - index: 0\n        path: /nonexistent/lazy-tmux\n"
+
+	warnings, err := app.SaveSessionYAML("demo", []byte(edited))
+	if err != nil {
+		t.Fatalf("SaveSessionYAML: %v", err)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "/nonexistent/lazy-tmux does not exist") {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+
+	snap, err := app.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("LoadSession: %v", err)
+	}
+
+	pane := snap.Windows[0].Panes[0]
+	if pane.RestoreCmd != "nvim -S Session.vim" || pane.Scrollback == nil || pane.Scrollback.Content != "old output\n" {
+		t.Fatalf("unexpected pane after edit: %+v", pane)
+	}
+
+	if len(snap.Windows) != 2 || !snap.Windows[1].Panes[0].IsActive {
+		t.Fatalf("unexpected windows after edit: %+v", snap.Windows)
+	}
+
+	rec, err := app.store.LatestRecord()
+	if err != nil || rec.Windows != 2 || rec.Panes != 2 {
+		t.Fatalf("index not updated: %+v %v", rec, err)
+	}
+
+	bad := strings.Replace(edited, "index: 1", "index: 0", 1) + "    layout: abcd,80x24,0,0,1\n"
+
+	_, err = app.SaveSessionYAML("demo", []byte(bad))
+	if err == nil || !strings.Contains(err.Error(), "duplicate window index") || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	renamed := strings.Replace(string(doc), "session: demo", "session: other", 1)
+	if _, err := app.SaveSessionYAML("demo", []byte(renamed)); err == nil {
+		t.Fatal("expected error when renaming through edit")
+	}
+}
+
+func TestSaveSessionYAMLRepairsRedactedCommand(t *testing.T) {
+	logPath := t.TempDir() + "/tmux.log"
+	fake := writeFakeTmuxForApp(t, `
+echo "$*" >> "$TMUX_LOG"
+case "$1" in
+has-session) exit 1 ;;
+list-windows) echo "0" ;;
+esac
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	app := New(config.Config{DataDir: t.TempDir(), TmuxBin: fake})
+	app.tmux = tmux.NewClient(fake)
+
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{{Index: 0, Name: "db", IsActive: true, Panes: []snapshot.Pane{{
+			Index: 0, CurrentCmd: "mysql", RestoreCmd: "mysql -p[REDACTED:password]", CommandRedacted: true, IsActive: true,
+		}}}},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	doc, err := app.SessionYAML("demo")
+	if err != nil {
+		t.Fatalf("SessionYAML: %v", err)
+	}
+
+	edited := strings.Replace(string(doc), "mysql -p[REDACTED:password]", "mysql --login-path=local", 1)
+	if _, err := app.SaveSessionYAML("demo", []byte(edited)); err != nil {
+		t.Fatalf("SaveSessionYAML: %v", err)
+	}
+
+	snap, err := app.LoadSession("demo")
+	if err != nil || snap.Windows[0].Panes[0].CommandRedacted {
+		t.Fatalf("expected the edited command to be runnable, got %+v %v", snap.Windows[0].Panes[0], err)
+	}
+
+	if err := app.Restore("demo", false); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	if !strings.Contains(string(b), "send-keys") || !strings.Contains(string(b), "mysql --login-path=local") {
+		t.Fatalf("expected the edited command to be replayed, got:\n%s", b)
+	}
+}
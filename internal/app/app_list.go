@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/picker"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// ListedSession is a saved session with its live state, as printed by list.
+type ListedSession struct {
+	snapshot.Record
+	// Live reports whether the session is running; a saved session that is
+	// not running is sleeping.
+	Live bool `json:"live"`
+}
+
+// LastUsed returns when the session was last used, or when it was captured
+// if it was never accessed.
+func (s ListedSession) LastUsed() time.Time {
+	if s.LastAccessed.IsZero() {
+		return s.CapturedAt
+	}
+
+	return s.LastAccessed
+}
+
+// ListFilter selects saved sessions. Zero fields do not filter.
+type ListFilter struct {
+	Live     bool
+	Sleeping bool
+	// Match is a glob matched against the session name, as in path.Match.
+	Match string
+	// OlderThan keeps sessions last used at least this long ago.
+	OlderThan time.Duration
+}
+
+// ListSessions returns the saved sessions that pass filter, sorted by keys,
+// or newest capture first when keys is empty.
+func (a *App) ListSessions(filter ListFilter, keys []SessionSortKey) ([]ListedSession, error) {
+	if filter.Match != "" {
+		if _, err := path.Match(filter.Match, ""); err != nil {
+			return nil, fmt.Errorf("invalid match pattern %q: %w", filter.Match, err)
+		}
+	}
+
+	records, err := a.ListRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	liveSessions, err := a.tmux.ListSessions()
+	if err != nil {
+		// A server that is not running has no live sessions.
+		if classifySaveError(err) != saveErrorUnreachable {
+			return nil, fmt.Errorf("list sessions: %w", err)
+		}
+
+		liveSessions = nil
+	}
+
+	live := make(map[string]bool, len(liveSessions))
+	for _, name := range liveSessions {
+		live[name] = true
+	}
+
+	if len(keys) > 0 {
+		picker.SortSessionRecords(records, keys)
+	}
+
+	now := time.Now()
+	out := make([]ListedSession, 0, len(records))
+
+	for _, rec := range records {
+		listed := ListedSession{Record: rec, Live: live[rec.SessionName]}
+
+		if filter.Live && !listed.Live || filter.Sleeping && listed.Live {
+			continue
+		}
+
+		if filter.Match != "" {
+			if ok, _ := path.Match(filter.Match, rec.SessionName); !ok {
+				continue
+			}
+		}
+
+		if filter.OlderThan > 0 && now.Sub(listed.LastUsed()) < filter.OlderThan {
+			continue
+		}
+
+		out = append(out, listed)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestListSessionsFiltersAndSorts(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "list-sessions" ]; then
+  echo "api-live"
+  exit 0
+fi
+exit 0
+`)
+
+	app := New(config.Config{DataDir: t.TempDir(), TmuxBin: fake})
+	app.tmux = tmux.NewClient(fake)
+
+	now := time.Now()
+	captured := map[string]time.Time{
+		"api-live":  now.Add(-time.Hour),
+		"api-old":   now.Add(-72 * time.Hour),
+		"web-sleep": now.Add(-2 * time.Hour),
+	}
+
+	for name, at := range captured {
+		if err := app.store.SaveSession(snapshot.SessionSnapshot{
+			Version:     snapshot.FormatVersion,
+			SessionName: name,
+			CapturedAt:  at,
+			Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+		}); err != nil {
+			t.Fatalf("save session %q: %v", name, err)
+		}
+	}
+
+	names := func(sessions []ListedSession) []string {
+		out := make([]string, 0, len(sessions))
+		for _, s := range sessions {
+			out = append(out, s.SessionName)
+		}
+
+		return out
+	}
+
+	nameAsc := []SessionSortKey{{Field: SessionSortName}}
+
+	for _, tc := range []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{name: "all", want: []string{"api-live", "api-old", "web-sleep"}},
+		{name: "live", filter: ListFilter{Live: true}, want: []string{"api-live"}},
+		{name: "sleeping", filter: ListFilter{Sleeping: true}, want: []string{"api-old", "web-sleep"}},
+		{name: "match", filter: ListFilter{Match: "api-*"}, want: []string{"api-live", "api-old"}},
+		{name: "older-than", filter: ListFilter{OlderThan: 24 * time.Hour}, want: []string{"api-old"}},
+	} {
+		sessions, err := app.ListSessions(tc.filter, nameAsc)
+		if err != nil {
+			t.Fatalf("%s: ListSessions: %v", tc.name, err)
+		}
+
+		if got := names(sessions); !slices.Equal(got, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// Without sort keys the newest capture comes first.
+	sessions, err := app.ListSessions(ListFilter{}, nil)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+
+	if got := names(sessions); !slices.Equal(got, []string{"api-live", "web-sleep", "api-old"}) || !sessions[0].Live || sessions[1].Live {
+		t.Fatalf("unexpected default order or live state: %+v", sessions)
+	}
+
+	if _, err := app.ListSessions(ListFilter{Match: "["}, nil); err == nil {
+		t.Fatal("expected error for an invalid glob")
+	}
+}
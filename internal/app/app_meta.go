@@ -0,0 +1,121 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+)
+
+func (a *App) PinSession(session string, pinned bool) error {
+	_, err := a.updateSessionMeta(session, func(meta *snapshot.SessionMeta) {
+		meta.Pinned = pinned
+	})
+
+	return err
+}
+
+func (a *App) SetSessionTags(session string, tags []string) error {
+	_, err := a.updateSessionMeta(session, func(meta *snapshot.SessionMeta) {
+		meta.Tags = tags
+	})
+
+	return err
+}
+
+// TagSession adds and removes tags and returns the resulting tag set.
+func (a *App) TagSession(session string, add, remove []string) ([]string, error) {
+	drop := make(map[string]struct{}, len(remove))
+	for _, tag := range remove {
+		drop[strings.TrimSpace(tag)] = struct{}{}
+	}
+
+	meta, err := a.updateSessionMeta(session, func(meta *snapshot.SessionMeta) {
+		tags := make([]string, 0, len(meta.Tags)+len(add))
+		for _, tag := range append(meta.Tags, add...) {
+			if _, ok := drop[strings.TrimSpace(tag)]; !ok {
+				tags = append(tags, tag)
+			}
+		}
+
+		meta.Tags = tags
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return meta.Tags, nil
+}
+
+func (a *App) SetSessionNote(session, note string) error {
+	_, err := a.updateSessionMeta(session, func(meta *snapshot.SessionMeta) {
+		meta.Note = note
+	})
+
+	return err
+}
+
+func (a *App) SessionMeta(session string) (snapshot.SessionMeta, error) {
+	rec, err := a.store.Record(strings.TrimSpace(session))
+	if err != nil {
+		return snapshot.SessionMeta{}, fmt.Errorf("get session record: %w", err)
+	}
+
+	return rec.SessionMeta, nil
+}
+
+func (a *App) updateSessionMeta(
+	session string,
+	update func(*snapshot.SessionMeta),
+) (snapshot.SessionMeta, error) {
+	session = strings.TrimSpace(session)
+	if session == "" {
+		return snapshot.SessionMeta{}, fmt.Errorf("session name is empty")
+	}
+
+	rec, err := a.store.Record(session)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || !a.tmux.SessionExists(session) {
+			return snapshot.SessionMeta{}, fmt.Errorf("get session record: %w", err)
+		}
+
+		// Running sessions the daemon has not saved yet get a snapshot first.
+		if err := a.SaveSession(session); err != nil {
+			return snapshot.SessionMeta{}, err
+		}
+
+		if rec, err = a.store.Record(session); err != nil {
+			return snapshot.SessionMeta{}, fmt.Errorf("get session record: %w", err)
+		}
+	}
+
+	meta := rec.SessionMeta
+	meta.Tags = append([]string(nil), meta.Tags...)
+	update(&meta)
+	meta.Tags = store.NormalizeTags(meta.Tags)
+
+	if err := a.store.SetSessionMeta(session, meta); err != nil {
+		return snapshot.SessionMeta{}, fmt.Errorf("set session meta: %w", err)
+	}
+
+	return meta, nil
+}
+
+// restoreRecordMeta carries user metadata and access time of a record over to
+// a session that was re-created under the given name.
+func (a *App) restoreRecordMeta(session string, rec snapshot.Record) error {
+	if err := a.store.SetSessionMeta(session, rec.SessionMeta); err != nil {
+		return fmt.Errorf("set session meta: %w", err)
+	}
+
+	if !rec.LastAccessed.IsZero() {
+		if err := a.store.MarkSessionAccessed(session, rec.LastAccessed); err != nil {
+			return fmt.Errorf("mark session accessed: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestTagSessionAddsAndRemoves(t *testing.T) {
+	app := newOfflineTrashApp(t)
+
+	tags, err := app.TagSession("demo", []string{"work", "client-x"}, nil)
+	if err != nil {
+		t.Fatalf("TagSession error: %v", err)
+	}
+
+	if strings.Join(tags, ",") != "client-x,work" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+
+	tags, err = app.TagSession("demo", []string{"urgent"}, []string{"work"})
+	if err != nil {
+		t.Fatalf("TagSession error: %v", err)
+	}
+
+	if strings.Join(tags, ",") != "client-x,urgent" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+
+	if _, err := app.TagSession("missing", []string{"x"}, nil); err == nil {
+		t.Fatal("expected error for unknown session")
+	}
+}
+
+func TestRenameSessionPreservesMeta(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+exit 0
+`)
+	app := &App{
+		store: store.New(t.TempDir()),
+		tmux:  tmux.NewClient(fake),
+	}
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "old",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	if err := app.PinSession("old", true); err != nil {
+		t.Fatalf("PinSession error: %v", err)
+	}
+
+	if err := app.SetSessionNote("old", "keep me"); err != nil {
+		t.Fatalf("SetSessionNote error: %v", err)
+	}
+
+	if err := app.RenameSession("old", "new"); err != nil {
+		t.Fatalf("RenameSession error: %v", err)
+	}
+
+	meta, err := app.SessionMeta("new")
+	if err != nil {
+		t.Fatalf("SessionMeta error: %v", err)
+	}
+
+	if !meta.Pinned || meta.Note != "keep me" {
+		t.Fatalf("expected meta to follow rename, got %+v", meta)
+	}
+}
+
+func TestRestoreTrashPreservesMeta(t *testing.T) {
+	app := newOfflineTrashApp(t)
+
+	if err := app.SetSessionTags("demo", []string{"work"}); err != nil {
+		t.Fatalf("SetSessionTags error: %v", err)
+	}
+
+	entry, err := app.deleteSession("demo")
+	if err != nil {
+		t.Fatalf("deleteSession error: %v", err)
+	}
+
+	if _, err := app.RestoreTrash(entry.ID); err != nil {
+		t.Fatalf("RestoreTrash error: %v", err)
+	}
+
+	meta, err := app.SessionMeta("demo")
+	if err != nil {
+		t.Fatalf("SessionMeta error: %v", err)
+	}
+
+	if strings.Join(meta.Tags, ",") != "work" {
+		t.Fatalf("expected tags to survive trash round-trip, got %v", meta.Tags)
+	}
+}
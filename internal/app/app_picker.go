@@ -3,8 +3,8 @@ package app
 import (
 	"errors"
 	"fmt"
-	"log"
 
+	"github.com/alchemmist/lazy-tmux/internal/memory"
 	"github.com/alchemmist/lazy-tmux/internal/picker"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
@@ -27,6 +27,55 @@ func (a *App) pickerRecords(opts PickerSortOptions) ([]snapshot.Record, error) {
 }
 
 func (a *App) pickerSessions(opts PickerSortOptions) ([]picker.Session, error) {
+	sessions, err := a.localPickerSessions(opts)
+	if err != nil && !errors.Is(err, errNoSavedSessions) {
+		return nil, err
+	}
+
+	foreign := append(a.foreignPickerSessions(opts), a.otherHostPickerSessions(opts)...)
+	if len(sessions) == 0 && len(foreign) == 0 {
+		return nil, errNoSavedSessions
+	}
+
+	return append(sessions, foreign...), nil
+}
+
+// foreignPickerSessions returns the saved sessions of the other tmux servers,
+// grouped by server. Servers that cannot be read are skipped.
+func (a *App) foreignPickerSessions(opts PickerSortOptions) []picker.Session {
+	servers, err := knownServers(hostDataDir(a.cfg.DataDir, a.host))
+	if err != nil {
+		a.log().Warn("picker cannot list tmux servers", "error", err)
+		return nil
+	}
+
+	var sessions []picker.Session
+
+	for _, server := range servers {
+		if a.isCurrentServer(server) {
+			continue
+		}
+
+		other, err := a.forServer(server).localPickerSessions(opts)
+		if err != nil {
+			if !errors.Is(err, errNoSavedSessions) {
+				a.log().Warn("picker skips tmux server", "server", server.String(), "error", err)
+			}
+
+			continue
+		}
+
+		for i := range other {
+			other[i].Server = server.String()
+		}
+
+		sessions = append(sessions, other...)
+	}
+
+	return sessions
+}
+
+func (a *App) localPickerSessions(opts PickerSortOptions) ([]picker.Session, error) {
 	records, err := a.pickerRecords(opts)
 	if err != nil {
 		return nil, err
@@ -34,7 +83,12 @@ func (a *App) pickerSessions(opts PickerSortOptions) ([]picker.Session, error) {
 
 	liveSessions, err := a.tmux.ListSessions()
 	if err != nil {
-		return nil, fmt.Errorf("list sessions: %w", err)
+		// A server that is not running has no live sessions to mark.
+		if classifySaveError(err) != saveErrorUnreachable {
+			return nil, fmt.Errorf("list sessions: %w", err)
+		}
+
+		liveSessions = nil
 	}
 
 	live := make(map[string]struct{}, len(liveSessions))
@@ -42,21 +96,30 @@ func (a *App) pickerSessions(opts PickerSortOptions) ([]picker.Session, error) {
 		live[name] = struct{}{}
 	}
 
+	var usage map[string]uint64
+	if len(live) > 0 {
+		if usage, err = a.sessionMemory(); err != nil {
+			a.log().Warn("picker session memory unavailable", "error", err)
+		}
+	}
+
 	sessions := make([]picker.Session, 0, len(records))
 
 	for _, rec := range records {
 		snap, err := a.store.LoadSession(rec.SessionName)
 		if err != nil {
-			log.Printf("picker: skip session %s: %v", rec.SessionName, err)
+			a.log().Warn("picker skips session", "session", rec.SessionName, "error", err)
 			continue
 		}
 
 		_, restored := live[rec.SessionName]
-		sessions = append(sessions, picker.Session{
-			Record:   rec,
-			Windows:  snap.Windows,
-			Restored: restored,
-		})
+		sess := picker.Session{Record: rec, Windows: snap.Windows, Restored: restored}
+
+		if n := usage[rec.SessionName]; n > 0 {
+			sess.Memory = memory.FormatBytes(n)
+		}
+
+		sessions = append(sessions, sess)
 	}
 
 	return sessions, nil
@@ -76,15 +139,21 @@ func (a *App) SelectTargetWithTUISorted(opts PickerSortOptions) (PickerTarget, e
 		}
 	}
 
+	undo := &pickerUndo{app: a}
 	actions := picker.Actions{
-		DeleteWindow:  a.DeleteWindow,
-		DeleteSession: a.DeleteSession,
+		DeleteWindow:  undo.deleteWindow,
+		DeleteSession: undo.deleteSession,
 		RenameWindow:  a.RenameWindow,
 		RenameSession: a.RenameSession,
 		NewSession:    a.NewSession,
 		NewWindow:     a.NewWindow,
 		Wakeup:        a.Wakeup,
 		Sleep:         a.Sleep,
+		Undo:          undo.undo,
+		SetPinned:     a.PinSession,
+		SetTags:       a.SetSessionTags,
+		SetNote:       a.SetSessionNote,
+		Export:        a.ExportSession,
 		Reload: func() ([]picker.Session, error) {
 			sessions, err := a.pickerSessions(opts)
 			if err != nil {
@@ -1,11 +1,15 @@
 package app
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/logging"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 	"github.com/alchemmist/lazy-tmux/internal/tmux"
 )
@@ -45,6 +49,10 @@ exit 0
 
 	app.tmux = tmux.NewClient(fake)
 
+	var logs bytes.Buffer
+
+	app.SetLogger(logging.New(&logs, logging.FormatText, slog.LevelWarn))
+
 	sessions, err := app.pickerSessions(DefaultPickerSortOptions())
 	if err != nil {
 		t.Fatalf("pickerSessions: %v", err)
@@ -61,4 +69,53 @@ exit 0
 	if !sessions[0].Restored {
 		t.Fatal("expected alpha to be marked as restored")
 	}
+
+	if !strings.Contains(logs.String(), `msg="picker skips session" session=beta`) {
+		t.Fatalf("expected the skipped session to be logged, got %q", logs.String())
+	}
+}
+
+func TestPickerSessionsListsOtherServersSeparately(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "-L" ] && [ "$2" = "work" ] && [ "$3" = "list-sessions" ]; then
+  echo "alpha"
+  exit 0
+fi
+echo "no server running on /tmp/tmux-0/$2" >&2
+exit 1
+`)
+
+	dataDir := t.TempDir()
+	work := New(config.Config{DataDir: dataDir, TmuxBin: fake, TmuxSocketName: "work"})
+	home := New(config.Config{DataDir: dataDir, TmuxBin: fake})
+
+	for _, a := range []*App{work, home} {
+		if err := a.store.SaveSession(snapshot.SessionSnapshot{
+			Version:     snapshot.FormatVersion,
+			SessionName: "alpha",
+			CapturedAt:  time.Now().UTC(),
+			Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+		}); err != nil {
+			t.Fatalf("save session: %v", err)
+		}
+	}
+
+	sessions, err := work.pickerSessions(DefaultPickerSortOptions())
+	if err != nil {
+		t.Fatalf("pickerSessions: %v", err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("expected alpha from both servers, got %+v", sessions)
+	}
+
+	if sessions[0].Server != "" || !sessions[0].Restored {
+		t.Fatalf("expected live local alpha first, got %+v", sessions[0])
+	}
+
+	if sessions[1].Server != "default" || sessions[1].Restored {
+		t.Fatalf("expected sleeping alpha of the default server, got %+v", sessions[1])
+	}
 }
@@ -1,10 +1,15 @@
 package app
 
 import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/logging"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 	"github.com/alchemmist/lazy-tmux/internal/store"
 	"github.com/alchemmist/lazy-tmux/internal/tmux"
@@ -79,10 +84,79 @@ exit 0
 		tmux:  tmux.NewClient(fake),
 	}
 
+	var logs bytes.Buffer
+
+	app.SetLogger(logging.New(&logs, logging.FormatJSON, slog.LevelDebug))
+
 	if err := app.SaveAll(); err != nil {
 		t.Fatalf("SaveAll error: %v", err)
 	}
 
+	for _, name := range []string{"alpha", "beta"} {
+		if _, err := app.store.LoadSession(name); err != nil {
+			t.Fatalf("expected %s snapshot saved, got %v", name, err)
+		}
+
+		if !strings.Contains(logs.String(), `"msg":"session saved","session":"`+name+`","windows":1,"duration":`) {
+			t.Fatalf("expected timed save record for %s, got %s", name, logs.String())
+		}
+	}
+
+	for _, component := range []string{`"component":"tmux"`, `"component":"store"`} {
+		if !strings.Contains(logs.String(), component) {
+			t.Fatalf("expected %s records, got %s", component, logs.String())
+		}
+	}
+}
+
+func TestSaveAllContinuesPastFailedSessions(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "list-sessions" ]; then
+  printf "alpha\ngone\nbroken\nbeta\n"
+  exit 0
+fi
+if [ "$1" = "has-session" ]; then
+  [ "$3" = "=gone" ] && exit 1
+  exit 0
+fi
+if [ "$1" = "display-message" ]; then
+  printf "0\0370\n"
+  exit 0
+fi
+if [ "$1" = "list-windows" ]; then
+  if [ "$3" = "=broken" ]; then
+    echo "boom" >&2
+    exit 1
+  fi
+  printf "0\037main\037layout\0371\n"
+  exit 0
+fi
+if [ "$1" = "list-panes" ]; then
+  printf "0\037/tmp\037zsh\0371\037111\037\n"
+  exit 0
+fi
+exit 0
+`)
+
+	app := &App{
+		cfg:   config.Config{Scrollback: config.ScrollbackConfig{Enabled: false}},
+		store: store.New(t.TempDir()),
+		tmux:  tmux.NewClient(fake),
+	}
+
+	var observed []string
+
+	err := app.saveAll(func(session string, _ time.Duration, _ error) {
+		observed = append(observed, session)
+	})
+	if err == nil || !strings.Contains(err.Error(), "session broken:") || strings.Contains(err.Error(), "gone") {
+		t.Fatalf("expected only the broken session to fail, got %v", err)
+	}
+
+	if strings.Join(observed, ",") != "alpha,beta,broken,gone" {
+		t.Fatalf("expected every session to be attempted, got %v", observed)
+	}
+
 	for _, name := range []string{"alpha", "beta"} {
 		if _, err := app.store.LoadSession(name); err != nil {
 			t.Fatalf("expected %s snapshot saved, got %v", name, err)
@@ -137,3 +211,110 @@ exit 0
 		t.Fatalf("expected last accessed to be updated, got %+v", rec)
 	}
 }
+
+func TestRestoreReportsRedactedCommands(t *testing.T) {
+	logPath := t.TempDir() + "/tmux.log"
+	fake := writeFakeTmuxForApp(t, `
+echo "$*" >> "$TMUX_LOG"
+case "$1" in
+has-session) exit 1 ;;
+list-windows) echo "0" ;;
+list-clients) echo "/dev/pts/1" ;;
+esac
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	var logs bytes.Buffer
+
+	app := &App{
+		store:  store.New(t.TempDir()),
+		tmux:   tmux.NewClient(fake),
+		logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{
+			Index: 0, CurrentCmd: "mysql", RestoreCmd: "mysql -p[REDACTED:password]", CommandRedacted: true,
+		}}}},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	if err := app.Restore("demo", false); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	if strings.Contains(string(b), "send-keys") || !strings.Contains(string(b), "display-message -c /dev/pts/1 lazy-tmux: demo: 1 pane(s)") {
+		t.Fatalf("expected the redacted command to be reported instead of run, got:\n%s", b)
+	}
+
+	if !strings.Contains(logs.String(), "restore command not run") {
+		t.Fatalf("expected a warning, got %s", logs.String())
+	}
+}
+
+func TestSaveSessionRedactsSecrets(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "has-session" ]; then
+  exit 0
+fi
+if [ "$1" = "display-message" ]; then
+  printf "0\0370\n"
+  exit 0
+fi
+if [ "$1" = "list-windows" ]; then
+  printf "0\037main\037layout\0371\n"
+  exit 0
+fi
+if [ "$1" = "list-panes" ]; then
+  printf "0\037/tmp\037zsh\0371\037111\037\n"
+  exit 0
+fi
+if [ "$1" = "capture-pane" ]; then
+  printf '$ mysql -u root -phunter2 db\n$ echo ticket-4711\n'
+  exit 0
+fi
+exit 0
+`)
+
+	cfg := config.Default()
+	cfg.TmuxBin = fake
+	cfg.DataDir = t.TempDir()
+	cfg.Scrollback.Enabled = true
+	cfg.Redact = config.RedactConfig{Enabled: true, Patterns: []string{`ticket-(\d+)`}}
+
+	app := New(cfg)
+
+	var logs bytes.Buffer
+	app.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	if err := app.SaveSession("demo"); err != nil {
+		t.Fatalf("SaveSession error: %v", err)
+	}
+
+	snap, err := app.store.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+
+	pane := snap.Windows[0].Panes[0]
+	if pane.Redactions != 2 || pane.Scrollback == nil ||
+		pane.Scrollback.Content != "$ mysql -u root -p[REDACTED:password] db\n$ echo ticket-[REDACTED:custom]\n" {
+		t.Fatalf("unexpected redaction: %d %+v", pane.Redactions, pane.Scrollback)
+	}
+
+	if !strings.Contains(logs.String(), "secrets redacted") || !strings.Contains(logs.String(), "count=2") {
+		t.Fatalf("expected redaction report in log, got %s", logs.String())
+	}
+}
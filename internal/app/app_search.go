@@ -0,0 +1,41 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/alchemmist/lazy-tmux/internal/search"
+)
+
+// GrepScrollback searches the saved scrollback of one session, or of every
+// saved session when session is empty. Searching every session skips the
+// ones that fail to load, like grep goes on past unreadable files, and
+// returns their errors in skipped.
+func (a *App) GrepScrollback(session string, match search.Matcher, context int) (matches []search.Match, skipped []error, err error) {
+	if session != "" {
+		snap, err := a.store.LoadSession(session)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load session %s: %w", session, err)
+		}
+
+		return search.Session(snap, match, context), nil, nil
+	}
+
+	records, err := a.ListRecords()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, rec := range records {
+		snap, err := a.store.LoadSession(rec.SessionName)
+		if err != nil {
+			a.log().Warn("grep skips session", "session", rec.SessionName, "error", err)
+			skipped = append(skipped, fmt.Errorf("load session %s: %w", rec.SessionName, err))
+
+			continue
+		}
+
+		matches = append(matches, search.Session(snap, match, context)...)
+	}
+
+	return matches, skipped, nil
+}
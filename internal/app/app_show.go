@@ -0,0 +1,174 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// LoadSession returns the saved snapshot of a session with its scrollback.
+func (a *App) LoadSession(session string) (snapshot.SessionSnapshot, error) {
+	snap, err := a.store.LoadSession(session)
+	if err != nil {
+		return snap, fmt.Errorf("load session %s: %w", session, err)
+	}
+
+	return snap, nil
+}
+
+// PaneScrollback returns the saved scrollback of one pane, empty when none
+// was captured.
+func (a *App) PaneScrollback(session string, windowIndex, paneIndex int) (string, error) {
+	snap, err := a.LoadSession(session)
+	if err != nil {
+		return "", err
+	}
+
+	for _, w := range snap.Windows {
+		if w.Index != windowIndex {
+			continue
+		}
+
+		for _, p := range w.Panes {
+			if p.Index != paneIndex {
+				continue
+			}
+
+			if p.Scrollback == nil {
+				return "", nil
+			}
+
+			return p.Scrollback.Content, nil
+		}
+
+		return "", fmt.Errorf("pane %d not found in window %d of session %s", paneIndex, windowIndex, session)
+	}
+
+	return "", fmt.Errorf("window %d not found in session %s", windowIndex, session)
+}
+
+// SnapshotJSON encodes a snapshot as stored, indented, with each pane's
+// scrollback content added under scrollback.content.
+func SnapshotJSON(snap snapshot.SessionSnapshot) ([]byte, error) {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("marshal snapshot: %w", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+
+	windows, _ := doc["windows"].([]any)
+	for wi, rawWindow := range windows {
+		panes, _ := rawWindow.(map[string]any)["panes"].([]any)
+		for pi, rawPane := range panes {
+			ref, ok := rawPane.(map[string]any)["scrollback"].(map[string]any)
+			if ok && snap.Windows[wi].Panes[pi].Scrollback != nil {
+				ref["content"] = snap.Windows[wi].Panes[pi].Scrollback.Content
+			}
+		}
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return append(out, '\n'), nil
+}
+
+// ExportSession writes a saved session, scrollback included, as plain JSON
+// to ExportDir and returns the file's path. The file is named after the
+// session, host and tmux server, so exports of several machines can share a
+// directory. With encryption on, exports are refused: they would put the
+// encrypted data on disk in the clear.
+func (a *App) ExportSession(session string) (string, error) {
+	if a.cfg.Encryption.Scrollback || a.cfg.Encryption.Sessions {
+		return "", errors.New("export refused: snapshots are encrypted and exports are plain JSON")
+	}
+
+	dir, err := a.exportDir()
+	if err != nil {
+		return "", err
+	}
+
+	snap, err := a.LoadSession(session)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := SnapshotJSON(snap)
+	if err != nil {
+		return "", err
+	}
+
+	stored, err := a.store.SessionPath(session)
+	if err != nil {
+		return "", fmt.Errorf("export session %s: %w", session, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(stored), ".json")
+
+	path := filepath.Join(dir, exportFileName(name, a.exportHost(), a.tmux.Server()))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("write export: %w", err)
+	}
+
+	return path, nil
+}
+
+// exportDir returns the absolute ExportDir, refusing one inside the data dir.
+func (a *App) exportDir() (string, error) {
+	dir, err := filepath.Abs(a.cfg.ExportDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve export dir: %w", err)
+	}
+
+	dataDir, err := filepath.Abs(a.cfg.DataDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve data dir: %w", err)
+	}
+
+	if rel, err := filepath.Rel(dataDir, dir); err == nil && rel != ".." &&
+		!strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("export dir %s is inside the data dir; pick another with --export-dir", dir)
+	}
+
+	return dir, nil
+}
+
+// exportHost names this machine in export file names, also when snapshots
+// are not namespaced by host.
+func (a *App) exportHost() string {
+	if a.host != "" {
+		return a.host
+	}
+
+	name, err := os.Hostname()
+	name, _, _ = strings.Cut(name, ".")
+
+	if err != nil || name == "" || config.ValidateProfile(name) != nil {
+		return "localhost"
+	}
+
+	return name
+}
+
+// exportFileName returns lazy-tmux-<session>@<host>[+<server>].json.
+func exportFileName(session, host string, server tmux.Server) string {
+	name := "lazy-tmux-" + session + "@" + host
+	if id := server.ID(); id != "" {
+		name += "+" + id
+	}
+
+	return name + ".json"
+}
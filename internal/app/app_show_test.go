@@ -0,0 +1,100 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestExportSessionWritesJSONWithScrollback(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `exit 1`)
+
+	dataDir := t.TempDir()
+	exportDir := t.TempDir()
+	app := New(config.Config{DataDir: dataDir, TmuxBin: fake, Host: config.HostConfig{Profile: "laptop"}})
+	app.tmux = tmux.NewClient(fake)
+	app.cfg.ExportDir = exportDir
+
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC),
+		Windows: []snapshot.Window{{Index: 0, Name: "shell", Panes: []snapshot.Pane{{
+			Index:      0,
+			Scrollback: &snapshot.ScrollbackRef{Content: "$ make\nok\n"},
+		}}}},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	path, err := app.ExportSession("demo")
+	if err != nil {
+		t.Fatalf("ExportSession: %v", err)
+	}
+
+	if path != filepath.Join(exportDir, "lazy-tmux-demo@laptop.json") {
+		t.Fatalf("unexpected export path %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+
+	var doc struct {
+		SessionName string `json:"session_name"`
+		Windows     []struct {
+			Panes []struct {
+				Scrollback struct {
+					Content string `json:"content"`
+				} `json:"scrollback"`
+			} `json:"panes"`
+		} `json:"windows"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode export: %v\n%s", err, data)
+	}
+
+	if doc.SessionName != "demo" || doc.Windows[0].Panes[0].Scrollback.Content != "$ make\nok\n" {
+		t.Fatalf("unexpected export:\n%s", data)
+	}
+
+	if _, err := app.ExportSession("missing"); err == nil {
+		t.Fatal("expected error exporting a session that is not saved")
+	}
+}
+
+func TestExportSessionRefusesDataDirAndEncryption(t *testing.T) {
+	dataDir := t.TempDir()
+	app := New(config.Config{DataDir: dataDir, TmuxBin: "tmux"})
+
+	app.cfg.ExportDir = filepath.Join(dataDir, "exports")
+	if _, err := app.ExportSession("demo"); err == nil || !strings.Contains(err.Error(), "inside the data dir") {
+		t.Fatalf("expected export into the data dir to be refused, got %v", err)
+	}
+
+	app.cfg.ExportDir = t.TempDir()
+	app.cfg.Encryption.Scrollback = true
+
+	if _, err := app.ExportSession("demo"); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Fatalf("expected export of encrypted snapshots to be refused, got %v", err)
+	}
+}
+
+func TestExportFileNameNamesHostAndServer(t *testing.T) {
+	if got := exportFileName("demo", "laptop", tmux.Server{}); got != "lazy-tmux-demo@laptop.json" {
+		t.Fatalf("unexpected default server name %q", got)
+	}
+
+	if got := exportFileName("demo", "laptop", tmux.Server{SocketName: "work"}); got != "lazy-tmux-demo@laptop+work.json" {
+		t.Fatalf("unexpected named server name %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func (a *App) ListTrash() ([]snapshot.TrashEntry, error) {
+	entries, err := a.store.ListTrash()
+	if err != nil {
+		return nil, fmt.Errorf("list trash: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (a *App) EmptyTrash(olderThan time.Duration) (int, error) {
+	var before time.Time
+	if olderThan > 0 {
+		before = time.Now().UTC().Add(-olderThan)
+	}
+
+	removed, err := a.store.EmptyTrash(before)
+	if err != nil {
+		return removed, fmt.Errorf("empty trash: %w", err)
+	}
+
+	return removed, nil
+}
+
+// LatestTrashID returns the most recent trash entry, optionally limited to one
+// session.
+func (a *App) LatestTrashID(session string) (string, error) {
+	entries, err := a.ListTrash()
+	if err != nil {
+		return "", err
+	}
+
+	session = strings.TrimSpace(session)
+	for _, entry := range entries {
+		if session == "" || entry.SessionName == session {
+			return entry.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("trash is empty: %w", os.ErrNotExist)
+}
+
+func (a *App) RestoreTrash(id string) (snapshot.TrashEntry, error) {
+	entry, snap, err := a.store.LoadTrash(strings.TrimSpace(id))
+	if err != nil {
+		return snapshot.TrashEntry{}, fmt.Errorf("load trash entry: %w", err)
+	}
+
+	switch entry.Kind {
+	case snapshot.TrashKindSession:
+		err = a.restoreTrashedSession(entry, snap)
+	case snapshot.TrashKindWindow:
+		err = a.restoreTrashedWindow(entry, snap)
+	default:
+		err = fmt.Errorf("unknown trash entry kind %q", entry.Kind)
+	}
+
+	if err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	if err := a.store.RemoveTrash(entry.ID); err != nil {
+		return entry, fmt.Errorf("remove trash entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (a *App) restoreTrashedSession(entry snapshot.TrashEntry, snap snapshot.SessionSnapshot) error {
+	exists, err := a.store.SessionExists(entry.SessionName)
+	if err != nil {
+		return fmt.Errorf("check session exists: %w", err)
+	}
+
+	if exists {
+		return fmt.Errorf("session %q already exists", entry.SessionName)
+	}
+
+	if a.tmux.SessionExists(entry.SessionName) {
+		return fmt.Errorf("session %q is running", entry.SessionName)
+	}
+
+	if err := a.store.SaveSession(snap); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+
+	return a.restoreRecordMeta(entry.SessionName, entry.Record)
+}
+
+func (a *App) restoreTrashedWindow(entry snapshot.TrashEntry, snap snapshot.SessionSnapshot) error {
+	if len(snap.Windows) == 0 {
+		return fmt.Errorf("trash entry %s has no windows", entry.ID)
+	}
+
+	window := snap.Windows[0]
+
+	if a.tmux.SessionExists(entry.SessionName) {
+		if err := a.tmux.RestoreWindow(entry.SessionName, window); err != nil {
+			return fmt.Errorf("restore window: %w", err)
+		}
+
+		a.reportRedactedCommands(entry.SessionName, []snapshot.Window{window})
+
+		return a.SaveSession(entry.SessionName)
+	}
+
+	current, err := a.store.LoadSession(entry.SessionName)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("load session: %w", err)
+		}
+
+		// The session went away together with its last window.
+		return a.restoreTrashedSession(entry, snap)
+	}
+
+	if hasWindow(current.Windows, window.Index) {
+		window.Index = nextWindowIndex(current.Windows)
+	}
+
+	current.Windows = append(current.Windows, window)
+	sort.Slice(current.Windows, func(i, j int) bool { return current.Windows[i].Index < current.Windows[j].Index })
+
+	if err := a.store.SaveSession(current); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+
+	return nil
+}
+
+// pickerUndo remembers trash entries created by one picker run so that they
+// can be reverted in reverse order.
+type pickerUndo struct {
+	app *App
+	ids []string
+}
+
+func (u *pickerUndo) deleteWindow(session string, windowIndex int) error {
+	entry, err := u.app.deleteWindow(session, windowIndex)
+	u.push(entry)
+
+	return err
+}
+
+func (u *pickerUndo) deleteSession(session string) error {
+	entry, err := u.app.deleteSession(session)
+	u.push(entry)
+
+	return err
+}
+
+func (u *pickerUndo) push(entry snapshot.TrashEntry) {
+	if entry.ID != "" {
+		u.ids = append(u.ids, entry.ID)
+	}
+}
+
+func (u *pickerUndo) undo() error {
+	if len(u.ids) == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+
+	id := u.ids[len(u.ids)-1]
+
+	if _, err := u.app.RestoreTrash(id); err != nil {
+		return err
+	}
+
+	u.ids = u.ids[:len(u.ids)-1]
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func newOfflineTrashApp(t *testing.T) *App {
+	t.Helper()
+
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+exit 0
+`)
+
+	app := &App{
+		store: store.New(t.TempDir()),
+		tmux:  tmux.NewClient(fake),
+	}
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{
+			{Index: 0, Name: "editor", Panes: []snapshot.Pane{{Index: 0}}},
+			{Index: 1, Name: "logs", Panes: []snapshot.Pane{{Index: 0}}},
+		},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	return app
+}
+
+func TestDeleteSessionMovesToTrashAndRestores(t *testing.T) {
+	app := newOfflineTrashApp(t)
+
+	if err := app.DeleteSession("demo"); err != nil {
+		t.Fatalf("DeleteSession error: %v", err)
+	}
+
+	if _, err := app.store.LoadSession("demo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected session to be deleted, got %v", err)
+	}
+
+	id, err := app.LatestTrashID("demo")
+	if err != nil {
+		t.Fatalf("LatestTrashID error: %v", err)
+	}
+
+	entry, err := app.RestoreTrash(id)
+	if err != nil {
+		t.Fatalf("RestoreTrash error: %v", err)
+	}
+
+	if entry.Kind != snapshot.TrashKindSession {
+		t.Fatalf("unexpected entry kind: %s", entry.Kind)
+	}
+
+	snap, err := app.store.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("expected session to be restored, got %v", err)
+	}
+
+	if len(snap.Windows) != 2 {
+		t.Fatalf("expected 2 windows after restore, got %d", len(snap.Windows))
+	}
+
+	entries, err := app.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash error: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("expected trash to be empty after restore, got %+v", entries)
+	}
+}
+
+func TestRestoreTrashedSessionRefusesExistingSession(t *testing.T) {
+	app := newOfflineTrashApp(t)
+
+	entry, err := app.deleteSession("demo")
+	if err != nil {
+		t.Fatalf("deleteSession error: %v", err)
+	}
+
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows:     []snapshot.Window{{Index: 0}},
+	}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	if _, err := app.RestoreTrash(entry.ID); err == nil {
+		t.Fatal("expected restore to fail when session already exists")
+	}
+}
+
+func TestPickerUndoRevertsWindowDelete(t *testing.T) {
+	app := newOfflineTrashApp(t)
+	undo := &pickerUndo{app: app}
+
+	if err := undo.undo(); err == nil {
+		t.Fatal("expected error when nothing to undo")
+	}
+
+	if err := undo.deleteWindow("demo", 1); err != nil {
+		t.Fatalf("deleteWindow error: %v", err)
+	}
+
+	snap, err := app.store.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+
+	if len(snap.Windows) != 1 {
+		t.Fatalf("expected 1 window after delete, got %d", len(snap.Windows))
+	}
+
+	if err := undo.undo(); err != nil {
+		t.Fatalf("undo error: %v", err)
+	}
+
+	snap, err = app.store.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+
+	if len(snap.Windows) != 2 || snap.Windows[1].Name != "logs" {
+		t.Fatalf("expected window to be restored, got %+v", snap.Windows)
+	}
+}
+
+func TestPickerUndoRestoresSessionDeletedWithLastWindow(t *testing.T) {
+	app := newOfflineTrashApp(t)
+	undo := &pickerUndo{app: app}
+
+	for _, idx := range []int{0, 1} {
+		if err := undo.deleteWindow("demo", idx); err != nil {
+			t.Fatalf("deleteWindow %d error: %v", idx, err)
+		}
+	}
+
+	if _, err := app.store.LoadSession("demo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected session to be deleted with last window, got %v", err)
+	}
+
+	for range 2 {
+		if err := undo.undo(); err != nil {
+			t.Fatalf("undo error: %v", err)
+		}
+	}
+
+	snap, err := app.store.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+
+	if len(snap.Windows) != 2 {
+		t.Fatalf("expected both windows to be restored, got %+v", snap.Windows)
+	}
+}
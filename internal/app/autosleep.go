@@ -0,0 +1,198 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/memory"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// SleepIdle puts detached sessions to sleep that saw no activity for
+// AutoSleep.IdleAfter and returns their names. Attached, pinned and excluded
+// sessions are never touched.
+func (a *App) SleepIdle(now time.Time) ([]string, error) {
+	idleAfter := a.cfg.AutoSleep.IdleAfter
+	if idleAfter <= 0 {
+		return nil, nil
+	}
+
+	sessions, err := a.tmux.ListSessionInfo()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	var (
+		slept []string
+		errs  []error
+	)
+
+	for _, info := range sessions {
+		if info.Activity.IsZero() || now.Sub(info.Activity) < idleAfter {
+			continue
+		}
+
+		if ok, err := a.canAutoSleep(info); err != nil || !ok {
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		idle := now.Sub(info.Activity).Round(time.Minute)
+		if err := a.autoSleep(info.Name, "idle for "+idle.String()); err != nil {
+			if classifySaveError(err) != saveErrorVanished {
+				errs = append(errs, fmt.Errorf("session %s: %w", info.Name, err))
+			}
+
+			continue
+		}
+
+		slept = append(slept, info.Name)
+	}
+
+	return slept, errors.Join(errs...)
+}
+
+// SleepUnderPressure puts least recently used detached sessions to sleep
+// while available memory is below AutoSleep.MinAvailable and returns their
+// names. The memory a sleep frees is estimated from the session's process
+// tree RSS, since exiting processes release it with a delay.
+func (a *App) SleepUnderPressure() ([]string, error) {
+	threshold := a.cfg.AutoSleep.MinAvailable
+	if threshold.IsZero() {
+		return nil, nil
+	}
+
+	stats, err := a.mem.System()
+	if err != nil {
+		return nil, fmt.Errorf("sample memory: %w", err)
+	}
+
+	want := threshold.Min(stats.Total)
+	if stats.Available >= want {
+		return nil, nil
+	}
+
+	sessions, err := a.tmux.ListSessionInfo()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Activity.Before(sessions[j].Activity)
+	})
+
+	rss, err := a.sessionMemory()
+	if err != nil {
+		a.log().Warn("session memory unavailable", "error", err)
+	}
+
+	var (
+		slept     []string
+		errs      []error
+		available = stats.Available
+	)
+
+	for _, info := range sessions {
+		if available >= want {
+			break
+		}
+
+		if ok, err := a.canAutoSleep(info); err != nil || !ok {
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		reason := fmt.Sprintf(
+			"memory pressure: %s available, %s used by session",
+			memory.FormatBytes(available),
+			memory.FormatBytes(rss[info.Name]),
+		)
+		if err := a.autoSleep(info.Name, reason); err != nil {
+			if classifySaveError(err) != saveErrorVanished {
+				errs = append(errs, fmt.Errorf("session %s: %w", info.Name, err))
+			}
+
+			continue
+		}
+
+		slept = append(slept, info.Name)
+		available += rss[info.Name]
+
+		if fresh, err := a.mem.System(); err == nil {
+			available = max(available, fresh.Available)
+		}
+	}
+
+	return slept, errors.Join(errs...)
+}
+
+// sessionMemory returns the RSS of each running session's pane process trees.
+func (a *App) sessionMemory() (map[string]uint64, error) {
+	pids, err := a.tmux.ListPanePIDs()
+	if err != nil {
+		return nil, fmt.Errorf("list pane pids: %w", err)
+	}
+
+	rss, err := a.mem.TreeRSS(pids)
+	if err != nil {
+		return nil, fmt.Errorf("sample session memory: %w", err)
+	}
+
+	return rss, nil
+}
+
+// canAutoSleep reports whether the daemon may put a running session to sleep
+// on its own.
+func (a *App) canAutoSleep(info tmux.SessionInfo) (bool, error) {
+	if info.Attached > 0 || a.autoSleepExcluded(info.Name) {
+		return false, nil
+	}
+
+	rec, err := a.store.Record(info.Name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("get session record: %w", err)
+	}
+
+	return !rec.Pinned, nil
+}
+
+func (a *App) autoSleepExcluded(session string) bool {
+	for _, pattern := range a.cfg.AutoSleep.Exclude {
+		if ok, _ := path.Match(strings.TrimSpace(pattern), session); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// autoSleep sleeps session and tells attached clients why it disappeared.
+func (a *App) autoSleep(session, reason string) error {
+	if err := a.Sleep(session); err != nil {
+		return err
+	}
+
+	a.log().Info("session auto-slept", "session", session, "reason", reason)
+
+	msg := fmt.Sprintf("lazy-tmux: session %s went to sleep (%s)", session, reason)
+	if err := a.tmux.DisplayMessage(msg); err != nil {
+		a.log().Warn("auto-sleep notification failed", "session", session, "error", err)
+	}
+
+	return nil
+}
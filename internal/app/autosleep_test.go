@@ -0,0 +1,185 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/memory"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestSleepIdleSkipsAttachedPinnedAndExcluded(t *testing.T) {
+	logPath := t.TempDir() + "/tmux.log"
+	fake := writeFakeTmuxForApp(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "list-sessions" ]; then
+  printf "idle\0371699989200\0370\n"
+  printf "busy\0371699999940\0370\n"
+  printf "attached\0371699989200\0371\n"
+  printf "pinned\0371699989200\0370\n"
+  printf "main\0371699989200\0370\n"
+  exit 0
+fi
+if [ "$1" = "list-clients" ]; then
+  echo "/dev/pts/1"
+  exit 0
+fi
+if [ "$1" = "display-message" ]; then
+  printf "0\0370\n"
+  exit 0
+fi
+if [ "$1" = "list-windows" ]; then
+  printf "0\037main\037layout\0371\n"
+  exit 0
+fi
+if [ "$1" = "list-panes" ]; then
+  printf "0\037/tmp\037zsh\0371\037111\037\n"
+  exit 0
+fi
+exit 0
+`)
+	t.Setenv("TMUX_LOG", logPath)
+
+	app := &App{
+		cfg: config.Config{AutoSleep: config.AutoSleepConfig{
+			IdleAfter: time.Hour,
+			Exclude:   []string{"ma*"},
+		}},
+		store: store.New(t.TempDir()),
+		tmux:  tmux.NewClient(fake),
+	}
+
+	if err := app.store.SaveSession(snapshot.SessionSnapshot{SessionName: "pinned"}); err != nil {
+		t.Fatalf("seed pinned session: %v", err)
+	}
+
+	if err := app.PinSession("pinned", true); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+
+	slept, err := app.SleepIdle(time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("SleepIdle error: %v", err)
+	}
+
+	if strings.Join(slept, ",") != "idle" {
+		t.Fatalf("expected only idle session slept, got %v", slept)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	log := string(data)
+	if strings.Count(log, "kill-session") != 1 || !strings.Contains(log, "kill-session -t =idle") {
+		t.Fatalf("expected only idle to be killed, got:\n%s", log)
+	}
+
+	if !strings.Contains(log, "display-message -c /dev/pts/1 lazy-tmux: session idle went to sleep (idle for 3h0m0s)") {
+		t.Fatalf("expected sleep notification, got:\n%s", log)
+	}
+
+	if _, err := app.store.LoadSession("idle"); err != nil {
+		t.Fatalf("expected idle session saved before sleeping: %v", err)
+	}
+}
+
+func TestSleepIdleDisabledByDefault(t *testing.T) {
+	app := &App{tmux: tmux.NewClient("false")}
+
+	slept, err := app.SleepIdle(time.Now())
+	if err != nil || slept != nil {
+		t.Fatalf("expected auto-sleep to be a no-op, got %v, %v", slept, err)
+	}
+}
+
+func TestSleepUnderPressureSleepsLeastRecentlyUsedFirst(t *testing.T) {
+	logPath := t.TempDir() + "/tmux.log"
+	fake := writeFakeTmuxForApp(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "list-sessions" ]; then
+  printf "newest\0371700000000\0370\n"
+  printf "oldest\0371600000000\0370\n"
+  printf "middle\0371650000000\0370\n"
+  printf "viewed\0371500000000\0371\n"
+  exit 0
+fi
+if [ "$1" = "list-panes" ] && [ "$2" = "-a" ]; then
+  printf "oldest\03710\nmiddle\03720\nnewest\03730\nviewed\03740\n"
+  exit 0
+fi
+if [ "$1" = "display-message" ]; then
+  printf "0\0370\n"
+  exit 0
+fi
+if [ "$1" = "list-windows" ]; then
+  printf "0\037main\037layout\0371\n"
+  exit 0
+fi
+if [ "$1" = "list-panes" ]; then
+  printf "0\037/tmp\037zsh\0371\037111\037\n"
+  exit 0
+fi
+exit 0
+`)
+	t.Setenv("TMUX_LOG", logPath)
+
+	proc := t.TempDir()
+	pages := uint64(1000<<20) / uint64(os.Getpagesize())
+	writeTestFile(t, proc+"/meminfo", "MemTotal: 10240000 kB\nMemAvailable: 512000 kB\n")
+
+	for _, pid := range []string{"10", "20", "30", "40"} {
+		writeTestFile(t, proc+"/"+pid+"/stat", pid+" (zsh) S 1 "+strings.Repeat("0 ", 19)+fmt.Sprint(pages)+" 0\n")
+	}
+
+	threshold, err := memory.ParseThreshold("10%")
+	if err != nil {
+		t.Fatalf("ParseThreshold: %v", err)
+	}
+
+	app := &App{
+		cfg:   config.Config{AutoSleep: config.AutoSleepConfig{MinAvailable: threshold}},
+		store: store.New(t.TempDir()),
+		tmux:  tmux.NewClient(fake),
+		mem:   memory.Sampler{ProcDir: proc, CgroupDir: t.TempDir()},
+	}
+
+	slept, err := app.SleepUnderPressure()
+	if err != nil {
+		t.Fatalf("SleepUnderPressure error: %v", err)
+	}
+
+	// 500M available, 1000M wanted: each sleep frees about 1000M of RSS.
+	if strings.Join(slept, ",") != "oldest" {
+		t.Fatalf("expected only the least recently used detached session slept, got %v", slept)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	if !strings.Contains(string(data), "kill-session -t =oldest") || strings.Contains(string(data), "kill-session -t =viewed") {
+		t.Fatalf("unexpected sleeps:\n%s", data)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
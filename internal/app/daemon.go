@@ -1,10 +1,15 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -26,47 +31,567 @@ var newDaemonTicker = func(d time.Duration) daemonTicker {
 	return &realDaemonTicker{Ticker: time.NewTicker(d)}
 }
 
+// trashPurgeInterval is how often the daemon drops trash entries older than
+// the configured retention.
+const trashPurgeInterval = time.Hour
+
+// serverWatchInterval is how often the daemon checks that its tmux server is
+// still alive.
+const serverWatchInterval = 5 * time.Second
+
+var newServerWatchTicker = func(d time.Duration) daemonTicker {
+	return &realDaemonTicker{Ticker: time.NewTicker(d)}
+}
+
+type realDaemonTimer struct {
+	*time.Timer
+}
+
+func (t *realDaemonTimer) Chan() <-chan time.Time { return t.Timer.C }
+
+func (t *realDaemonTimer) Stop() { t.Timer.Stop() }
+
+// newDaemonRetryTimer fires once when a save that failed with a retriable
+// error should be retried.
+var newDaemonRetryTimer = func(d time.Duration) daemonTicker {
+	return &realDaemonTimer{Timer: time.NewTimer(d)}
+}
+
+var notifyDaemonSignals = func(ch chan<- os.Signal) func() {
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	return func() { signal.Stop(ch) }
+}
+
+// daemon is the state of one RunDaemon call shared with its control socket.
+// Saves only run on the RunDaemon goroutine, so control requests that need a
+// save or a shutdown are queued to it and wait for any save in progress.
+type daemon struct {
+	app      *App
+	interval time.Duration
+	started  time.Time
+	requests chan daemonControl
+	done     chan struct{}
+
+	mu            sync.Mutex
+	paused        bool
+	lastSave      time.Time
+	lastError     string
+	failures      int
+	totalFailures int
+	nextRetry     time.Time
+	alerted       bool
+	sleepIdle     time.Duration
+	sleepMemory   string
+	slept         int
+	sessions      map[string]DaemonSessionStatus
+	lastPurge     time.Time
+}
+
+type daemonControl struct {
+	command string
+	reply   chan daemonResponse
+}
+
 func (a *App) RunDaemon(interval time.Duration) error {
 	if interval <= 0 {
 		interval = a.cfg.SaveInterval
 	}
 
-	unlock, err := acquireLock(a.tmux.SocketPath())
+	socketPath := a.tmux.SocketPath()
+
+	unlock, err := acquireLock(socketPath)
 	if err != nil {
 		return err
 	}
 
 	defer unlock()
 
-	ticker := newDaemonTicker(interval)
-	defer ticker.Stop()
+	if _, err := a.MigrateHostData(); err != nil {
+		a.log().Warn("moving snapshots into host namespace failed", "host", a.host, "error", err)
+	}
+
+	d := &daemon{
+		app:      a,
+		interval: interval,
+		started:  time.Now(),
+		requests: make(chan daemonControl),
+		done:     make(chan struct{}),
+		sessions: map[string]DaemonSessionStatus{},
+	}
+	d.sleepIdle = a.cfg.AutoSleep.IdleAfter
+	d.sleepMemory = a.cfg.AutoSleep.MinAvailable.String()
 
-	if err := a.runDaemonSaveAll(); err != nil {
-		fmt.Fprintf(os.Stderr, "lazy-tmux daemon save error: %v\n", err)
+	stopControl, err := d.listen(socketPath)
+	if err != nil {
+		a.log().Warn("daemon control socket unavailable", "error", err)
 	}
 
-	for range ticker.Chan() {
-		if err := a.runDaemonSaveAll(); err != nil {
-			fmt.Fprintf(os.Stderr, "lazy-tmux daemon save error: %v\n", err)
+	defer func() {
+		close(d.done)
+
+		if stopControl != nil {
+			stopControl()
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	stopSignals := notifyDaemonSignals(signals)
+
+	defer stopSignals()
+
+	watch := newServerWatchTicker(serverWatchInterval)
+	defer watch.Stop()
+
+	ticker := newDaemonTicker(interval)
+	defer func() { ticker.Stop() }()
+
+	a.log().Info("daemon started", "pid", os.Getpid(), "interval", interval, "socket", socketPath)
+
+	defer a.log().Info("daemon stopped")
+
+	var retry daemonTicker
+
+	defer func() {
+		if retry != nil {
+			retry.Stop()
+		}
+	}()
+
+	retry = d.afterSave(d.save(), retry)
+	d.collectBlobs()
+	d.purgeTrash(time.Now())
+
+	for {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				ticker = d.reload(ticker)
+				continue
+			}
+
+			a.log().Info("daemon received signal, saving before exit", "signal", sig.String())
+			d.logSave(d.save())
+
+			return nil
+		case <-watch.Chan():
+			if a.tmuxServerGone() {
+				a.log().Info("tmux server is gone, daemon exiting", "socket", socketPath)
+				return nil
+			}
+
+			if !d.isPaused() {
+				d.sleepUnderPressure()
+			}
+		case _, ok := <-ticker.Chan():
+			if !ok {
+				return nil
+			}
+
+			if d.isPaused() {
+				continue
+			}
+
+			retry = d.afterSave(d.save(), retry)
+			d.sleepIdleSessions()
+			d.purgeTrash(time.Now())
+		case <-retryChan(retry):
+			retry = nil
+
+			if d.isPaused() {
+				d.setNextRetry(time.Time{})
+				continue
+			}
+
+			retry = d.afterSave(d.save(), retry)
+		case req := <-d.requests:
+			switch req.command {
+			case DaemonCmdSaveNow:
+				err := d.save()
+				retry = d.afterSave(err, retry)
+				req.reply <- d.response(err)
+			case DaemonCmdStop:
+				req.reply <- d.response(nil)
+				return nil
+			}
 		}
 	}
+}
+
+func (d *daemon) save() error {
+	started := time.Now()
+	err := d.app.runDaemonSaveAll(d.recordSession)
+	d.app.log().Debug("daemon save finished", "duration", time.Since(started), "ok", err == nil)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	return nil
+	d.lastSave = time.Now()
+	d.lastError = ""
+
+	if err != nil {
+		d.lastError = err.Error()
+		d.failures++
+		d.totalFailures++
+	} else {
+		d.failures = 0
+		d.alerted = false
+	}
+
+	return err
 }
 
-func acquireLock(socketPath string) (func(), error) {
-	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
-	if runtimeDir == "" {
-		runtimeDir = os.TempDir()
+// afterSave logs the result of a save and replaces any pending retry: failures
+// tmux may recover from are retried with backoff, storage failures alert the
+// user once until a save succeeds again.
+func (d *daemon) afterSave(err error, retry daemonTicker) daemonTicker {
+	if retry != nil {
+		retry.Stop()
 	}
 
-	if err := os.MkdirAll(runtimeDir, 0o755); err != nil {
-		return nil, fmt.Errorf("create runtime dir: %w", err)
+	d.logSave(err)
+
+	class := classifySaveError(err)
+	if err == nil || !class.retriable() {
+		d.setNextRetry(time.Time{})
+		return nil
 	}
 
-	hash := fnv.New64a()
-	_, _ = hash.Write([]byte(socketPath))
-	lockPath := filepath.Join(runtimeDir, fmt.Sprintf("lazy-tmux-%x.lock", hash.Sum64()))
+	d.mu.Lock()
+	delay := retryBackoff(d.failures, d.interval)
+	d.nextRetry = time.Now().Add(delay)
+	d.mu.Unlock()
+
+	d.app.log().Info("daemon save will be retried", "delay", delay)
+
+	return newDaemonRetryTimer(delay)
+}
+
+// sleepIdleSessions runs auto-sleep after a regular save.
+func (d *daemon) sleepIdleSessions() {
+	slept, err := d.app.SleepIdle(time.Now())
+	if err != nil {
+		d.app.log().Error("daemon auto-sleep failed", "error", err)
+	}
+
+	d.recordSlept(slept)
+}
+
+// collectBlobs removes scrollback blobs left behind by interrupted saves or
+// by releases that could not run, once per daemon start.
+func (d *daemon) collectBlobs() {
+	removed, err := d.app.store.GCBlobs()
+	if err != nil {
+		d.app.log().Warn("daemon blob gc failed", "error", err)
+		return
+	}
+
+	d.app.log().Debug("daemon blob gc finished", "removed", removed)
+}
+
+// purgeTrash drops trash entries older than the configured retention, at most
+// once per trashPurgeInterval.
+func (d *daemon) purgeTrash(now time.Time) {
+	retention := d.app.cfg.TrashRetention
+	if retention <= 0 || now.Sub(d.lastPurge) < trashPurgeInterval {
+		return
+	}
+
+	d.lastPurge = now
+
+	removed, err := d.app.EmptyTrash(retention)
+	if err != nil {
+		d.app.log().Error("daemon trash purge failed", "error", err)
+		return
+	}
+
+	if removed > 0 {
+		d.app.log().Info("daemon purged trash", "removed", removed, "retention", retention)
+	}
+}
+
+// sleepUnderPressure runs memory-pressure sleeping on every server check.
+func (d *daemon) sleepUnderPressure() {
+	slept, err := d.app.SleepUnderPressure()
+	if err != nil {
+		d.app.log().Error("daemon memory-pressure sleep failed", "error", err)
+	}
+
+	d.recordSlept(slept)
+}
+
+func (d *daemon) recordSlept(slept []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.slept += len(slept)
+
+	for _, session := range slept {
+		delete(d.sessions, session)
+	}
+}
+
+func retryChan(retry daemonTicker) <-chan time.Time {
+	if retry == nil {
+		return nil
+	}
+
+	return retry.Chan()
+}
+
+func (d *daemon) setNextRetry(at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.nextRetry = at
+}
+
+// reload re-reads the configuration and restarts the ticker when the save
+// interval changed. On error the current configuration is kept.
+func (d *daemon) reload(ticker daemonTicker) daemonTicker {
+	if d.app.reload == nil {
+		return ticker
+	}
+
+	cfg, err := d.app.reload()
+	if err != nil {
+		d.app.log().Error("daemon config reload failed", "error", err)
+		return ticker
+	}
+
+	d.app.applyConfig(cfg)
+	d.app.log().Info("daemon reloaded configuration", "interval", cfg.SaveInterval)
+
+	d.mu.Lock()
+	d.sleepIdle = cfg.AutoSleep.IdleAfter
+	d.sleepMemory = cfg.AutoSleep.MinAvailable.String()
+	changed := cfg.SaveInterval > 0 && cfg.SaveInterval != d.interval
+	if changed {
+		d.interval = cfg.SaveInterval
+	}
+	d.mu.Unlock()
+
+	if !changed {
+		return ticker
+	}
+
+	ticker.Stop()
+
+	return newDaemonTicker(cfg.SaveInterval)
+}
+
+func (d *daemon) logSave(err error) {
+	if err == nil {
+		return
+	}
+
+	class := classifySaveError(err)
+
+	d.mu.Lock()
+	failures := d.failures
+	alert := class == saveErrorStorage && !d.alerted
+	if alert {
+		d.alerted = true
+	}
+	d.mu.Unlock()
+
+	d.app.log().Error("daemon save failed", "error", err, "class", class.String(), "failures", failures)
+
+	if alert {
+		d.alert(err)
+	}
+}
+
+// alert tells attached tmux clients that saving needs the user's attention.
+func (d *daemon) alert(err error) {
+	if d.app.tmux == nil {
+		return
+	}
+
+	if dErr := d.app.tmux.DisplayMessage("lazy-tmux: autosave failed: " + err.Error()); dErr != nil {
+		d.app.log().Warn("daemon alert failed", "error", dErr)
+	}
+}
+
+func (d *daemon) recordSession(session string, took time.Duration, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	class := classifySaveError(err)
+	if err != nil && class == saveErrorVanished {
+		delete(d.sessions, session)
+		return
+	}
+
+	status := DaemonSessionStatus{
+		Session:  session,
+		SavedAt:  time.Now(),
+		Duration: took,
+	}
+	if err != nil {
+		status.Error = err.Error()
+		status.Class = class.String()
+		status.Failures = d.sessions[session].Failures + 1
+	}
+
+	d.sessions[session] = status
+}
+
+func (d *daemon) isPaused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.paused
+}
+
+func (d *daemon) setPaused(paused bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.paused = paused
+}
+
+func (d *daemon) status() DaemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := DaemonStatus{
+		PID:       os.Getpid(),
+		StartedAt: d.started,
+		Interval:  d.interval,
+		Paused:    d.paused,
+		LastSave:  d.lastSave,
+		LastError: d.lastError,
+		Failures:  d.failures,
+		Total:     d.totalFailures,
+		NextRetry: d.nextRetry,
+		SleepIdle: d.sleepIdle,
+		SleepMem:  d.sleepMemory,
+		Slept:     d.slept,
+		Sessions:  make([]DaemonSessionStatus, 0, len(d.sessions)),
+	}
+
+	for _, s := range d.sessions {
+		status.Sessions = append(status.Sessions, s)
+	}
+
+	sort.Slice(status.Sessions, func(i, j int) bool {
+		return status.Sessions[i].Session < status.Sessions[j].Session
+	})
+
+	return status
+}
+
+func (d *daemon) response(err error) daemonResponse {
+	status := d.status()
+	resp := daemonResponse{OK: err == nil, Status: &status}
+
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	return resp
+}
+
+// handle runs one control command and returns the response for the client.
+func (d *daemon) handle(command string) daemonResponse {
+	switch command {
+	case DaemonCmdStatus:
+		return d.response(nil)
+	case DaemonCmdPause, DaemonCmdResume:
+		d.setPaused(command == DaemonCmdPause)
+		d.app.log().Info("daemon autosave toggled", "paused", command == DaemonCmdPause)
+		return d.response(nil)
+	case DaemonCmdSaveNow, DaemonCmdStop:
+		req := daemonControl{command: command, reply: make(chan daemonResponse, 1)}
+
+		select {
+		case d.requests <- req:
+		case <-d.done:
+			return daemonResponse{Error: "daemon is shutting down"}
+		}
+
+		select {
+		case resp := <-req.reply:
+			return resp
+		case <-d.done:
+			select {
+			case resp := <-req.reply:
+				return resp
+			default:
+				return daemonResponse{Error: "daemon is shutting down"}
+			}
+		}
+	default:
+		return daemonResponse{Error: fmt.Sprintf("unknown daemon command: %s", command)}
+	}
+}
+
+func (d *daemon) listen(socketPath string) (func(), error) {
+	path, err := daemonRuntimePath(socketPath, ".sock")
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock is held, so any existing socket was left by a dead daemon.
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on control socket: %w", err)
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("chmod control socket: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				serveDaemonConn(conn, d.handle)
+			}()
+		}
+	}()
+
+	return func() {
+		_ = listener.Close()
+		wg.Wait()
+		_ = os.Remove(path)
+	}, nil
+}
+
+// tmuxServerGone probes the daemon's tmux server. A server that was killed
+// or crashed leaves its socket file behind, so only a failed connection tells
+// that it is gone.
+func (a *App) tmuxServerGone() bool {
+	err := a.tmux.Probe()
+
+	return err != nil && classifySaveError(err) == saveErrorUnreachable
+}
+
+func acquireLock(socketPath string) (func(), error) {
+	lockPath, err := daemonRuntimePath(socketPath, ".lock")
+	if err != nil {
+		return nil, err
+	}
 
 	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
@@ -75,7 +600,7 @@ func acquireLock(socketPath string) (func(), error) {
 
 	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
 		_ = file.Close()
-		return nil, fmt.Errorf("daemon already running")
+		return nil, fmt.Errorf("daemon already running (control it with `lazy-tmux daemon status|stop`)")
 	}
 
 	return func() {
@@ -83,3 +608,21 @@ func acquireLock(socketPath string) (func(), error) {
 		_ = file.Close()
 	}, nil
 }
+
+// daemonRuntimePath returns the per-tmux-server runtime file with the given
+// extension, e.g. the daemon lock or control socket.
+func daemonRuntimePath(socketPath, ext string) (string, error) {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = os.TempDir()
+	}
+
+	if err := os.MkdirAll(runtimeDir, 0o755); err != nil {
+		return "", fmt.Errorf("create runtime dir: %w", err)
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(socketPath))
+
+	return filepath.Join(runtimeDir, fmt.Sprintf("lazy-tmux-%x%s", hash.Sum64(), ext)), nil
+}
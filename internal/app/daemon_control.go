@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	DaemonCmdStatus  = "status"
+	DaemonCmdSaveNow = "save-now"
+	DaemonCmdPause   = "pause"
+	DaemonCmdResume  = "resume"
+	DaemonCmdStop    = "stop"
+)
+
+const daemonControlTimeout = 5 * time.Second
+
+// DaemonStatus describes a running daemon. Failures counts failed saves since
+// the last successful one and Total all failed saves since it started; Slept
+// counts sessions it put to sleep after SleepIdle without activity or while
+// available memory was below SleepMem.
+type DaemonStatus struct {
+	PID       int                   `json:"pid"`
+	StartedAt time.Time             `json:"started_at"`
+	Interval  time.Duration         `json:"interval"`
+	Paused    bool                  `json:"paused"`
+	LastSave  time.Time             `json:"last_save"`
+	LastError string                `json:"last_error,omitempty"`
+	Failures  int                   `json:"failures,omitempty"`
+	Total     int                   `json:"total_failures,omitempty"`
+	NextRetry time.Time             `json:"next_retry,omitzero"`
+	SleepIdle time.Duration         `json:"sleep_idle,omitempty"`
+	SleepMem  string                `json:"sleep_min_available,omitempty"`
+	Slept     int                   `json:"slept,omitempty"`
+	Sessions  []DaemonSessionStatus `json:"sessions,omitempty"`
+}
+
+type DaemonSessionStatus struct {
+	Session  string        `json:"session"`
+	SavedAt  time.Time     `json:"saved_at"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Class    string        `json:"class,omitempty"`
+	Failures int           `json:"failures,omitempty"`
+}
+
+type daemonRequest struct {
+	Command string `json:"command"`
+}
+
+type daemonResponse struct {
+	OK     bool          `json:"ok"`
+	Error  string        `json:"error,omitempty"`
+	Status *DaemonStatus `json:"status,omitempty"`
+}
+
+// ControlDaemon sends a command to the daemon running for the current tmux
+// server and returns its status after the command completed.
+func (a *App) ControlDaemon(command string) (DaemonStatus, error) {
+	command = strings.TrimSpace(command)
+
+	switch command {
+	case DaemonCmdStatus, DaemonCmdSaveNow, DaemonCmdPause, DaemonCmdResume, DaemonCmdStop:
+	default:
+		return DaemonStatus{}, fmt.Errorf("unknown daemon command: %s", command)
+	}
+
+	path, err := daemonRuntimePath(a.tmux.SocketPath(), ".sock")
+	if err != nil {
+		return DaemonStatus{}, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, daemonControlTimeout)
+	if err != nil {
+		return DaemonStatus{}, fmt.Errorf("daemon not running: %w", err)
+	}
+
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Command: command}); err != nil {
+		return DaemonStatus{}, fmt.Errorf("send daemon command: %w", err)
+	}
+
+	var resp daemonResponse
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		return DaemonStatus{}, fmt.Errorf("read daemon response: %w", err)
+	}
+
+	var status DaemonStatus
+	if resp.Status != nil {
+		status = *resp.Status
+	}
+
+	if !resp.OK {
+		if resp.Error == "" {
+			resp.Error = "daemon command failed"
+		}
+
+		return status, errors.New(resp.Error)
+	}
+
+	return status, nil
+}
+
+func serveDaemonConn(conn net.Conn, handle func(command string) daemonResponse) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(daemonControlTimeout))
+
+	var req daemonRequest
+
+	resp := daemonResponse{}
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("decode daemon request: %v", err)
+	} else {
+		resp = handle(req.Command)
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(daemonControlTimeout))
+	_ = json.NewEncoder(conn).Encode(resp)
+}
@@ -71,6 +71,7 @@ exit 0
 		"--interval", "50ms",
 		"--data-dir", dataDir,
 		"--tmux-bin", tmuxBin,
+		"--log-file", "-",
 	)
 	var logBuf bytes.Buffer
 	cmd.Stdout = &logBuf
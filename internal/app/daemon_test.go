@@ -1,14 +1,20 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
-	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/logging"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
 	"github.com/alchemmist/lazy-tmux/internal/tmux"
 )
 
@@ -32,13 +38,14 @@ fi
 exit 0
 `)
 	app := &App{
-		cfg:  config.Config{SaveInterval: time.Second},
-		tmux: tmux.NewClient(fake),
+		cfg:   config.Config{SaveInterval: time.Second},
+		tmux:  tmux.NewClient(fake),
+		store: store.New(t.TempDir()),
 	}
 
 	var calls int
 
-	app.saveAllFn = func() error {
+	saveAll := func() error {
 		calls++
 		if calls == 2 {
 			return fmt.Errorf("boom")
@@ -60,38 +67,472 @@ exit 0
 		return ticker
 	}
 
-	read, write, err := os.Pipe()
-	if err != nil {
-		t.Fatalf("open pipe: %v", err)
+	var logs bytes.Buffer
+
+	app.SetLogger(logging.New(&logs, logging.FormatText, slog.LevelInfo))
+	app.saveAllFn = saveAll
+
+	if err := app.RunDaemon(10 * time.Millisecond); err != nil {
+		t.Fatalf("RunDaemon error: %v", err)
+	}
+
+	if !strings.Contains(logs.String(), `msg="daemon save failed" error=boom`) {
+		t.Fatalf("expected logged error, got %q", logs.String())
+	}
+
+	if calls != 2 {
+		t.Fatalf("unexpected saveAll calls: %d", calls)
+	}
+}
+
+func TestRunDaemonControlSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "display-message" ]; then
+  echo "/tmp/fake-control.sock"
+  exit 0
+fi
+if [ "$1" = "list-sessions" ]; then
+  printf "alpha\n"
+  exit 0
+fi
+if [ "$1" = "has-session" ]; then
+  exit 0
+fi
+echo "boom" >&2
+exit 1
+`)
+	app := &App{
+		cfg:   config.Config{SaveInterval: time.Hour},
+		store: store.New(t.TempDir()),
+		tmux:  tmux.NewClient(fake),
+	}
+
+	origTicker := newDaemonTicker
+	defer func() { newDaemonTicker = origTicker }()
+
+	newDaemonTicker = func(time.Duration) daemonTicker {
+		return &testDaemonTicker{ch: make(chan time.Time)}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- app.RunDaemon(time.Hour) }()
+
+	var (
+		status DaemonStatus
+		err    error
+	)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err = app.ControlDaemon(DaemonCmdStatus)
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("daemon control socket not ready: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status.PID != os.Getpid() || status.Interval != time.Hour || status.Paused {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	status, err = app.ControlDaemon(DaemonCmdSaveNow)
+	if err == nil || !strings.Contains(err.Error(), "capture session") {
+		t.Fatalf("expected save-now to report the save error, got %v", err)
+	}
+
+	if status.LastSave.IsZero() || status.LastError == "" {
+		t.Fatalf("expected last save result in status, got %+v", status)
+	}
+
+	if len(status.Sessions) != 1 || status.Sessions[0].Session != "alpha" || status.Sessions[0].Error == "" {
+		t.Fatalf("expected failed save of alpha in status, got %+v", status.Sessions)
+	}
+
+	if status, err = app.ControlDaemon(DaemonCmdPause); err != nil || !status.Paused {
+		t.Fatalf("pause: status=%+v err=%v", status, err)
+	}
+
+	if status, err = app.ControlDaemon(DaemonCmdResume); err != nil || status.Paused {
+		t.Fatalf("resume: status=%+v err=%v", status, err)
+	}
+
+	if _, err := app.ControlDaemon(DaemonCmdStop); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunDaemon error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+
+	if _, err := app.ControlDaemon(DaemonCmdStatus); err == nil || !strings.Contains(err.Error(), "daemon not running") {
+		t.Fatalf("expected daemon not running after stop, got %v", err)
+	}
+}
+
+func TestControlDaemonRejectsUnknownCommand(t *testing.T) {
+	app := &App{tmux: tmux.NewClient("true")}
+
+	if _, err := app.ControlDaemon("reboot"); err == nil || !strings.Contains(err.Error(), "unknown daemon command") {
+		t.Fatalf("expected unknown command error, got %v", err)
+	}
+}
+
+func TestRunDaemonReloadsOnHangupAndSavesOnTerm(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "display-message" ]; then
+  echo "/tmp/fake-signals.sock"
+fi
+exit 0
+`)
+	app := &App{
+		cfg:   config.Config{SaveInterval: time.Minute},
+		tmux:  tmux.NewClient(fake),
+		store: store.New(t.TempDir()),
 	}
 
-	origErr := os.Stderr
-	os.Stderr = write
+	var saves int
 
+	app.saveAllFn = func() error {
+		saves++
+		return nil
+	}
+	app.SetReloader(func() (config.Config, error) {
+		return config.Config{TmuxBin: fake, DataDir: t.TempDir(), SaveInterval: 2 * time.Minute}, nil
+	})
+
+	origTicker, origSignals := newDaemonTicker, notifyDaemonSignals
 	defer func() {
-		os.Stderr = origErr
+		newDaemonTicker, notifyDaemonSignals = origTicker, origSignals
+	}()
+
+	var intervals []time.Duration
+
+	newDaemonTicker = func(d time.Duration) daemonTicker {
+		intervals = append(intervals, d)
+		return &testDaemonTicker{ch: make(chan time.Time)}
+	}
+	notifyDaemonSignals = func(ch chan<- os.Signal) func() {
+		go func() {
+			ch <- syscall.SIGHUP
+			ch <- syscall.SIGTERM
+		}()
+
+		return func() {}
+	}
+
+	if err := app.RunDaemon(time.Minute); err != nil {
+		t.Fatalf("RunDaemon error: %v", err)
+	}
+
+	if len(intervals) != 2 || intervals[0] != time.Minute || intervals[1] != 2*time.Minute {
+		t.Fatalf("expected ticker restarted with reloaded interval, got %v", intervals)
+	}
+
+	if app.cfg.SaveInterval != 2*time.Minute {
+		t.Fatalf("expected reloaded config to be applied, got %+v", app.cfg)
+	}
+
+	if saves != 2 {
+		t.Fatalf("expected initial and final save, got %d", saves)
+	}
+}
+
+func TestRunDaemonExitsWhenTmuxServerIsGone(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	// A killed server leaves its socket file behind.
+	socket := filepath.Join(t.TempDir(), "default")
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatalf("write socket: %v", err)
+	}
+
+	t.Setenv("TMUX_SOCKET", socket)
+
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "display-message" ]; then
+  echo "$TMUX_SOCKET"
+fi
+if [ "$1" = "list-sessions" ]; then
+  echo "error connecting to $TMUX_SOCKET (Connection refused)" >&2
+  exit 1
+fi
+exit 0
+`)
+	app := &App{
+		cfg:   config.Config{SaveInterval: time.Minute},
+		tmux:  tmux.NewClient(fake),
+		store: store.New(t.TempDir()),
+	}
 
-		write.Close()
+	origTicker, origWatch := newDaemonTicker, newServerWatchTicker
+	defer func() {
+		newDaemonTicker, newServerWatchTicker = origTicker, origWatch
 	}()
 
-	if err := app.RunDaemon(10 * time.Millisecond); err != nil {
+	newDaemonTicker = func(time.Duration) daemonTicker {
+		return &testDaemonTicker{ch: make(chan time.Time)}
+	}
+	newServerWatchTicker = func(time.Duration) daemonTicker {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+
+		return &testDaemonTicker{ch: ch}
+	}
+
+	var logs bytes.Buffer
+
+	app.SetLogger(logging.New(&logs, logging.FormatText, slog.LevelInfo))
+	app.saveAllFn = func() error { return nil }
+
+	if err := app.RunDaemon(time.Minute); err != nil {
+		t.Fatalf("RunDaemon error: %v", err)
+	}
+
+	if !strings.Contains(logs.String(), `msg="tmux server is gone, daemon exiting" socket=`+socket) {
+		t.Fatalf("expected exit message, got %q", logs.String())
+	}
+}
+
+func TestTmuxServerGoneProbesTheServer(t *testing.T) {
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "list-sessions" ] && [ -n "$TMUX_DOWN" ]; then
+  echo "no server running on /tmp/tmux-1000/default" >&2
+  exit 1
+fi
+exit 0
+`)
+	app := &App{tmux: tmux.NewClient(fake)}
+
+	if app.tmuxServerGone() {
+		t.Fatal("expected a server that answers to be running")
+	}
+
+	t.Setenv("TMUX_DOWN", "1")
+
+	if !app.tmuxServerGone() {
+		t.Fatal("expected an unreachable server to be gone")
+	}
+}
+
+func TestRunDaemonRetriesUnreachableTmuxWithBackoff(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "display-message" ]; then
+  echo "/tmp/fake-retry.sock"
+fi
+exit 0
+`)
+	app := &App{
+		cfg:   config.Config{SaveInterval: time.Minute},
+		tmux:  tmux.NewClient(fake),
+		store: store.New(t.TempDir()),
+	}
+
+	origTicker, origSignals, origRetry := newDaemonTicker, notifyDaemonSignals, newDaemonRetryTimer
+	defer func() {
+		newDaemonTicker, notifyDaemonSignals, newDaemonRetryTimer = origTicker, origSignals, origRetry
+	}()
+
+	var (
+		signals chan<- os.Signal
+		delays  []time.Duration
+		saves   int
+	)
+
+	newDaemonTicker = func(time.Duration) daemonTicker {
+		return &testDaemonTicker{ch: make(chan time.Time)}
+	}
+	notifyDaemonSignals = func(ch chan<- os.Signal) func() {
+		signals = ch
+		return func() {}
+	}
+	newDaemonRetryTimer = func(d time.Duration) daemonTicker {
+		delays = append(delays, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+
+		return &testDaemonTicker{ch: ch}
+	}
+
+	app.saveAllFn = func() error {
+		saves++
+		if saves <= 2 {
+			return fmt.Errorf("list sessions: %w", fmt.Errorf("no server running on /tmp/fake-retry.sock"))
+		}
+
+		if saves == 3 {
+			signals <- syscall.SIGTERM
+		}
+
+		return nil
+	}
+
+	if err := app.RunDaemon(time.Minute); err != nil {
 		t.Fatalf("RunDaemon error: %v", err)
 	}
 
-	write.Close()
+	if saves != 4 {
+		t.Fatalf("expected two retries, a successful save and a final save, got %d saves", saves)
+	}
+
+	if len(delays) != 2 {
+		t.Fatalf("expected two retry delays, got %v", delays)
+	}
+
+	if delays[0] < 500*time.Millisecond || delays[0] > time.Second ||
+		delays[1] < time.Second || delays[1] > 2*time.Second {
+		t.Fatalf("expected exponential backoff, got %v", delays)
+	}
+}
 
-	out, err := io.ReadAll(read)
+func TestDaemonStatusCountsFailures(t *testing.T) {
+	app := &App{tmux: tmux.NewClient("true")}
+	d := &daemon{app: app, interval: time.Minute, sessions: map[string]DaemonSessionStatus{}}
+
+	origRetry := newDaemonRetryTimer
+	defer func() { newDaemonRetryTimer = origRetry }()
+
+	newDaemonRetryTimer = func(time.Duration) daemonTicker {
+		return &testDaemonTicker{ch: make(chan time.Time)}
+	}
+
+	app.saveAllFn = func() error { return fmt.Errorf("tmux: lost server") }
+	retry := d.afterSave(d.save(), nil)
+	retry = d.afterSave(d.save(), retry)
+
+	status := d.status()
+	if retry == nil || status.Failures != 2 || status.Total != 2 || status.NextRetry.IsZero() {
+		t.Fatalf("expected two failures and a pending retry, got %+v", status)
+	}
+
+	d.recordSession("alpha", time.Millisecond, fmt.Errorf("tmux: lost server"))
+	d.recordSession("alpha", time.Millisecond, fmt.Errorf("tmux: lost server"))
+	d.recordSession("gone", time.Millisecond, fmt.Errorf("capture session: %w", tmux.ErrSessionNotFound))
+
+	status = d.status()
+	if len(status.Sessions) != 1 || status.Sessions[0].Failures != 2 || status.Sessions[0].Class != "unreachable" {
+		t.Fatalf("expected per-session failure count without vanished session, got %+v", status.Sessions)
+	}
+
+	app.saveAllFn = func() error { return nil }
+	if retry = d.afterSave(d.save(), retry); retry != nil {
+		t.Fatal("expected pending retry to be cleared after a successful save")
+	}
+
+	status = d.status()
+	if status.Failures != 0 || status.Total != 2 || !status.NextRetry.IsZero() {
+		t.Fatalf("expected failure streak reset, got %+v", status)
+	}
+}
+
+func TestRunDaemonAlertsOnceOnStorageErrors(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	logPath := t.TempDir() + "/tmux.log"
+	fake := writeFakeTmuxForApp(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "display-message" ] && [ "$2" = "-p" ]; then
+  echo "/tmp/fake-alert.sock"
+fi
+if [ "$1" = "list-clients" ]; then
+  echo "/dev/pts/7"
+fi
+exit 0
+`)
+	t.Setenv("TMUX_LOG", logPath)
+
+	app := &App{
+		cfg:   config.Config{SaveInterval: time.Minute},
+		tmux:  tmux.NewClient(fake),
+		store: store.New(t.TempDir()),
+	}
+
+	origTicker := newDaemonTicker
+	defer func() { newDaemonTicker = origTicker }()
+
+	newDaemonTicker = func(time.Duration) daemonTicker {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		close(ch)
+
+		return &testDaemonTicker{ch: ch}
+	}
+
+	app.saveAllFn = func() error {
+		return fmt.Errorf("save session: %w", &os.PathError{Op: "write", Path: "/data", Err: syscall.ENOSPC})
+	}
+
+	if err := app.RunDaemon(time.Minute); err != nil {
+		t.Fatalf("RunDaemon error: %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
 	if err != nil {
-		t.Fatalf("read stderr: %v", err)
+		t.Fatalf("read log: %v", err)
 	}
 
-	read.Close()
+	if got := strings.Count(string(data), "display-message -c /dev/pts/7 lazy-tmux: autosave failed:"); got != 1 {
+		t.Fatalf("expected a single alert for two failed saves, got %d:\n%s", got, data)
+	}
+}
 
-	if !strings.Contains(string(out), "lazy-tmux daemon save error: boom") {
-		t.Fatalf("expected logged error, got %q", string(out))
+func TestDaemonPurgeTrashHonoursRetention(t *testing.T) {
+	st := store.New(t.TempDir())
+
+	if err := st.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "old",
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0}}}},
+	}); err != nil {
+		t.Fatalf("save: %v", err)
 	}
 
-	if calls != 2 {
-		t.Fatalf("unexpected saveAll calls: %d", calls)
+	if _, err := st.TrashSession("old"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	d := &daemon{app: &App{cfg: config.Config{TrashRetention: time.Hour}, store: st}}
+
+	count := func() int {
+		entries, err := st.ListTrash()
+		if err != nil {
+			t.Fatalf("list trash: %v", err)
+		}
+
+		return len(entries)
+	}
+
+	now := time.Now()
+
+	d.purgeTrash(now)
+
+	if count() != 1 {
+		t.Fatal("expected a fresh trash entry to be kept")
+	}
+
+	// Within the purge interval nothing runs, even with a shorter retention.
+	d.app.cfg.TrashRetention = time.Nanosecond
+	d.purgeTrash(now.Add(time.Minute))
+
+	if count() != 1 {
+		t.Fatal("expected no purge before trashPurgeInterval passed")
+	}
+
+	d.purgeTrash(now.Add(trashPurgeInterval))
+
+	if count() != 0 {
+		t.Fatal("expected the entry past its retention to be purged")
 	}
 }
@@ -0,0 +1,418 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/picker"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// With host namespaces on, each machine sharing a (synced) data dir keeps its
+// snapshots in hosts/<name>; the servers/<id> namespaces nest below it.
+const hostsDirName = "hosts"
+
+// hostsMigratedFileName marks a data dir whose legacy snapshots were moved
+// into a host namespace, or found nothing to move.
+const hostsMigratedFileName = ".migrated"
+
+// legacyDataEntries make up a data dir without host namespaces. They move
+// into the host namespace once, see MigrateHostData.
+var legacyDataEntries = []string{
+	"index.json", "blob-owners.json", "sessions", "blobs", "trash", "scrollback", serversDirName,
+}
+
+// hostFor returns the host namespace selected by cfg, or "" when snapshots
+// are not namespaced by host.
+func hostFor(cfg config.Config) string {
+	if cfg.Host.Profile != "" {
+		return cfg.Host.Profile
+	}
+
+	if !cfg.Host.Namespace {
+		return ""
+	}
+
+	name, err := os.Hostname()
+	name, _, _ = strings.Cut(name, ".")
+
+	if err != nil || name == "" || config.ValidateProfile(name) != nil {
+		return "localhost"
+	}
+
+	return name
+}
+
+func hostDataDir(dataDir, host string) string {
+	if host == "" {
+		return dataDir
+	}
+
+	return filepath.Join(dataDir, hostsDirName, host)
+}
+
+// MigrateHostData moves the snapshots saved before host namespaces were
+// turned on into this host's namespace. It runs once per data dir, when the
+// first App is configured: that caller creates a marker file, so concurrent
+// or later calls do nothing. It reports whether this App moved snapshots.
+func (a *App) MigrateHostData() (bool, error) {
+	if a.host == "" || a.hostDataMoved {
+		return a.hostDataMoved, nil
+	}
+
+	hostsDir := filepath.Join(a.cfg.DataDir, hostsDirName)
+	if err := os.MkdirAll(hostsDir, 0o755); err != nil {
+		return false, fmt.Errorf("create hosts dir: %w", err)
+	}
+
+	marker, err := os.OpenFile(filepath.Join(hostsDir, hostsMigratedFileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("create migration marker: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(marker, "%s\n", a.host)
+	_ = marker.Close()
+
+	root := hostDataDir(a.cfg.DataDir, a.host)
+
+	moved, err := migrateLegacyData(a.cfg.DataDir, root)
+	if err != nil {
+		// Let the next run try again.
+		_ = os.Remove(marker.Name())
+
+		return moved, fmt.Errorf("move snapshots into host %s: %w", a.host, err)
+	}
+
+	if moved {
+		a.hostDataMoved = true
+		a.log().Info("moved snapshots into host namespace", "host", a.host, "dir", root)
+	}
+
+	return moved, nil
+}
+
+// migrateLegacyData moves snapshots saved before host namespaces were turned
+// on into hostDir. When hostDir has snapshots of its own already, e.g. because
+// a command saved there first, the legacy ones are merged in.
+func migrateLegacyData(dataDir, hostDir string) (bool, error) {
+	_, err := os.Stat(hostDir)
+	merging := err == nil
+
+	moved := false
+
+	for _, name := range legacyDataEntries {
+		from := filepath.Join(dataDir, name)
+		if _, err := os.Lstat(from); err != nil {
+			continue
+		}
+
+		if err := os.MkdirAll(hostDir, 0o755); err != nil {
+			return moved, fmt.Errorf("create host dir: %w", err)
+		}
+
+		m, err := moveLegacyEntry(from, filepath.Join(hostDir, name), merging)
+		moved = moved || m
+
+		if err != nil {
+			return moved, fmt.Errorf("move %s: %w", name, err)
+		}
+	}
+
+	return moved, nil
+}
+
+// moveLegacyEntry moves the file or directory from to to. Into an existing
+// directory the entries are merged one by one: index.json gains the legacy
+// sessions it lacks, and files the host namespace has its own copy of stay
+// behind. A blob owners index no longer covers merged snapshots, so it is
+// removed on both sides and rebuilt on the next save.
+func moveLegacyEntry(from, to string, merging bool) (bool, error) {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return false, nil
+	}
+
+	if merging && filepath.Base(from) == "blob-owners.json" {
+		for _, path := range []string{from, to} {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return false, fmt.Errorf("remove blob owners index: %w", err)
+			}
+		}
+
+		return false, nil
+	}
+
+	if _, err := os.Lstat(to); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(from, to); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	switch {
+	case info.IsDir():
+		entries, err := os.ReadDir(from)
+		if err != nil {
+			return false, err
+		}
+
+		moved := false
+
+		for _, entry := range entries {
+			m, err := moveLegacyEntry(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name()), true)
+			moved = moved || m
+
+			if err != nil {
+				return moved, err
+			}
+		}
+
+		// Only succeeds once everything was moved.
+		_ = os.Remove(from)
+
+		return moved, nil
+	case filepath.Base(from) == "index.json":
+		return mergeLegacyIndex(from, to)
+	}
+
+	return false, nil
+}
+
+// mergeLegacyIndex adds the sessions of the legacy index at from that the
+// index at to lacks, then removes the legacy index.
+func mergeLegacyIndex(from, to string) (bool, error) {
+	var legacy, index snapshot.Index
+
+	for path, idx := range map[string]*snapshot.Index{from: &legacy, to: &index} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("read index: %w", err)
+		}
+
+		if err := json.Unmarshal(data, idx); err != nil {
+			return false, fmt.Errorf("decode index %s: %w", path, err)
+		}
+	}
+
+	if index.Sessions == nil {
+		index.Sessions = map[string]snapshot.Record{}
+	}
+
+	added := 0
+
+	for name, rec := range legacy.Sessions {
+		if _, ok := index.Sessions[name]; ok {
+			continue
+		}
+
+		if rel, err := filepath.Rel(filepath.Dir(from), rec.File); err == nil && !strings.HasPrefix(rel, "..") {
+			rec.File = filepath.Join(filepath.Dir(to), rel)
+		}
+
+		index.Sessions[name] = rec
+		added++
+	}
+
+	if added > 0 {
+		index.Updated = time.Now().UTC()
+
+		data, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("encode index: %w", err)
+		}
+
+		tmp := to + ".tmp"
+		if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+			return false, fmt.Errorf("write index: %w", err)
+		}
+
+		if err := os.Rename(tmp, to); err != nil {
+			return false, fmt.Errorf("write index: %w", err)
+		}
+	}
+
+	if err := os.Remove(from); err != nil {
+		return added > 0, fmt.Errorf("remove legacy index: %w", err)
+	}
+
+	return added > 0, nil
+}
+
+// otherHosts lists the host namespaces in dataDir except host.
+func otherHosts(dataDir, host string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dataDir, hostsDirName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("list host dirs: %w", err)
+	}
+
+	var hosts []string
+
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != host && config.ValidateProfile(entry.Name()) == nil {
+			hosts = append(hosts, entry.Name())
+		}
+	}
+
+	return hosts, nil
+}
+
+// hostStore opens the snapshots of a tmux server of another host.
+func (a *App) hostStore(host string, server tmux.Server) *store.Store {
+	dir := serverDataDir(hostDataDir(a.cfg.DataDir, host), server)
+
+	return store.NewWithOptions(dir, store.Options{
+		Codec:      a.cfg.Scrollback.Compression,
+		Logger:     a.log().With("component", "store", "host", host),
+		Encryption: storeEncryption(a.cfg.Encryption),
+	})
+}
+
+// otherHostPickerSessions returns the saved sessions of the other hosts, one
+// group per host. They never count as running here.
+func (a *App) otherHostPickerSessions(opts PickerSortOptions) []picker.Session {
+	if a.host == "" {
+		return nil
+	}
+
+	hosts, err := otherHosts(a.cfg.DataDir, a.host)
+	if err != nil {
+		a.log().Warn("picker cannot list hosts", "error", err)
+		return nil
+	}
+
+	var sessions []picker.Session
+
+	for _, host := range hosts {
+		servers, err := knownServers(hostDataDir(a.cfg.DataDir, host))
+		if err != nil {
+			a.log().Warn("picker skips host", "host", host, "error", err)
+			continue
+		}
+
+		for _, server := range servers {
+			st := a.hostStore(host, server)
+
+			records, err := st.ListRecords()
+			if err != nil {
+				a.log().Warn("picker skips host server", "host", host, "server", server.String(), "error", err)
+				continue
+			}
+
+			picker.SortSessionRecords(records, opts.Session)
+
+			for _, rec := range records {
+				snap, err := st.LoadSession(rec.SessionName)
+				if err != nil {
+					a.log().Warn("picker skips session", "session", rec.SessionName, "host", host, "error", err)
+					continue
+				}
+
+				sess := picker.Session{Record: rec, Windows: snap.Windows, Host: host}
+				if !server.IsDefault() {
+					sess.Server = server.String()
+				}
+
+				sessions = append(sessions, sess)
+			}
+		}
+	}
+
+	return sessions
+}
+
+// importFromHost copies a session snapshot of another host into this host's
+// store, rewriting pane paths with Host.PathMap, so it can be restored here.
+func (a *App) importFromHost(target PickerTarget) error {
+	session := target.SessionName
+	if err := config.ValidateProfile(target.Host); err != nil {
+		return fmt.Errorf("invalid host: %w", err)
+	}
+
+	exists, err := a.store.SessionExists(session)
+	if err != nil {
+		return fmt.Errorf("check session: %w", err)
+	}
+
+	if exists {
+		return fmt.Errorf("session %s is already saved here; rename or delete it before restoring the one from %s",
+			session, target.Host)
+	}
+
+	st := a.hostStore(target.Host, tmux.ParseServer(target.Server))
+
+	snap, err := st.LoadSession(session)
+	if err != nil {
+		return fmt.Errorf("load session of host %s: %w", target.Host, err)
+	}
+
+	mapped := mapSnapshotPaths(&snap, a.cfg.Host.PathMap)
+
+	if err := a.store.SaveSession(snap); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+
+	if rec, err := st.Record(session); err == nil {
+		if err := a.store.SetSessionMeta(session, rec.SessionMeta); err != nil {
+			return fmt.Errorf("copy session meta: %w", err)
+		}
+	}
+
+	a.log().Info("session imported", "session", session, "host", target.Host, "paths_mapped", mapped)
+
+	return nil
+}
+
+// mapSnapshotPaths rewrites pane paths by the longest matching prefix of
+// pathMap and returns how many panes changed.
+func mapSnapshotPaths(snap *snapshot.SessionSnapshot, pathMap map[string]string) int {
+	if len(pathMap) == 0 {
+		return 0
+	}
+
+	prefixes := make([]string, 0, len(pathMap))
+	for from := range pathMap {
+		prefixes = append(prefixes, from)
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	mapped := 0
+
+	for wi := range snap.Windows {
+		for pi := range snap.Windows[wi].Panes {
+			pane := &snap.Windows[wi].Panes[pi]
+
+			for _, from := range prefixes {
+				rest, ok := strings.CutPrefix(pane.CurrentPath, from)
+				if !ok || (rest != "" && !strings.HasPrefix(rest, "/") && from != "/") {
+					continue
+				}
+
+				pane.CurrentPath = filepath.Join(pathMap[from], rest)
+				mapped++
+
+				break
+			}
+		}
+	}
+
+	return mapped
+}
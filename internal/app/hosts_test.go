@@ -0,0 +1,188 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+)
+
+func TestHostForUsesProfileOrHostname(t *testing.T) {
+	if got := hostFor(config.Config{}); got != "" {
+		t.Fatalf("expected no host namespace by default, got %q", got)
+	}
+
+	if got := hostFor(config.Config{Host: config.HostConfig{Profile: "laptop"}}); got != "laptop" {
+		t.Fatalf("expected profile namespace, got %q", got)
+	}
+
+	if got := hostFor(config.Config{Host: config.HostConfig{Namespace: true}}); got == "" {
+		t.Fatal("expected hostname namespace")
+	}
+}
+
+func TestMigrateHostDataMovesLegacySnapshotsOnce(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	dataDir := t.TempDir()
+	saveTestSnapshot(t, store.New(dataDir), "legacy", "/home/alice")
+
+	a := New(config.Config{DataDir: dataDir, Host: config.HostConfig{Profile: "laptop"}})
+
+	// Opening the app moves the snapshots before any command uses the store.
+	moved, err := a.MigrateHostData()
+	if err != nil || !moved {
+		t.Fatalf("MigrateHostData: moved=%v err=%v", moved, err)
+	}
+
+	if _, err := a.store.LoadSession("legacy"); err != nil {
+		t.Fatalf("expected legacy session in the host namespace: %v", err)
+	}
+
+	for _, name := range []string{"index.json", "blob-owners.json"} {
+		if _, err := os.Stat(filepath.Join(dataDir, "hosts", "laptop", name)); err != nil {
+			t.Fatalf("expected %s moved: %v", name, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, "sessions")); !os.IsNotExist(err) {
+		t.Fatalf("expected legacy sessions dir to be moved, got %v", err)
+	}
+
+	// Later legacy data, e.g. from a machine without namespaces, stays put.
+	saveTestSnapshot(t, store.New(dataDir), "later", "/home/alice")
+
+	other := New(config.Config{DataDir: dataDir, Host: config.HostConfig{Profile: "desktop"}})
+	if moved, err := other.MigrateHostData(); err != nil || moved {
+		t.Fatalf("expected the migration to run once, moved=%v err=%v", moved, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, "sessions", "later.json")); err != nil {
+		t.Fatalf("expected later legacy snapshot to stay: %v", err)
+	}
+}
+
+func TestMigrateHostDataMergesIntoExistingHostIndex(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	dataDir := t.TempDir()
+	saveTestSnapshot(t, store.New(dataDir), "legacy", "/home/alice")
+	saveTestSnapshot(t, store.New(dataDir), "both", "/home/alice/old")
+
+	// A save with the new configuration reached the host namespace first.
+	hostDir := filepath.Join(dataDir, "hosts", "laptop")
+	saveTestSnapshot(t, store.New(hostDir), "both", "/home/alice/new")
+
+	a := New(config.Config{DataDir: dataDir, Host: config.HostConfig{Profile: "laptop"}})
+	if moved, err := a.MigrateHostData(); err != nil || !moved {
+		t.Fatalf("MigrateHostData: moved=%v err=%v", moved, err)
+	}
+
+	records, err := a.store.ListRecords()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("expected both sessions listed, got %+v %v", records, err)
+	}
+
+	snap, err := a.store.LoadSession("both")
+	if err != nil || snap.Windows[0].Panes[0].CurrentPath != "/home/alice/new" {
+		t.Fatalf("expected the host's own snapshot to win, got %+v %v", snap, err)
+	}
+
+	if _, err := a.store.LoadSession("legacy"); err != nil {
+		t.Fatalf("expected legacy session merged into the host namespace: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(dataDir, "index.json"), filepath.Join(hostDir, "blob-owners.json")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v", path, err)
+		}
+	}
+}
+
+func TestRestoreFromOtherHostMapsPaths(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	fake := writeFakeTmuxForApp(t, `
+if [ "$1" = "list-sessions" ]; then
+  exit 0
+fi
+exit 0
+`)
+
+	dataDir := t.TempDir()
+	saveTestSnapshot(t, store.New(filepath.Join(dataDir, "hosts", "desktop")), "work", "/home/alice/src/app")
+
+	a := New(config.Config{
+		DataDir: dataDir,
+		TmuxBin: fake,
+		Host: config.HostConfig{
+			Profile: "laptop",
+			PathMap: map[string]string{"/home/alice": "/Users/alice", "/home/alicex": "/nope"},
+		},
+	})
+
+	sessions, err := a.pickerSessions(DefaultPickerSortOptions())
+	if err != nil {
+		t.Fatalf("pickerSessions: %v", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].Host != "desktop" || sessions[0].Restored {
+		t.Fatalf("expected the desktop session in its own group, got %+v", sessions)
+	}
+
+	if err := a.RestoreTarget(PickerTarget{SessionName: "work", Host: "desktop"}, false); err != nil {
+		t.Fatalf("RestoreTarget error: %v", err)
+	}
+
+	snap, err := a.store.LoadSession("work")
+	if err != nil {
+		t.Fatalf("expected session imported into this host: %v", err)
+	}
+
+	if got := snap.Windows[0].Panes[0].CurrentPath; got != "/Users/alice/src/app" {
+		t.Fatalf("expected mapped path, got %q", got)
+	}
+
+	if err := a.RestoreTarget(PickerTarget{SessionName: "work", Host: "desktop"}, false); err == nil {
+		t.Fatal("expected an error when the session is already saved here")
+	}
+}
+
+func TestMapSnapshotPathsMatchesWholeComponents(t *testing.T) {
+	snap := snapshot.SessionSnapshot{Windows: []snapshot.Window{{Panes: []snapshot.Pane{
+		{CurrentPath: "/home/alice"},
+		{CurrentPath: "/home/alicebob/x"},
+		{CurrentPath: "/home/alice/work/repo"},
+	}}}}
+
+	got := mapSnapshotPaths(&snap, map[string]string{
+		"/home/alice":      "/Users/alice",
+		"/home/alice/work": "/Volumes/work",
+	})
+	if got != 2 {
+		t.Fatalf("expected 2 panes mapped, got %d", got)
+	}
+
+	panes := snap.Windows[0].Panes
+	if panes[0].CurrentPath != "/Users/alice" || panes[1].CurrentPath != "/home/alicebob/x" ||
+		panes[2].CurrentPath != "/Volumes/work/repo" {
+		t.Fatalf("unexpected paths: %+v", panes)
+	}
+}
+
+func saveTestSnapshot(t *testing.T, st *store.Store, name, path string) {
+	t.Helper()
+
+	if err := st.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: name,
+		CapturedAt:  time.Now().UTC(),
+		Windows:     []snapshot.Window{{Index: 0, Panes: []snapshot.Pane{{Index: 0, CurrentPath: path}}}},
+	}); err != nil {
+		t.Fatalf("save session %s: %v", name, err)
+	}
+}
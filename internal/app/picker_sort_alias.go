@@ -22,6 +22,8 @@ const (
 	SessionSortName     = picker.SessionSortName
 	SessionSortWindows  = picker.SessionSortWindows
 	SessionSortPanes    = picker.SessionSortPanes
+	SessionSortPinned   = picker.SessionSortPinned
+	SessionSortTags     = picker.SessionSortTags
 )
 
 const (
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// saveErrorClass tells the autosave loop how to react to a failed save.
+type saveErrorClass int
+
+const (
+	// saveErrorOther is an unexpected failure; the next regular save retries it.
+	saveErrorOther saveErrorClass = iota
+	// saveErrorVanished means the session was closed while it was captured.
+	saveErrorVanished
+	// saveErrorUnreachable means tmux could not be reached; saves are retried
+	// with backoff.
+	saveErrorUnreachable
+	// saveErrorStorage means the data dir cannot be written (disk full,
+	// permissions); the user is alerted because retrying will not help.
+	saveErrorStorage
+)
+
+func (c saveErrorClass) String() string {
+	switch c {
+	case saveErrorVanished:
+		return "vanished"
+	case saveErrorUnreachable:
+		return "unreachable"
+	case saveErrorStorage:
+		return "storage"
+	default:
+		return "error"
+	}
+}
+
+func (c saveErrorClass) retriable() bool {
+	return c == saveErrorUnreachable
+}
+
+// tmux reports these when a session, window or pane disappears mid-capture.
+var vanishedMessages = []string{
+	"can't find session",
+	"can't find window",
+	"can't find pane",
+	"session not found",
+	"no such session",
+}
+
+// tmux reports these when the server is not running or not responding.
+var unreachableMessages = []string{
+	"no server running",
+	"error connecting to",
+	"connection refused",
+	"lost server",
+	"server exited",
+	"resource temporarily unavailable",
+}
+
+// classifySaveError returns the class of err. For joined errors the most
+// severe class wins: storage, then unreachable, then other, then vanished.
+func classifySaveError(err error) saveErrorClass {
+	if err == nil {
+		return saveErrorOther
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		worst := saveErrorVanished
+
+		for _, e := range joined.Unwrap() {
+			worst = worseSaveError(worst, classifySaveError(e))
+		}
+
+		return worst
+	}
+
+	if wrapped := errors.Unwrap(err); wrapped != nil {
+		if class := classifySaveError(wrapped); class != saveErrorOther {
+			return class
+		}
+	}
+
+	switch {
+	case errors.Is(err, syscall.ENOSPC),
+		errors.Is(err, syscall.EDQUOT),
+		errors.Is(err, syscall.EROFS),
+		errors.Is(err, os.ErrPermission):
+		return saveErrorStorage
+	case errors.Is(err, tmux.ErrSessionNotFound):
+		return saveErrorVanished
+	case errors.Is(err, exec.ErrNotFound):
+		return saveErrorUnreachable
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, m := range unreachableMessages {
+		if strings.Contains(msg, m) {
+			return saveErrorUnreachable
+		}
+	}
+
+	for _, m := range vanishedMessages {
+		if strings.Contains(msg, m) {
+			return saveErrorVanished
+		}
+	}
+
+	return saveErrorOther
+}
+
+func worseSaveError(a, b saveErrorClass) saveErrorClass {
+	rank := func(c saveErrorClass) int {
+		switch c {
+		case saveErrorStorage:
+			return 3
+		case saveErrorUnreachable:
+			return 2
+		case saveErrorOther:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	if rank(b) > rank(a) {
+		return b
+	}
+
+	return a
+}
+
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+// retryBackoff returns the delay before retry number attempt (starting at 1):
+// exponential from retryBaseDelay, capped at the save interval and
+// retryMaxDelay, with the upper half jittered so daemons do not retry in step.
+func retryBackoff(attempt int, interval time.Duration) time.Duration {
+	limit := retryMaxDelay
+	if interval > 0 && interval < limit {
+		limit = interval
+	}
+
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, limit)
+	half := delay / 2
+
+	return half + time.Duration(rand.Int64N(int64(delay-half)+1))
+}
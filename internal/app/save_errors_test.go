@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestClassifySaveError(t *testing.T) {
+	diskFull := &fs.PathError{Op: "write", Path: "/data/blob", Err: syscall.ENOSPC}
+	denied := &fs.PathError{Op: "open", Path: "/data/index.json", Err: syscall.EACCES}
+	tmuxErr := func(msg string) error {
+		return fmt.Errorf("capture session: tmux list-windows: exit status 1 (%s)", msg)
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want saveErrorClass
+	}{
+		{"session gone before capture", fmt.Errorf("capture session: %w", tmux.ErrSessionNotFound), saveErrorVanished},
+		{"session gone mid capture", tmuxErr("can't find session: work"), saveErrorVanished},
+		{"pane gone mid capture", tmuxErr("can't find pane: %3"), saveErrorVanished},
+		{"no server", fmt.Errorf("list sessions: %w", errors.New("no server running on /tmp/tmux-1000/default")), saveErrorUnreachable},
+		{"connection refused", tmuxErr("error connecting to /tmp/tmux-1000/default (Connection refused)"), saveErrorUnreachable},
+		{"missing binary", fmt.Errorf("list sessions: %w", exec.ErrNotFound), saveErrorUnreachable},
+		{"disk full", fmt.Errorf("save session: %w", diskFull), saveErrorStorage},
+		{"permission", fmt.Errorf("save session: %w", denied), saveErrorStorage},
+		{"other", errors.New("unmarshal snapshot: unexpected end of JSON input"), saveErrorOther},
+		{"joined picks worst", errors.Join(tmuxErr("can't find session: a"), fmt.Errorf("x: %w", diskFull)), saveErrorStorage},
+		{
+			"wrapped join",
+			fmt.Errorf("2 of 3 sessions failed to save: %w", errors.Join(errors.New("boom"), tmuxErr("lost server"))),
+			saveErrorUnreachable,
+		},
+	}
+
+	for _, tc := range cases {
+		if got := classifySaveError(tc.err); got != tc.want {
+			t.Errorf("%s: classifySaveError(%v) = %s, want %s", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryBackoffGrowsAndIsCapped(t *testing.T) {
+	for attempt, limit := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second} {
+		for range 20 {
+			got := retryBackoff(attempt, time.Hour)
+			if got < limit/2 || got > limit {
+				t.Fatalf("retryBackoff(%d) = %s, want within [%s, %s]", attempt, got, limit/2, limit)
+			}
+		}
+	}
+
+	if got := retryBackoff(30, 10*time.Second); got > 10*time.Second || got < 5*time.Second {
+		t.Fatalf("expected backoff capped by interval, got %s", got)
+	}
+
+	if got := retryBackoff(30, time.Hour); got > retryMaxDelay {
+		t.Fatalf("expected backoff capped at %s, got %s", retryMaxDelay, got)
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/config"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// Snapshots of the default tmux server live directly in the data dir; other
+// servers get servers/<Server.ID> so equally named sessions do not collide.
+const serversDirName = "servers"
+
+// serverFor returns the tmux server selected by cfg, falling back to the one
+// lazy-tmux runs in.
+func serverFor(cfg config.Config) tmux.Server {
+	if cfg.TmuxSocketName == "" && cfg.TmuxSocketPath == "" {
+		return tmux.ServerFromEnv()
+	}
+
+	return configuredServer(cfg)
+}
+
+func configuredServer(cfg config.Config) tmux.Server {
+	return tmux.Server{SocketName: cfg.TmuxSocketName, SocketPath: cfg.TmuxSocketPath}
+}
+
+func serverDataDir(dataDir string, server tmux.Server) string {
+	if server.IsDefault() {
+		return dataDir
+	}
+
+	return filepath.Join(dataDir, serversDirName, server.ID())
+}
+
+// knownServers lists the default server and every server with a snapshot
+// directory under dataDir.
+func knownServers(dataDir string) ([]tmux.Server, error) {
+	servers := []tmux.Server{{SocketName: "default"}}
+
+	entries, err := os.ReadDir(filepath.Join(dataDir, serversDirName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return servers, nil
+		}
+
+		return nil, fmt.Errorf("list server dirs: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		server, err := tmux.ServerFromID(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+// forServer returns an App with the same configuration bound to another tmux
+// server and its snapshots.
+func (a *App) forServer(server tmux.Server) *App {
+	cfg := a.cfg
+	cfg.TmuxSocketName, cfg.TmuxSocketPath = server.SocketName, server.SocketPath
+
+	if server.IsDefault() {
+		// Name the default server explicitly so $TMUX does not redirect it.
+		cfg.TmuxSocketName, cfg.TmuxSocketPath = "default", ""
+	}
+
+	other := &App{logger: a.logger, mem: a.mem}
+	other.applyConfig(cfg)
+
+	return other
+}
+
+func (a *App) isCurrentServer(server tmux.Server) bool {
+	return a.server.ID() == server.ID()
+}
+
+// restoreOnServer restores a session of another tmux server. Clients cannot
+// switch across servers, so attached clients are told how to attach instead.
+func (a *App) restoreOnServer(server tmux.Server, target PickerTarget, switchClient bool) error {
+	target.Server = ""
+
+	if err := a.forServer(server).RestoreTarget(target, false); err != nil {
+		return fmt.Errorf("restore on tmux server %s: %w", server, err)
+	}
+
+	if switchClient {
+		msg := fmt.Sprintf(
+			"lazy-tmux: %s restored on tmux server %s (tmux %s attach -t %s)",
+			target.SessionName,
+			server,
+			strings.Join(server.Args(), " "),
+			target.SessionName,
+		)
+		if err := a.tmux.DisplayMessage(msg); err != nil {
+			a.log().Warn("restore notification failed", "error", err)
+		}
+	}
+
+	return nil
+}
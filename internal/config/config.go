@@ -1,21 +1,109 @@
 package config
 
 import (
+	"os"
+	"strings"
 	"time"
 
+	"github.com/alchemmist/lazy-tmux/internal/logging"
+	"github.com/alchemmist/lazy-tmux/internal/memory"
 	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
 )
 
 type Config struct {
-	TmuxBin      string
-	DataDir      string
-	SaveInterval time.Duration
-	Scrollback   ScrollbackConfig
+	TmuxBin string
+	// TmuxSocketName and TmuxSocketPath select a tmux server like tmux -L
+	// and -S; both empty means the server lazy-tmux runs in, or the default.
+	TmuxSocketName string
+	TmuxSocketPath string
+	DataDir        string
+	SaveInterval   time.Duration
+	Scrollback     ScrollbackConfig
+	Log            LogConfig
+	AutoSleep      AutoSleepConfig
+	Host           HostConfig
+	// CaptureOptions lists the tmux session and window options saved with
+	// each snapshot and reapplied on restore.
+	CaptureOptions tmux.OptionNames
+	// Environment lists name globs of session environment variables saved
+	// with each snapshot; keep secrets out of it.
+	Environment []string
+	Redact      RedactConfig
+	Encryption  EncryptionConfig
+	// TrashRetention is how long the daemon keeps deleted sessions and
+	// windows in the trash; 0 keeps them until the trash is emptied.
+	TrashRetention time.Duration
+	// ExportDir is where the picker exports sessions as JSON; "" is the
+	// current directory. It must be outside DataDir.
+	ExportDir string
 }
 
-type ScrollbackConfig struct {
+// EncryptionConfig turns on encryption at rest. The key is read from the
+// first of KeyFile, KeyEnv and KeyCommand that is set, only when encrypted
+// data is first read or written.
+type EncryptionConfig struct {
+	Scrollback bool
+	Sessions   bool
+	KeyFile    string
+	KeyEnv     string
+	// KeyCommand is run with sh -c and prints the key, e.g. "pass show lazy-tmux".
+	KeyCommand string
+}
+
+// HasKey reports whether a key source is configured.
+func (e EncryptionConfig) HasKey() bool {
+	return e.KeyFile != "" || e.KeyEnv != "" || e.KeyCommand != ""
+}
+
+// RedactConfig controls masking of secrets before snapshots are saved. It is
+// off by default, since masking changes the saved scrollback and commands.
+type RedactConfig struct {
 	Enabled bool
-	Lines   int
+	// Patterns are extra regular expressions to mask; with a capture group
+	// only the first group is masked.
+	Patterns []string
+}
+
+type ScrollbackConfig struct {
+	Enabled     bool
+	Lines       int
+	Compression string
+	// Replay selects how restored panes show their scrollback again, one
+	// of the tmux.Replay* modes.
+	Replay string
+}
+
+// AutoSleepConfig controls how the daemon puts unused sessions to sleep.
+type AutoSleepConfig struct {
+	// IdleAfter sleeps detached sessions idle for this long; 0 disables it.
+	IdleAfter time.Duration
+	// Exclude lists session name globs that are never put to sleep.
+	Exclude []string
+	// MinAvailable sleeps least recently used sessions while available
+	// memory is below it; the zero value disables it.
+	MinAvailable memory.Threshold
+}
+
+// HostConfig keeps the snapshots of machines sharing one data dir apart.
+type HostConfig struct {
+	// Namespace stores snapshots under hosts/<hostname> in the data dir.
+	Namespace bool
+	// Profile names this machine's namespace instead of its hostname and
+	// implies Namespace.
+	Profile string
+	// PathMap rewrites pane path prefixes of snapshots restored from other
+	// hosts, e.g. /home/alice to /Users/alice.
+	PathMap map[string]string
+}
+
+type LogConfig struct {
+	// File is the daemon log path; "-" logs to stderr.
+	File     string
+	Format   string
+	Level    string
+	MaxSize  int64
+	MaxFiles int
 }
 
 func Default() Config {
@@ -24,8 +112,22 @@ func Default() Config {
 		DataDir:      store.DefaultDataDir(),
 		SaveInterval: 5 * time.Minute,
 		Scrollback: ScrollbackConfig{
-			Enabled: false,
-			Lines:   5000,
+			Enabled:     false,
+			Lines:       5000,
+			Compression: store.DefaultCodec,
+			Replay:      tmux.DefaultReplay,
+		},
+		Log: LogConfig{
+			File:     logging.DefaultPath(),
+			Format:   logging.FormatText,
+			Level:    "info",
+			MaxSize:  logging.DefaultMaxSize,
+			MaxFiles: logging.DefaultMaxFiles,
+		},
+		Host: HostConfig{
+			Profile: strings.TrimSpace(os.Getenv("LAZY_TMUX_PROFILE")),
 		},
+		CaptureOptions: tmux.DefaultOptionNames(),
+		Environment:    tmux.DefaultEnvironment(),
 	}
 }
@@ -27,3 +27,9 @@ func TestDefault(t *testing.T) {
 		t.Fatalf("expected default scrollback lines 5000, got %d", cfg.Scrollback.Lines)
 	}
 }
+
+func TestDefaultScrollbackCompression(t *testing.T) {
+	if got := Default().Scrollback.Compression; got != "gzip" {
+		t.Fatalf("expected gzip scrollback compression by default, got %q", got)
+	}
+}
@@ -0,0 +1,414 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/memory"
+	"github.com/alchemmist/lazy-tmux/internal/redact"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+// fileConfig mirrors Config as stored in the JSON config file. Every field is
+// optional; unset fields keep their defaults.
+type fileConfig struct {
+	TmuxBin        *string               `json:"tmux_bin"`
+	SocketName     *string               `json:"tmux_socket_name"`
+	SocketPath     *string               `json:"tmux_socket_path"`
+	DataDir        *string               `json:"data_dir"`
+	SaveInterval   *string               `json:"save_interval"`
+	Scrollback     *fileScrollbackConfig `json:"scrollback"`
+	Log            *fileLogConfig        `json:"log"`
+	AutoSleep      *fileAutoSleepConfig  `json:"auto_sleep"`
+	Host           *fileHostConfig       `json:"host"`
+	Options        *fileOptionsConfig    `json:"capture_options"`
+	Environment    []string              `json:"environment"`
+	Redact         *fileRedactConfig     `json:"redact"`
+	Encryption     *fileEncryptionConfig `json:"encryption"`
+	TrashRetention *string               `json:"trash_retention"`
+	ExportDir      *string               `json:"export_dir"`
+}
+
+type fileEncryptionConfig struct {
+	Scrollback *bool   `json:"scrollback"`
+	Sessions   *bool   `json:"sessions"`
+	KeyFile    *string `json:"key_file"`
+	KeyEnv     *string `json:"key_env"`
+	KeyCommand *string `json:"key_command"`
+}
+
+type fileRedactConfig struct {
+	Enabled  *bool    `json:"enabled"`
+	Patterns []string `json:"patterns"`
+}
+
+type fileScrollbackConfig struct {
+	Enabled     *bool   `json:"enabled"`
+	Lines       *int    `json:"lines"`
+	Compression *string `json:"compression"`
+	Replay      *string `json:"replay"`
+}
+
+type fileLogConfig struct {
+	File     *string `json:"file"`
+	Format   *string `json:"format"`
+	Level    *string `json:"level"`
+	MaxSize  *int64  `json:"max_size"`
+	MaxFiles *int    `json:"max_files"`
+}
+
+type fileAutoSleepConfig struct {
+	IdleAfter    *string  `json:"idle_after"`
+	Exclude      []string `json:"exclude"`
+	MinAvailable *string  `json:"min_available"`
+}
+
+type fileHostConfig struct {
+	Namespace *bool             `json:"namespace"`
+	Profile   *string           `json:"profile"`
+	PathMap   map[string]string `json:"path_map"`
+}
+
+type fileOptionsConfig struct {
+	Session []string `json:"session"`
+	Window  []string `json:"window"`
+}
+
+// Path returns the config file location: $LAZY_TMUX_CONFIG, or
+// lazy-tmux/config.json under $XDG_CONFIG_HOME (default ~/.config).
+func Path() string {
+	if v := strings.TrimSpace(os.Getenv("LAZY_TMUX_CONFIG")); v != "" {
+		return v
+	}
+
+	configHome := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME"))
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "lazy-tmux", "config.json")
+}
+
+// Load returns the defaults overlaid with the config file at path. A missing
+// file is not an error.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if err := ValidateProfile(cfg.Host.Profile); err != nil {
+		return cfg, fmt.Errorf("LAZY_TMUX_PROFILE: %w", err)
+	}
+
+	if strings.TrimSpace(path) == "" {
+		return cfg, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+
+		return cfg, fmt.Errorf("read config file: %w", err)
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return cfg, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	if err := fc.apply(&cfg); err != nil {
+		return cfg, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+func (fc fileConfig) apply(cfg *Config) error {
+	if fc.TmuxBin != nil {
+		cfg.TmuxBin = *fc.TmuxBin
+	}
+
+	if fc.SocketName != nil || fc.SocketPath != nil {
+		var name, socketPath string
+		if fc.SocketName != nil {
+			name = *fc.SocketName
+		}
+
+		if fc.SocketPath != nil {
+			socketPath = expandHome(*fc.SocketPath)
+		}
+
+		server, err := tmux.NewServer(name, socketPath)
+		if err != nil {
+			return err
+		}
+
+		cfg.TmuxSocketName, cfg.TmuxSocketPath = server.SocketName, server.SocketPath
+	}
+
+	if fc.DataDir != nil {
+		cfg.DataDir = expandHome(*fc.DataDir)
+	}
+
+	if fc.ExportDir != nil {
+		cfg.ExportDir = expandHome(*fc.ExportDir)
+	}
+
+	if fc.SaveInterval != nil {
+		interval, err := time.ParseDuration(*fc.SaveInterval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid save_interval %q", *fc.SaveInterval)
+		}
+
+		cfg.SaveInterval = interval
+	}
+
+	if fc.TrashRetention != nil {
+		retention, err := time.ParseDuration(*fc.TrashRetention)
+		if err != nil || retention < 0 {
+			return fmt.Errorf("invalid trash_retention %q", *fc.TrashRetention)
+		}
+
+		cfg.TrashRetention = retention
+	}
+
+	if sc := fc.Scrollback; sc != nil {
+		if sc.Enabled != nil {
+			cfg.Scrollback.Enabled = *sc.Enabled
+		}
+
+		if sc.Lines != nil {
+			if *sc.Lines <= 0 {
+				return fmt.Errorf("invalid scrollback.lines %d", *sc.Lines)
+			}
+
+			cfg.Scrollback.Lines = *sc.Lines
+		}
+
+		if sc.Compression != nil {
+			codec, err := store.ParseCodec(*sc.Compression)
+			if err != nil {
+				return fmt.Errorf("invalid scrollback.compression: %w", err)
+			}
+
+			cfg.Scrollback.Compression = codec
+		}
+
+		if sc.Replay != nil {
+			mode, err := tmux.ParseReplay(*sc.Replay)
+			if err != nil {
+				return fmt.Errorf("invalid scrollback.replay: %w", err)
+			}
+
+			cfg.Scrollback.Replay = mode
+		}
+	}
+
+	if lc := fc.Log; lc != nil {
+		if lc.File != nil {
+			cfg.Log.File = expandHome(*lc.File)
+		}
+
+		if lc.Format != nil {
+			cfg.Log.Format = *lc.Format
+		}
+
+		if lc.Level != nil {
+			cfg.Log.Level = *lc.Level
+		}
+
+		if lc.MaxSize != nil {
+			cfg.Log.MaxSize = *lc.MaxSize
+		}
+
+		if lc.MaxFiles != nil {
+			cfg.Log.MaxFiles = *lc.MaxFiles
+		}
+	}
+
+	if ac := fc.AutoSleep; ac != nil {
+		if ac.IdleAfter != nil {
+			idle, err := time.ParseDuration(*ac.IdleAfter)
+			if err != nil || idle < 0 {
+				return fmt.Errorf("invalid auto_sleep.idle_after %q", *ac.IdleAfter)
+			}
+
+			cfg.AutoSleep.IdleAfter = idle
+		}
+
+		if ac.Exclude != nil {
+			if err := ValidateSessionGlobs(ac.Exclude); err != nil {
+				return fmt.Errorf("invalid auto_sleep.exclude: %w", err)
+			}
+
+			cfg.AutoSleep.Exclude = ac.Exclude
+		}
+
+		if ac.MinAvailable != nil {
+			threshold, err := memory.ParseThreshold(*ac.MinAvailable)
+			if err != nil {
+				return fmt.Errorf("invalid auto_sleep.min_available: %w", err)
+			}
+
+			cfg.AutoSleep.MinAvailable = threshold
+		}
+	}
+
+	if hc := fc.Host; hc != nil {
+		if hc.Namespace != nil {
+			cfg.Host.Namespace = *hc.Namespace
+		}
+
+		if hc.Profile != nil {
+			profile := strings.TrimSpace(*hc.Profile)
+			if err := ValidateProfile(profile); err != nil {
+				return fmt.Errorf("invalid host.profile: %w", err)
+			}
+
+			cfg.Host.Profile = profile
+		}
+
+		if hc.PathMap != nil {
+			pathMap, err := parsePathMap(hc.PathMap)
+			if err != nil {
+				return fmt.Errorf("invalid host.path_map: %w", err)
+			}
+
+			cfg.Host.PathMap = pathMap
+		}
+	}
+
+	if oc := fc.Options; oc != nil {
+		for _, names := range []struct {
+			key  string
+			in   []string
+			dest *[]string
+		}{
+			{"session", oc.Session, &cfg.CaptureOptions.Session},
+			{"window", oc.Window, &cfg.CaptureOptions.Window},
+		} {
+			if names.in == nil {
+				continue
+			}
+
+			for _, name := range names.in {
+				if err := tmux.ValidateOptionName(name); err != nil {
+					return fmt.Errorf("invalid capture_options.%s: %w", names.key, err)
+				}
+			}
+
+			*names.dest = names.in
+		}
+	}
+
+	if fc.Environment != nil {
+		if err := ValidateSessionGlobs(fc.Environment); err != nil {
+			return fmt.Errorf("invalid environment: %w", err)
+		}
+
+		cfg.Environment = fc.Environment
+	}
+
+	if rc := fc.Redact; rc != nil {
+		if rc.Enabled != nil {
+			cfg.Redact.Enabled = *rc.Enabled
+		}
+
+		if rc.Patterns != nil {
+			if _, err := redact.New(rc.Patterns); err != nil {
+				return fmt.Errorf("invalid redact.patterns: %w", err)
+			}
+
+			cfg.Redact.Patterns = rc.Patterns
+		}
+	}
+
+	if ec := fc.Encryption; ec != nil {
+		if ec.Scrollback != nil {
+			cfg.Encryption.Scrollback = *ec.Scrollback
+		}
+
+		if ec.Sessions != nil {
+			cfg.Encryption.Sessions = *ec.Sessions
+		}
+
+		if ec.KeyFile != nil {
+			cfg.Encryption.KeyFile = expandHome(strings.TrimSpace(*ec.KeyFile))
+		}
+
+		if ec.KeyEnv != nil {
+			cfg.Encryption.KeyEnv = strings.TrimSpace(*ec.KeyEnv)
+		}
+
+		if ec.KeyCommand != nil {
+			cfg.Encryption.KeyCommand = strings.TrimSpace(*ec.KeyCommand)
+		}
+
+		if (cfg.Encryption.Scrollback || cfg.Encryption.Sessions) && !cfg.Encryption.HasKey() {
+			return fmt.Errorf("encryption needs encryption.key_file, key_env or key_command")
+		}
+	}
+
+	return nil
+}
+
+// ValidateProfile reports whether name can name a host namespace directory.
+func ValidateProfile(name string) error {
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("%q is not a valid profile name", name)
+	}
+
+	return nil
+}
+
+// parsePathMap cleans a prefix mapping; both sides must be absolute.
+func parsePathMap(in map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(in))
+
+	for from, to := range in {
+		to = expandHome(to)
+		if !filepath.IsAbs(from) || !filepath.IsAbs(to) {
+			return nil, fmt.Errorf("%q: %q: paths must be absolute", from, to)
+		}
+
+		out[filepath.Clean(from)] = filepath.Clean(to)
+	}
+
+	return out, nil
+}
+
+// ValidateSessionGlobs reports the first malformed session (or other) name
+// glob.
+func ValidateSessionGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
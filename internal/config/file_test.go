@@ -0,0 +1,333 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/store"
+	"github.com/alchemmist/lazy-tmux/internal/tmux"
+)
+
+func TestLoadMissingFileReturnsDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.SaveInterval != Default().SaveInterval || cfg.TmuxBin != "tmux" {
+		t.Fatalf("expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadOverlaysFileValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	body := `{"save_interval": "90s", "scrollback": {"enabled": true, "lines": 800}}`
+
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.SaveInterval != 90*time.Second || !cfg.Scrollback.Enabled || cfg.Scrollback.Lines != 800 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	if cfg.TmuxBin != "tmux" || cfg.Scrollback.Compression != Default().Scrollback.Compression {
+		t.Fatalf("expected unset fields to keep defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{"save_interval": "soon"}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid save_interval") {
+		t.Fatalf("expected invalid interval error, got %v", err)
+	}
+}
+
+func TestLoadAutoSleep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	body := `{"auto_sleep": {"idle_after": "2h", "exclude": ["main", "scratch-*"], "min_available": "10%"}}`
+
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.AutoSleep.IdleAfter != 2*time.Hour || strings.Join(cfg.AutoSleep.Exclude, ",") != "main,scratch-*" {
+		t.Fatalf("unexpected auto sleep config: %+v", cfg.AutoSleep)
+	}
+
+	if cfg.AutoSleep.MinAvailable.Percent != 10 {
+		t.Fatalf("unexpected memory threshold: %+v", cfg.AutoSleep.MinAvailable)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"auto_sleep": {"exclude": ["[x"]}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid auto_sleep.exclude") {
+		t.Fatalf("expected invalid exclude error, got %v", err)
+	}
+}
+
+func TestLoadTmuxSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{"tmux_socket_name": "work"}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.TmuxSocketName != "work" || cfg.TmuxSocketPath != "" {
+		t.Fatalf("unexpected tmux socket: %q %q", cfg.TmuxSocketName, cfg.TmuxSocketPath)
+	}
+
+	body := `{"tmux_socket_name": "work", "tmux_socket_path": "/tmp/tmux.sock"}`
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Fatalf("expected conflicting socket error, got %v", err)
+	}
+}
+
+func TestLoadHostConfig(t *testing.T) {
+	t.Setenv("LAZY_TMUX_PROFILE", "")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	body := `{"host": {"namespace": true, "path_map": {"/home/alice/": "/Users/alice"}}}`
+
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if !cfg.Host.Namespace || cfg.Host.Profile != "" || cfg.Host.PathMap["/home/alice"] != "/Users/alice" {
+		t.Fatalf("unexpected host config: %+v", cfg.Host)
+	}
+
+	t.Setenv("LAZY_TMUX_PROFILE", "laptop")
+
+	if cfg, err = Load(path); err != nil || cfg.Host.Profile != "laptop" {
+		t.Fatalf("expected profile from env, got %+v, %v", cfg.Host, err)
+	}
+
+	for _, bad := range []string{`{"host": {"profile": "../x"}}`, `{"host": {"path_map": {"src": "/src"}}}`} {
+		if err := os.WriteFile(path, []byte(bad), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid host.") {
+			t.Fatalf("expected invalid host error for %s, got %v", bad, err)
+		}
+	}
+}
+
+func TestLoadCaptureOptionsAndEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	body := `{"capture_options": {"window": ["synchronize-panes", "@layout-name"]}}`
+
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if strings.Join(cfg.CaptureOptions.Window, ",") != "synchronize-panes,@layout-name" ||
+		strings.Join(cfg.CaptureOptions.Session, ",") != "default-command" {
+		t.Fatalf("unexpected capture options: %+v", cfg.CaptureOptions)
+	}
+
+	if len(cfg.Environment) == 0 || cfg.Environment[0] != "PATH" {
+		t.Fatalf("expected default environment allowlist, got %v", cfg.Environment)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"environment": ["PROJ_*", "[x"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid environment") {
+		t.Fatalf("expected invalid environment glob error, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"capture_options": {"session": ["bad name"]}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid capture_options.session") {
+		t.Fatalf("expected invalid option error, got %v", err)
+	}
+}
+
+func TestLoadScrollbackCompression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{"scrollback": {"compression": "GZ"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.Scrollback.Compression != store.CodecGzip {
+		t.Fatalf("unexpected compression: %q", cfg.Scrollback.Compression)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"scrollback": {"compression": "gzip9"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid scrollback.compression") {
+		t.Fatalf("expected invalid compression error, got %v", err)
+	}
+}
+
+func TestLoadScrollbackReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{"scrollback": {"replay": "wrapper"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.Scrollback.Replay != tmux.ReplayWrapper {
+		t.Fatalf("unexpected replay mode: %q", cfg.Scrollback.Replay)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"scrollback": {"replay": "paste"}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid scrollback.replay") {
+		t.Fatalf("expected invalid replay error, got %v", err)
+	}
+}
+
+func TestLoadRedact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if Default().Redact.Enabled {
+		t.Fatal("expected redaction off by default")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"redact": {"enabled": true, "patterns": ["corp-[0-9]+"]}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if !cfg.Redact.Enabled || len(cfg.Redact.Patterns) != 1 {
+		t.Fatalf("unexpected redact config: %+v", cfg.Redact)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"redact": {"patterns": ["("]}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid redact.patterns") {
+		t.Fatalf("expected invalid pattern error, got %v", err)
+	}
+}
+
+func TestLoadEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	body := `{"encryption": {"scrollback": true, "sessions": true, "key_command": "pass show lazy-tmux"}}`
+
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if !cfg.Encryption.Scrollback || !cfg.Encryption.Sessions || cfg.Encryption.KeyCommand != "pass show lazy-tmux" {
+		t.Fatalf("unexpected encryption config: %+v", cfg.Encryption)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"encryption": {"scrollback": true}}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "key_file") {
+		t.Fatalf("expected missing key source error, got %v", err)
+	}
+}
+
+func TestPathPrefersEnv(t *testing.T) {
+	t.Setenv("LAZY_TMUX_CONFIG", "/etc/lazy.json")
+
+	if got := Path(); got != "/etc/lazy.json" {
+		t.Fatalf("unexpected path: %q", got)
+	}
+
+	t.Setenv("LAZY_TMUX_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "/cfg")
+
+	if got := Path(); got != filepath.Join("/cfg", "lazy-tmux", "config.json") {
+		t.Fatalf("unexpected path: %q", got)
+	}
+}
+
+func TestLoadTrashRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{"trash_retention": "720h"}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if cfg.TrashRetention != 720*time.Hour {
+		t.Fatalf("unexpected trash retention: %s", cfg.TrashRetention)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"trash_retention": "-1h"}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for a negative trash retention")
+	}
+}
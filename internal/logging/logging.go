@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	DefaultMaxSize  = 10 << 20
+	DefaultMaxFiles = 3
+)
+
+// Discard is a logger that drops every record; used when logging is not set up.
+var Discard = slog.New(slog.DiscardHandler)
+
+// DefaultPath returns $LAZY_TMUX_LOG_FILE, or lazy-tmux/lazy-tmux.log under
+// $XDG_STATE_HOME (default ~/.local/state).
+func DefaultPath() string {
+	if v := strings.TrimSpace(os.Getenv("LAZY_TMUX_LOG_FILE")); v != "" {
+		return v
+	}
+
+	stateHome := strings.TrimSpace(os.Getenv("XDG_STATE_HOME"))
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "lazy-tmux.log")
+		}
+
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateHome, "lazy-tmux", "lazy-tmux.log")
+}
+
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported log format %q (expected text|json)", name)
+	}
+}
+
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if strings.TrimSpace(name) == "" {
+		return slog.LevelInfo, nil
+	}
+
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return 0, fmt.Errorf("unsupported log level %q (expected debug|info|warn|error)", name)
+	}
+
+	return level, nil
+}
+
+// New returns a logger writing records in the given format to w.
+func New(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Or returns logger, or Discard when it is nil.
+func Or(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard
+	}
+
+	return logger
+}
@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDefaultPathUsesStateHome(t *testing.T) {
+	t.Setenv("LAZY_TMUX_LOG_FILE", "")
+	t.Setenv("XDG_STATE_HOME", "/state")
+
+	if got := DefaultPath(); got != filepath.Join("/state", "lazy-tmux", "lazy-tmux.log") {
+		t.Fatalf("unexpected path: %q", got)
+	}
+
+	t.Setenv("LAZY_TMUX_LOG_FILE", "/tmp/custom.log")
+
+	if got := DefaultPath(); got != "/tmp/custom.log" {
+		t.Fatalf("unexpected path: %q", got)
+	}
+}
+
+func TestParseFormatAndLevel(t *testing.T) {
+	if got, err := ParseFormat("JSON"); err != nil || got != FormatJSON {
+		t.Fatalf("ParseFormat(JSON) = %q, %v", got, err)
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatal("expected unsupported format error")
+	}
+
+	if got, err := ParseLevel("debug"); err != nil || got != slog.LevelDebug {
+		t.Fatalf("ParseLevel(debug) = %v, %v", got, err)
+	}
+
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Fatal("expected unsupported level error")
+	}
+}
+
+func TestNewWritesJSONRecords(t *testing.T) {
+	var buf bytes.Buffer
+
+	New(&buf, FormatJSON, slog.LevelInfo).Info("session saved", "session", "work")
+	New(&buf, FormatJSON, slog.LevelInfo).Debug("hidden")
+
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"session saved"`) || !strings.Contains(out, `"session":"work"`) {
+		t.Fatalf("unexpected json log: %q", out)
+	}
+
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("expected debug record to be filtered: %q", out)
+	}
+}
+
+func TestRotatingFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "lazy-tmux.log")
+
+	file, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile error: %v", err)
+	}
+	defer file.Close()
+
+	for _, line := range []string{"first-1\n", "second\n", "third-3\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	assertFile(t, path, "fourth\n")
+	assertFile(t, path+".1", "third-3\n")
+	assertFile(t, path+".2", "second\n")
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most 2 backups, got err=%v", err)
+	}
+}
+
+func TestTailReturnsLastLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	if err := os.WriteFile(path, []byte("a\nb\nc\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	lines, err := Tail(path, 2)
+	if err != nil {
+		t.Fatalf("Tail error: %v", err)
+	}
+
+	if strings.Join(lines, ",") != "b,c" {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+}
+
+func TestFollowStreamsAppendedData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+
+	go func() { done <- Follow(ctx, path, 4, out, 5*time.Millisecond) }()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	_, _ = file.WriteString("new\n")
+	file.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "new\n") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Follow error: %v", err)
+	}
+
+	if out.String() != "new\n" {
+		t.Fatalf("unexpected followed output: %q", out.String())
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+
+	if string(got) != want {
+		t.Fatalf("%s = %q, want %q", path, got, want)
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
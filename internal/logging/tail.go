@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Tail returns the last n lines of the file at path.
+func Tail(path string, n int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read log file: %w", err)
+	}
+
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 || n <= 0 {
+		return nil, nil
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = string(line)
+	}
+
+	return out, nil
+}
+
+// Follow copies data appended to path after offset into w until ctx is done,
+// checking every poll. A file that shrinks or is replaced (rotation) is read
+// again from the start.
+func Follow(ctx context.Context, path string, offset int64, w io.Writer, poll time.Duration) error {
+	var (
+		file *os.File
+		info os.FileInfo
+	)
+
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+	}()
+
+	for {
+		current, err := os.Stat(path)
+
+		switch {
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("stat log file: %w", err)
+		case err == nil && (file == nil || !os.SameFile(info, current) || current.Size() < offset):
+			if file != nil {
+				_ = file.Close()
+				offset = 0
+			}
+
+			file, err = os.Open(path)
+			if err != nil {
+				return fmt.Errorf("open log file: %w", err)
+			}
+
+			info = current
+		}
+
+		if file != nil {
+			n, err := copyFrom(file, offset, w)
+			if err != nil {
+				return err
+			}
+
+			offset += n
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(poll):
+		}
+	}
+}
+
+func copyFrom(file *os.File, offset int64, w io.Writer) (int64, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek log file: %w", err)
+	}
+
+	n, err := io.Copy(w, file)
+	if err != nil {
+		return n, fmt.Errorf("copy log file: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,266 @@
+// Package memory samples system memory and process tree usage from /proc and
+// the cgroup v2 hierarchy.
+package memory
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultProcDir   = "/proc"
+	defaultCgroupDir = "/sys/fs/cgroup"
+)
+
+// Stats is the memory available to this process, in bytes.
+type Stats struct {
+	Total     uint64
+	Available uint64
+}
+
+// Sampler reads memory usage. The zero value reads the live system.
+type Sampler struct {
+	ProcDir   string
+	CgroupDir string
+}
+
+func (s Sampler) procDir() string {
+	if s.ProcDir == "" {
+		return defaultProcDir
+	}
+
+	return s.ProcDir
+}
+
+func (s Sampler) cgroupDir() string {
+	if s.CgroupDir == "" {
+		return defaultCgroupDir
+	}
+
+	return s.CgroupDir
+}
+
+// System returns MemTotal and MemAvailable from /proc/meminfo, narrowed to the
+// memory.max limit of this process's cgroup when one is set.
+func (s Sampler) System() (Stats, error) {
+	stats, err := s.meminfo()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	limit, current, ok := s.cgroupMemory()
+	if !ok || limit >= stats.Total {
+		return stats, nil
+	}
+
+	stats.Total = limit
+
+	free := uint64(0)
+	if current < limit {
+		free = limit - current
+	}
+
+	stats.Available = min(stats.Available, free)
+
+	return stats, nil
+}
+
+func (s Sampler) meminfo() (Stats, error) {
+	file, err := os.Open(filepath.Join(s.procDir(), "meminfo"))
+	if err != nil {
+		return Stats{}, fmt.Errorf("read meminfo: %w", err)
+	}
+	defer file.Close()
+
+	var (
+		stats                Stats
+		haveTotal, haveAvail bool
+	)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "MemTotal:":
+			stats.Total, haveTotal = kb*1024, true
+		case "MemAvailable:":
+			stats.Available, haveAvail = kb*1024, true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Stats{}, fmt.Errorf("read meminfo: %w", err)
+	}
+
+	if !haveTotal || !haveAvail {
+		return Stats{}, errors.New("read meminfo: MemTotal or MemAvailable missing")
+	}
+
+	return stats, nil
+}
+
+// cgroupMemory returns memory.max and memory.current of this process's
+// cgroup v2 group; ok is false without a group or limit.
+func (s Sampler) cgroupMemory() (limit, current uint64, ok bool) {
+	b, err := os.ReadFile(filepath.Join(s.procDir(), "self", "cgroup"))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	var group string
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if rest, found := strings.CutPrefix(line, "0::"); found {
+			group = strings.TrimSpace(rest)
+			break
+		}
+	}
+
+	if group == "" {
+		return 0, 0, false
+	}
+
+	dir := filepath.Join(s.cgroupDir(), filepath.Clean("/"+group))
+
+	limit, err = readUintFile(filepath.Join(dir, "memory.max"))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	current, err = readUintFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return limit, current, true
+}
+
+// readUintFile parses a single number; "max" is reported as an error.
+func readUintFile(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// TreeRSS returns the resident memory of each group of root processes and all
+// of their descendants. Processes that exit while sampling are skipped.
+func (s Sampler) TreeRSS(roots map[string][]int) (map[string]uint64, error) {
+	procs, err := s.processes()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[int][]int, len(procs))
+	for pid, p := range procs {
+		children[p.ppid] = append(children[p.ppid], pid)
+	}
+
+	out := make(map[string]uint64, len(roots))
+
+	for key, pids := range roots {
+		seen := map[int]struct{}{}
+		stack := append([]int(nil), pids...)
+		total := uint64(0)
+
+		for len(stack) > 0 {
+			pid := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if _, ok := seen[pid]; ok {
+				continue
+			}
+
+			seen[pid] = struct{}{}
+
+			if p, ok := procs[pid]; ok {
+				total += p.rss
+			}
+
+			stack = append(stack, children[pid]...)
+		}
+
+		out[key] = total
+	}
+
+	return out, nil
+}
+
+type process struct {
+	ppid int
+	rss  uint64
+}
+
+func (s Sampler) processes() (map[int]process, error) {
+	entries, err := os.ReadDir(s.procDir())
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	procs := make(map[int]process, len(entries))
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(s.procDir(), entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+
+		p, ok := parseStat(string(b), pageSize)
+		if ok {
+			procs[pid] = p
+		}
+	}
+
+	return procs, nil
+}
+
+// parseStat reads the parent pid and resident pages from /proc/<pid>/stat.
+// The command name may contain spaces and parentheses, so fields are counted
+// from the last ')'.
+func parseStat(stat string, pageSize uint64) (process, bool) {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return process{}, false
+	}
+
+	// Fields after the name start at field 3 (state); ppid is field 4 and
+	// rss field 24.
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return process{}, false
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return process{}, false
+	}
+
+	pages, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return process{}, false
+	}
+
+	return process{ppid: ppid, rss: pages * pageSize}, true
+}
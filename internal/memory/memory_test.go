@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProcFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestSystemReadsMeminfo(t *testing.T) {
+	proc := t.TempDir()
+	writeProcFile(t, filepath.Join(proc, "meminfo"), "MemTotal:  8000 kB\nMemFree:  100 kB\nMemAvailable:  2000 kB\n")
+
+	stats, err := Sampler{ProcDir: proc, CgroupDir: t.TempDir()}.System()
+	if err != nil {
+		t.Fatalf("System error: %v", err)
+	}
+
+	if stats.Total != 8000*1024 || stats.Available != 2000*1024 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestSystemAppliesCgroupLimit(t *testing.T) {
+	proc, cgroup := t.TempDir(), t.TempDir()
+	writeProcFile(t, filepath.Join(proc, "meminfo"), "MemTotal: 8000 kB\nMemAvailable: 6000 kB\n")
+	writeProcFile(t, filepath.Join(proc, "self", "cgroup"), "0::/user.slice/tmux.scope\n")
+	writeProcFile(t, filepath.Join(cgroup, "user.slice", "tmux.scope", "memory.max"), "4096000\n")
+	writeProcFile(t, filepath.Join(cgroup, "user.slice", "tmux.scope", "memory.current"), "3072000\n")
+
+	stats, err := Sampler{ProcDir: proc, CgroupDir: cgroup}.System()
+	if err != nil {
+		t.Fatalf("System error: %v", err)
+	}
+
+	if stats.Total != 4096000 || stats.Available != 1024000 {
+		t.Fatalf("expected cgroup limit to apply, got %+v", stats)
+	}
+
+	writeProcFile(t, filepath.Join(cgroup, "user.slice", "tmux.scope", "memory.max"), "max\n")
+
+	stats, err = Sampler{ProcDir: proc, CgroupDir: cgroup}.System()
+	if err != nil {
+		t.Fatalf("System error: %v", err)
+	}
+
+	if stats.Total != 8000*1024 || stats.Available != 6000*1024 {
+		t.Fatalf("expected unlimited cgroup to be ignored, got %+v", stats)
+	}
+}
+
+func TestTreeRSSSumsDescendants(t *testing.T) {
+	proc := t.TempDir()
+	page := uint64(os.Getpagesize())
+	stat := func(pid, ppid, pages string) string {
+		return pid + " (z sh) S " + ppid + " 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 100 1000 " + pages + " 0\n"
+	}
+
+	writeProcFile(t, filepath.Join(proc, "10", "stat"), stat("10", "1", "100"))
+	writeProcFile(t, filepath.Join(proc, "11", "stat"), stat("11", "10", "50"))
+	writeProcFile(t, filepath.Join(proc, "12", "stat"), stat("12", "11", "25"))
+	writeProcFile(t, filepath.Join(proc, "20", "stat"), stat("20", "1", "7"))
+	writeProcFile(t, filepath.Join(proc, "self", "stat"), stat("30", "1", "1"))
+
+	got, err := Sampler{ProcDir: proc}.TreeRSS(map[string][]int{"work": {10}, "misc": {20, 99}})
+	if err != nil {
+		t.Fatalf("TreeRSS error: %v", err)
+	}
+
+	if got["work"] != 175*page || got["misc"] != 7*page {
+		t.Fatalf("unexpected rss: %v", got)
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	cases := map[string]Threshold{
+		"":      {},
+		"10%":   {Percent: 10},
+		"512M":  {Bytes: 512 << 20},
+		"2GiB":  {Bytes: 2 << 30},
+		"1.5g":  {Bytes: 3 << 29},
+		"4096":  {Bytes: 4096},
+		"64 MB": {Bytes: 64 << 20},
+	}
+
+	for in, want := range cases {
+		got, err := ParseThreshold(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseThreshold(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"lots", "150%", "-1G"} {
+		if _, err := ParseThreshold(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+
+	if got := (Threshold{Percent: 10}).Min(1000); got != 100 {
+		t.Fatalf("unexpected percent threshold: %d", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[uint64]string{
+		512:       "512B",
+		1536:      "1.5K",
+		300 << 20: "300M",
+		3 << 29:   "1.5G",
+	}
+
+	for in, want := range cases {
+		if got := FormatBytes(in); got != want {
+			t.Fatalf("FormatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
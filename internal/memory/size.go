@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Threshold is a minimum amount of available memory, either absolute or as a
+// percentage of the total.
+type Threshold struct {
+	Bytes   uint64
+	Percent float64
+}
+
+// ParseThreshold parses "10%" or a size like "512M" or "2GiB". An empty string
+// is the zero threshold, which disables the check.
+func ParseThreshold(s string) (Threshold, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Threshold{}, nil
+	}
+
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		v, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil || v <= 0 || v >= 100 {
+			return Threshold{}, fmt.Errorf("invalid memory percentage %q (expected 0-100%%)", s)
+		}
+
+		return Threshold{Percent: v}, nil
+	}
+
+	n, err := ParseSize(s)
+	if err != nil {
+		return Threshold{}, err
+	}
+
+	return Threshold{Bytes: n}, nil
+}
+
+func (t Threshold) IsZero() bool {
+	return t.Bytes == 0 && t.Percent == 0
+}
+
+// Min returns the threshold in bytes for a system with total bytes of memory.
+func (t Threshold) Min(total uint64) uint64 {
+	if t.Percent > 0 {
+		return uint64(float64(total) * t.Percent / 100)
+	}
+
+	return t.Bytes
+}
+
+func (t Threshold) String() string {
+	switch {
+	case t.Percent > 0:
+		return strconv.FormatFloat(t.Percent, 'f', -1, 64) + "%"
+	case t.Bytes > 0:
+		return FormatBytes(t.Bytes)
+	default:
+		return ""
+	}
+}
+
+var sizeUnits = []struct {
+	suffix string
+	scale  uint64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a byte count with an optional binary unit suffix
+// (K, M, G, T, optionally followed by B or iB).
+func ParseSize(s string) (uint64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	scale := uint64(1)
+
+	for _, unit := range sizeUnits {
+		if num, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			upper, scale = strings.TrimSpace(num), unit.scale
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(upper, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return uint64(v * float64(scale)), nil
+}
+
+// FormatBytes renders n with a short binary unit, e.g. "312M" or "1.5G".
+func FormatBytes(n uint64) string {
+	units := []string{"K", "M", "G", "T"}
+	if n < 1<<10 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+
+	v := float64(n)
+	unit := ""
+
+	for _, u := range units {
+		v /= 1024
+		unit = u
+
+		if v < 1024 {
+			break
+		}
+	}
+
+	if v < 10 {
+		return strconv.FormatFloat(v, 'f', 1, 64) + unit
+	}
+
+	return strconv.FormatFloat(v, 'f', 0, 64) + unit
+}
@@ -8,6 +8,8 @@ import (
 
 	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
+
+	"github.com/alchemmist/lazy-tmux/internal/store"
 )
 
 func (m *pickerModel) deleteCurrentWindow() error {
@@ -16,11 +18,15 @@ func (m *pickerModel) deleteCurrentWindow() error {
 		return fmt.Errorf("select a window row to delete")
 	}
 
+	return m.deleteWindowAt(row.target.SessionName, *row.target.WindowIndex)
+}
+
+func (m *pickerModel) deleteWindowAt(session string, windowIndex int) error {
 	if m.actions.DeleteWindow == nil {
 		return fmt.Errorf("delete window not available")
 	}
 
-	return m.actions.DeleteWindow(row.target.SessionName, *row.target.WindowIndex)
+	return m.actions.DeleteWindow(session, windowIndex)
 }
 
 func (m *pickerModel) confirmDeleteSession() {
@@ -96,6 +102,44 @@ func (m *pickerModel) newWindow() {
 	m.resize()
 }
 
+func (m *pickerModel) editTags() {
+	row, ok := m.currentRow()
+	if !ok || strings.TrimSpace(row.target.SessionName) == "" {
+		m.setStatus("select a session to tag")
+		return
+	}
+
+	sess, _ := m.sessionByName(row.target.SessionName)
+
+	m.pending = row.target
+	m.mode = modeEditTags
+	m.promptInput = textinput.New()
+	m.promptInput.Prompt = fmt.Sprintf("Tags for %s (comma separated): ", row.target.SessionName)
+	m.promptInput.SetValue(strings.Join(sess.Record.Tags, ","))
+	m.promptInput.CursorEnd()
+	m.promptInput.Focus()
+	m.resize()
+}
+
+func (m *pickerModel) editNote() {
+	row, ok := m.currentRow()
+	if !ok || strings.TrimSpace(row.target.SessionName) == "" {
+		m.setStatus("select a session to annotate")
+		return
+	}
+
+	sess, _ := m.sessionByName(row.target.SessionName)
+
+	m.pending = row.target
+	m.mode = modeEditNote
+	m.promptInput = textinput.New()
+	m.promptInput.Prompt = fmt.Sprintf("Note for %s: ", row.target.SessionName)
+	m.promptInput.SetValue(sess.Record.Note)
+	m.promptInput.CursorEnd()
+	m.promptInput.Focus()
+	m.resize()
+}
+
 func (m pickerModel) handlePromptKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc", "ctrl+c":
@@ -106,6 +150,16 @@ func (m pickerModel) handlePromptKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "enter":
 		switch m.mode {
+		case modeConfirmBulk:
+			if strings.EqualFold(strings.TrimSpace(m.promptInput.Value()), "y") {
+				m.runBulk(m.pendingBulk)
+				m.reload()
+				m.renderViewport()
+			}
+		case modeEditMarkedTags:
+			m.tagMarked(m.promptInput.Value())
+			m.reload()
+			m.renderViewport()
 		case modeConfirmDeleteSession:
 			val := strings.TrimSpace(m.promptInput.Value())
 			if strings.EqualFold(val, "y") {
@@ -166,6 +220,24 @@ func (m pickerModel) handlePromptKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 				m.clearStatus()
 			}
 
+			m.reload()
+			m.renderViewport()
+		case modeEditTags:
+			if err := m.setTags(m.pending.SessionName, store.ParseTags(m.promptInput.Value())); err != nil {
+				m.setStatus(err.Error())
+			} else {
+				m.clearStatus()
+			}
+
+			m.reload()
+			m.renderViewport()
+		case modeEditNote:
+			if err := m.setNote(m.pending.SessionName, strings.TrimSpace(m.promptInput.Value())); err != nil {
+				m.setStatus(err.Error())
+			} else {
+				m.clearStatus()
+			}
+
 			m.reload()
 			m.renderViewport()
 		}
@@ -237,11 +309,15 @@ func (m *pickerModel) wakeupSession() error {
 		return fmt.Errorf("select a session to wakeup")
 	}
 
+	return m.wakeupNamed(row.target.SessionName)
+}
+
+func (m *pickerModel) wakeupNamed(session string) error {
 	if m.actions.Wakeup == nil {
 		return fmt.Errorf("wakeup not available")
 	}
 
-	return m.actions.Wakeup(row.target.SessionName)
+	return m.actions.Wakeup(session)
 }
 
 func (m *pickerModel) sleepSession() error {
@@ -250,11 +326,88 @@ func (m *pickerModel) sleepSession() error {
 		return fmt.Errorf("select a session to sleep")
 	}
 
+	return m.sleepNamed(row.target.SessionName)
+}
+
+func (m *pickerModel) sleepNamed(session string) error {
 	if m.actions.Sleep == nil {
 		return fmt.Errorf("sleep not available")
 	}
 
-	return m.actions.Sleep(row.target.SessionName)
+	return m.actions.Sleep(session)
+}
+
+func (m *pickerModel) exportSession() {
+	row, ok := m.currentRow()
+	if !ok || strings.TrimSpace(row.target.SessionName) == "" {
+		m.setStatus("select a session to export")
+		return
+	}
+
+	path, err := m.exportNamed(row.target.SessionName)
+	if err != nil {
+		m.setStatus(err.Error())
+		return
+	}
+
+	m.setStatus("exported " + row.target.SessionName + " to " + path)
+}
+
+func (m *pickerModel) exportNamed(session string) (string, error) {
+	if m.actions.Export == nil {
+		return "", fmt.Errorf("export not available")
+	}
+
+	return m.actions.Export(session)
+}
+
+func (m *pickerModel) undoDelete() error {
+	if m.actions.Undo == nil {
+		return fmt.Errorf("undo not available")
+	}
+
+	return m.actions.Undo()
+}
+
+func (m *pickerModel) togglePin() error {
+	row, ok := m.currentRow()
+	if !ok || strings.TrimSpace(row.target.SessionName) == "" {
+		return fmt.Errorf("select a session to pin")
+	}
+
+	if m.actions.SetPinned == nil {
+		return fmt.Errorf("pin not available")
+	}
+
+	sess, _ := m.sessionByName(row.target.SessionName)
+
+	return m.actions.SetPinned(row.target.SessionName, !sess.Record.Pinned)
+}
+
+func (m *pickerModel) setTags(session string, tags []string) error {
+	if m.actions.SetTags == nil {
+		return fmt.Errorf("tags not available")
+	}
+
+	return m.actions.SetTags(session, tags)
+}
+
+func (m *pickerModel) setNote(session, note string) error {
+	if m.actions.SetNote == nil {
+		return fmt.Errorf("note not available")
+	}
+
+	return m.actions.SetNote(session, note)
+}
+
+func (m *pickerModel) sessionByName(name string) (Session, bool) {
+	for _, sess := range m.sessions {
+		if sess.Record.SessionName == name && sess.Server == "" && sess.Host == "" {
+			return sess, true
+		}
+	}
+
+	return Session{}, false
 }
 
 func (m *pickerModel) reload() {
@@ -269,8 +422,89 @@ func (m *pickerModel) reload() {
 	}
 
 	m.sessions = sessions
+	m.scrollback = nil
+	m.pruneMarks()
+	m.applyFilter()
+	m.ensureCursorVisible()
+}
+
+// toggleGrep switches the query between names and saved scrollback.
+func (m *pickerModel) toggleGrep() {
+	m.grep = !m.grep
+
+	m.queryInput.Prompt = "> "
+	if m.grep {
+		m.queryInput.Prompt = "grep> "
+	}
+
 	m.applyFilter()
 	m.ensureCursorVisible()
+	m.renderViewport()
+}
+
+// localOnlyKeys change the selected session, which only works for sessions of
+// the picker's own tmux server.
+var localOnlyKeys = map[string]struct{}{
+	"ctrl+d": {}, "alt+d": {}, "ctrl+r": {}, "alt+r": {}, "ctrl+n": {},
+	"alt+w": {}, "alt+s": {}, "alt+p": {}, "alt+t": {}, "alt+m": {},
+	"tab": {}, "shift+tab": {}, "alt+e": {},
+}
+
+// markedKeys act on the marked targets instead of the row under the cursor
+// while there are marks.
+var markedKeys = map[string]struct{}{
+	"ctrl+d": {}, "alt+d": {}, "alt+w": {}, "alt+s": {}, "alt+t": {}, "alt+e": {},
+}
+
+// rejectForeign reports, with a status message, when key would change a
+// session of another tmux server or host.
+func (m *pickerModel) rejectForeign(key string) bool {
+	if _, ok := localOnlyKeys[key]; !ok {
+		return false
+	}
+
+	if _, ok := markedKeys[key]; ok && len(m.marked) > 0 {
+		return m.rejectForeignMarks()
+	}
+
+	row, ok := m.currentRow()
+	if !ok || (row.target.Server == "" && row.target.Host == "") {
+		return false
+	}
+
+	if row.target.Host != "" {
+		m.setStatus(fmt.Sprintf(
+			"%s was saved on host %s; press enter to restore it here first",
+			row.target.SessionName,
+			row.target.Host,
+		))
+	} else {
+		m.setStatus(fmt.Sprintf(
+			"%s belongs to tmux server %s; open the picker there to change it",
+			row.target.SessionName,
+			row.target.Server,
+		))
+	}
+	m.renderViewport()
+
+	return true
+}
+
+// rejectForeignMarks reports a marked session that is not saved on the
+// picker's own tmux server and host.
+func (m *pickerModel) rejectForeignMarks() bool {
+	for _, session := range m.markedSessions() {
+		if _, ok := m.sessionByName(session); ok {
+			continue
+		}
+
+		m.setStatus(fmt.Sprintf("%s is not a session of this tmux server; unmark it first", session))
+		m.renderViewport()
+
+		return true
+	}
+
+	return false
 }
 
 func (m *pickerModel) currentRow() (pickerRow, bool) {
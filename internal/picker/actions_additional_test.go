@@ -9,6 +9,8 @@ import (
 	"charm.land/bubbles/v2/textinput"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
 
 func baseModelForTests() pickerModel {
@@ -242,3 +244,135 @@ func TestSetStatusTrimmedAndCleared(t *testing.T) {
 		t.Fatalf("expected height 0, got %d", model.statusHeight())
 	}
 }
+
+func TestUndoDeleteInvokesAction(t *testing.T) {
+	model := pickerModel{}
+	if err := model.undoDelete(); err == nil {
+		t.Fatal("expected error when undo action is nil")
+	}
+
+	called := false
+	model.actions.Undo = func() error {
+		called = true
+		return nil
+	}
+
+	if err := model.undoDelete(); err != nil {
+		t.Fatalf("unexpected undo error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("expected Undo to be called")
+	}
+}
+
+func TestTogglePinFlipsSessionState(t *testing.T) {
+	var got *bool
+
+	model := pickerModel{
+		sessions: []Session{{Record: snapshot.Record{
+			SessionName: "demo",
+			SessionMeta: snapshot.SessionMeta{Pinned: true},
+		}}},
+		visible: []pickerRow{{target: Target{SessionName: "demo", WindowIndex: ptr(0)}, selectable: true}},
+		actions: Actions{
+			SetPinned: func(session string, pinned bool) error {
+				got = &pinned
+				return nil
+			},
+		},
+	}
+
+	if err := model.togglePin(); err != nil {
+		t.Fatalf("togglePin error: %v", err)
+	}
+
+	if got == nil || *got {
+		t.Fatal("expected pinned session to be unpinned")
+	}
+}
+
+func TestHandlePromptKeyEditTags(t *testing.T) {
+	var got []string
+
+	model := baseModelForTests()
+	model.mode = modeEditTags
+	model.pending = Target{SessionName: "demo"}
+	model.promptInput.SetValue("work, client-x,,")
+	model.actions = Actions{
+		SetTags: func(session string, tags []string) error {
+			got = tags
+			return nil
+		},
+		Reload: func() ([]Session, error) { return nil, nil },
+	}
+
+	next, _ := model.handlePromptKey(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if next.(pickerModel).mode != modeBrowse {
+		t.Fatal("expected mode to return to browse")
+	}
+
+	if strings.Join(got, "|") != "client-x|work" {
+		t.Fatalf("unexpected tags: %v", got)
+	}
+}
+
+func TestFilteredTreeRowsMatchesTags(t *testing.T) {
+	sessions := []Session{{
+		Record: snapshot.Record{
+			SessionName: "alpha",
+			SessionMeta: snapshot.SessionMeta{Tags: []string{"client-x"}},
+		},
+		Windows: []snapshot.Window{{Index: 0, Name: "editor"}},
+	}}
+
+	rows := filteredTreeRows(sessions, "client", DefaultSortOptions().Window)
+	if len(rows) != 2 || rows[0].tags != "client-x" {
+		t.Fatalf("expected tag query to match session, got %+v", rows)
+	}
+}
+
+func TestRejectForeignBlocksChangesToOtherServers(t *testing.T) {
+	model := baseModelForTests()
+	model.visible = []pickerRow{{target: Target{SessionName: "demo", Server: "work"}, selectable: true}}
+
+	if model.rejectForeign("enter") {
+		t.Fatal("expected enter to be allowed on foreign sessions")
+	}
+
+	if !model.rejectForeign("ctrl+d") {
+		t.Fatal("expected delete of a foreign session to be rejected")
+	}
+
+	if !strings.Contains(model.statusMsg, "tmux server work") {
+		t.Fatalf("unexpected status %q", model.statusMsg)
+	}
+
+	model.visible[0].target.Server = ""
+	if model.rejectForeign("ctrl+d") {
+		t.Fatal("expected delete of a local session to be allowed")
+	}
+}
+
+func TestRejectForeignChecksMarksInsteadOfCursor(t *testing.T) {
+	model := baseModelForTests()
+	model.sessions = []Session{
+		{Record: snapshot.Record{SessionName: "local"}},
+		{Record: snapshot.Record{SessionName: "remote"}, Server: "work"},
+	}
+	model.visible = []pickerRow{{target: Target{SessionName: "remote", Server: "work"}, selectable: true}}
+	model.marked = map[markKey]bool{{session: "local", window: sessionMark}: true}
+
+	if model.rejectForeign("alt+s") {
+		t.Fatalf("expected bulk sleep of a local mark to be allowed, got status %q", model.statusMsg)
+	}
+
+	if !model.rejectForeign("alt+r") {
+		t.Fatal("expected a rename of the foreign row under the cursor to be rejected")
+	}
+
+	model.marked[markKey{session: "remote", window: sessionMark}] = true
+	if !model.rejectForeign("alt+s") || !strings.Contains(model.statusMsg, "remote is not a session of this tmux server") {
+		t.Fatalf("expected a foreign mark to be rejected, got status %q", model.statusMsg)
+	}
+}
@@ -0,0 +1,361 @@
+//go:build !lazy_fzf
+
+package picker
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"charm.land/bubbles/v2/textinput"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+	"github.com/alchemmist/lazy-tmux/internal/store"
+)
+
+// markKey is a marked session (window -1) or window.
+type markKey struct {
+	session string
+	window  int
+}
+
+const sessionMark = -1
+
+// bulkOp is a bulk action waiting for its confirmation.
+type bulkOp int
+
+const (
+	bulkDelete bulkOp = iota
+	bulkSleep
+	bulkWakeup
+	bulkExport
+)
+
+// toggleMark marks or unmarks the session under the cursor, or with window
+// set the window under the cursor, and moves on to the next row.
+func (m *pickerModel) toggleMark(window bool) {
+	row, ok := m.currentRow()
+	if !ok || strings.TrimSpace(row.target.SessionName) == "" {
+		m.setStatus("select a session to mark")
+		return
+	}
+
+	key := markKey{session: row.target.SessionName, window: sessionMark}
+	if window {
+		if row.target.WindowIndex == nil {
+			m.setStatus("select a window to mark")
+			return
+		}
+
+		key.window = *row.target.WindowIndex
+	}
+
+	if m.marked == nil {
+		m.marked = make(map[markKey]bool)
+	}
+
+	if m.marked[key] {
+		delete(m.marked, key)
+	} else {
+		m.marked[key] = true
+	}
+
+	for i := m.cursor + 1; i < len(m.visible); i++ {
+		next := m.visible[i]
+		if next.selectable && (window || next.target.SessionName != key.session) {
+			m.cursor = i
+			break
+		}
+	}
+
+	m.setStatus(m.markStatus())
+}
+
+func (m *pickerModel) markStatus() string {
+	if len(m.marked) == 0 {
+		return ""
+	}
+
+	sessions, windows := m.markedTargets()
+
+	return describeTargets(len(sessions), len(windows)) +
+		" marked; alt+d, alt+s, alt+w, alt+t and alt+e apply to all"
+}
+
+// markedTargets returns the marked sessions in name order and the marked
+// windows of sessions that are not marked themselves.
+func (m *pickerModel) markedTargets() ([]string, []markKey) {
+	var (
+		sessions []string
+		windows  []markKey
+	)
+
+	for key := range m.marked {
+		if key.window == sessionMark {
+			sessions = append(sessions, key.session)
+		}
+	}
+
+	sort.Strings(sessions)
+
+	for key := range m.marked {
+		if key.window != sessionMark && !slices.Contains(sessions, key.session) {
+			windows = append(windows, key)
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		if windows[i].session == windows[j].session {
+			return windows[i].window < windows[j].window
+		}
+
+		return windows[i].session < windows[j].session
+	})
+
+	return sessions, windows
+}
+
+// markedSessions returns the sessions with a mark on them or on one of their
+// windows, in name order.
+func (m *pickerModel) markedSessions() []string {
+	sessions, windows := m.markedTargets()
+
+	for _, key := range windows {
+		if !slices.Contains(sessions, key.session) {
+			sessions = append(sessions, key.session)
+		}
+	}
+
+	sort.Strings(sessions)
+
+	return sessions
+}
+
+// pruneMarks drops marks of sessions and windows that are gone after a
+// reload.
+func (m *pickerModel) pruneMarks() {
+	for key := range m.marked {
+		sess, ok := m.sessionByName(key.session)
+		if ok && key.window != sessionMark {
+			ok = slices.ContainsFunc(sess.Windows, func(w snapshot.Window) bool {
+				return w.Index == key.window
+			})
+		}
+
+		if !ok {
+			delete(m.marked, key)
+		}
+	}
+}
+
+// confirmBulk asks once before op runs on every marked target.
+func (m *pickerModel) confirmBulk(op bulkOp) {
+	sessions, windows := m.markedTargets()
+
+	var prompt string
+
+	switch op {
+	case bulkDelete:
+		prompt = "Delete " + describeTargets(len(sessions), len(windows))
+	case bulkSleep:
+		prompt = "Sleep " + describeTargets(len(m.markedSessions()), 0)
+	case bulkWakeup:
+		prompt = "Wake up " + describeTargets(len(m.markedSessions()), 0)
+	case bulkExport:
+		prompt = "Export " + describeTargets(len(m.markedSessions()), 0)
+	}
+
+	m.pendingBulk = op
+	m.mode = modeConfirmBulk
+	m.promptInput = textinput.New()
+	m.promptInput.Prompt = prompt + "? type y: "
+	m.promptInput.Focus()
+	m.resize()
+}
+
+// runBulk runs the confirmed op, clears the marks and sums up the results in
+// one status message.
+func (m *pickerModel) runBulk(op bulkOp) {
+	var result bulkResult
+
+	switch op {
+	case bulkDelete:
+		sessions, windows := m.markedTargets()
+
+		for _, session := range sessions {
+			result.add(session, false, m.deleteSession(session))
+		}
+
+		// Highest index first, so tmux renumbering cannot move a window
+		// that is still to be deleted.
+		for i := len(windows) - 1; i >= 0; i-- {
+			key := windows[i]
+			result.add(fmt.Sprintf("%s:%d", key.session, key.window), true, m.deleteWindowAt(key.session, key.window))
+		}
+
+		m.setStatus(result.status("deleted"))
+	case bulkSleep:
+		for _, session := range m.markedSessions() {
+			result.add(session, false, m.sleepNamed(session))
+		}
+
+		m.setStatus(result.status("slept"))
+	case bulkWakeup:
+		for _, session := range m.markedSessions() {
+			result.add(session, false, m.wakeupNamed(session))
+		}
+
+		m.setStatus(result.status("woke up"))
+	case bulkExport:
+		for _, session := range m.markedSessions() {
+			path, err := m.exportNamed(session)
+			if err == nil {
+				result.dir = filepath.Dir(path)
+			}
+
+			result.add(session, false, err)
+		}
+
+		m.setStatus(result.status("exported"))
+	}
+
+	m.marked = nil
+}
+
+func (m *pickerModel) editMarkedTags() {
+	m.mode = modeEditMarkedTags
+	m.promptInput = textinput.New()
+	m.promptInput.Prompt = fmt.Sprintf("Tags for %s (tag adds, -tag removes): ",
+		describeTargets(len(m.markedSessions()), 0))
+	m.promptInput.Focus()
+	m.resize()
+}
+
+// tagMarked adds and removes tags on every marked session; "-name" removes.
+func (m *pickerModel) tagMarked(expr string) {
+	var add, remove []string
+
+	for _, tag := range store.ParseTags(expr) {
+		if name, ok := strings.CutPrefix(tag, "-"); ok {
+			remove = append(remove, name)
+		} else {
+			add = append(add, tag)
+		}
+	}
+
+	var result bulkResult
+
+	for _, session := range m.markedSessions() {
+		sess, _ := m.sessionByName(session)
+
+		tags := slices.DeleteFunc(slices.Clone(sess.Record.Tags), func(tag string) bool {
+			return slices.Contains(remove, tag)
+		})
+
+		for _, tag := range add {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+
+		result.add(session, false, m.setTags(session, tags))
+	}
+
+	m.setStatus(result.status("tagged"))
+	m.marked = nil
+}
+
+// markColumn is the mark column's value for a row.
+func (m *pickerModel) markColumn(row pickerRow) string {
+	key := markKey{session: row.target.SessionName, window: sessionMark}
+	if row.target.WindowIndex != nil {
+		key.window = *row.target.WindowIndex
+	}
+
+	if key.session != "" && row.target.Server == "" && row.target.Host == "" && m.marked[key] {
+		return "*"
+	}
+
+	return ""
+}
+
+// bulkResult counts the outcome of a bulk action.
+type bulkResult struct {
+	sessions, windows int
+	total             markCount
+	failed            []string
+	// dir is where an export wrote its files.
+	dir string
+}
+
+type markCount struct{ sessions, windows int }
+
+func (r *bulkResult) add(label string, window bool, err error) {
+	if window {
+		r.total.windows++
+	} else {
+		r.total.sessions++
+	}
+
+	switch {
+	case err != nil:
+		r.failed = append(r.failed, fmt.Sprintf("%s: %v", label, err))
+	case window:
+		r.windows++
+	default:
+		r.sessions++
+	}
+}
+
+// status reads like "slept 2 of 3 sessions; failed c: not running".
+func (r bulkResult) status(verb string) string {
+	parts := make([]string, 0, 2)
+	if r.total.sessions > 0 || r.total.windows == 0 {
+		parts = append(parts, countOf(r.sessions, r.total.sessions, "session"))
+	}
+
+	if r.total.windows > 0 {
+		parts = append(parts, countOf(r.windows, r.total.windows, "window"))
+	}
+
+	status := verb + " " + strings.Join(parts, " and ")
+	if r.dir != "" {
+		status += " to " + r.dir
+	}
+
+	if len(r.failed) > 0 {
+		status += "; failed " + strings.Join(r.failed, "; ")
+	}
+
+	return status
+}
+
+func countOf(done, total int, noun string) string {
+	if done == total {
+		return plural(total, noun)
+	}
+
+	return fmt.Sprintf("%d of %s", done, plural(total, noun))
+}
+
+// describeTargets reads like "2 sessions and 1 window".
+func describeTargets(sessions, windows int) string {
+	switch {
+	case windows == 0:
+		return plural(sessions, "session")
+	case sessions == 0:
+		return plural(windows, "window")
+	default:
+		return plural(sessions, "session") + " and " + plural(windows, "window")
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -18,6 +18,10 @@ type pickerRow struct {
 	captured   string
 	wins       string
 	state      string
+	mem        string
+	pinned     string
+	tags       string
+	note       string
 	cmd        string
 	windowName string
 	selectable bool
@@ -40,6 +44,15 @@ type pickerModel struct {
 	mode          pickerMode
 	promptInput   textinput.Model
 	pending       Target
+	// grep makes the query search saved scrollback instead of names.
+	grep bool
+	// scrollback holds the stripped scrollback lines of m.sessions for grep,
+	// built on first use after each load.
+	scrollback []windowLines
+	// marked holds the sessions and windows marked for bulk actions.
+	marked map[markKey]bool
+	// pendingBulk is the bulk action modeConfirmBulk asks about.
+	pendingBulk bulkOp
 }
 
 type pickerMode int
@@ -51,6 +64,10 @@ const (
 	modeRenameSession
 	modeNewSession
 	modeNewWindow
+	modeEditTags
+	modeEditNote
+	modeConfirmBulk
+	modeEditMarkedTags
 )
 
 const scrollMargin = 2
@@ -96,11 +113,20 @@ func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handlePromptKey(msg)
 		}
 
+		if m.rejectForeign(msg.String()) {
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "ctrl+q", "esc":
 			m.cancelled = true
 			return m, tea.Quit
 		case "ctrl+d":
+			if len(m.marked) > 0 {
+				m.confirmBulk(bulkDelete)
+				return m, nil
+			}
+
 			if err := m.deleteCurrentWindow(); err != nil {
 				m.setStatus(err.Error())
 			} else {
@@ -112,7 +138,13 @@ func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			return m, nil
 		case "alt+d":
+			if len(m.marked) > 0 {
+				m.confirmBulk(bulkDelete)
+				return m, nil
+			}
+
 			m.confirmDeleteSession()
+
 			return m, nil
 		case "ctrl+r":
 			m.renameCurrentWindow()
@@ -125,8 +157,19 @@ func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case "ctrl+n":
 			m.newWindow()
+			return m, nil
+		case "tab", "shift+tab":
+			m.toggleMark(msg.String() == "shift+tab")
+			m.ensureCursorVisible()
+			m.renderViewport()
+
 			return m, nil
 		case "alt+w":
+			if len(m.marked) > 0 {
+				m.confirmBulk(bulkWakeup)
+				return m, nil
+			}
+
 			if err := m.wakeupSession(); err != nil {
 				m.setStatus(err.Error())
 			} else {
@@ -138,6 +181,11 @@ func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			return m, nil
 		case "alt+s":
+			if len(m.marked) > 0 {
+				m.confirmBulk(bulkSleep)
+				return m, nil
+			}
+
 			if err := m.sleepSession(); err != nil {
 				m.setStatus(err.Error())
 			} else {
@@ -147,6 +195,52 @@ func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.reload()
 			m.renderViewport()
 
+			return m, nil
+		case "alt+u":
+			if err := m.undoDelete(); err != nil {
+				m.setStatus(err.Error())
+			} else {
+				m.clearStatus()
+			}
+
+			m.reload()
+			m.renderViewport()
+
+			return m, nil
+		case "alt+p":
+			if err := m.togglePin(); err != nil {
+				m.setStatus(err.Error())
+			} else {
+				m.clearStatus()
+			}
+
+			m.reload()
+			m.renderViewport()
+
+			return m, nil
+		case "alt+t":
+			if len(m.marked) > 0 {
+				m.editMarkedTags()
+				return m, nil
+			}
+
+			m.editTags()
+
+			return m, nil
+		case "alt+e":
+			if len(m.marked) > 0 {
+				m.confirmBulk(bulkExport)
+				return m, nil
+			}
+
+			m.exportSession()
+
+			return m, nil
+		case "alt+m":
+			m.editNote()
+			return m, nil
+		case "ctrl+g":
+			m.toggleGrep()
 			return m, nil
 		case "ctrl+k":
 			m.movePrevSelectable()
@@ -198,7 +292,7 @@ func (m pickerModel) View() tea.View {
 
 	layout := buildPickerTableLayout(m.tableContentWidth())
 
-	builder.WriteString("  ")
+	builder.WriteString("    ")
 	builder.WriteString(layout.header())
 	builder.WriteString("\n")
 
@@ -208,7 +302,11 @@ func (m pickerModel) View() tea.View {
 	}
 
 	if len(m.visible) == 0 {
-		builder.WriteString("No sessions or windows match query\n")
+		if m.grep {
+			builder.WriteString("No saved scrollback matches query\n")
+		} else {
+			builder.WriteString("No sessions or windows match query\n")
+		}
 		view := tea.NewView(builder.String())
 		view.AltScreen = true
 
@@ -241,7 +339,15 @@ func (m *pickerModel) resize() {
 func (m *pickerModel) applyFilter() {
 	query := strings.TrimSpace(strings.ToLower(m.queryInput.Value()))
 
-	m.visible = filteredTreeRows(m.sessions, query, m.windowSort)
+	if m.grep && query != "" {
+		if m.scrollback == nil {
+			m.scrollback = stripScrollback(m.sessions)
+		}
+
+		m.visible = scrollbackRows(m.sessions, m.scrollback, query, m.windowSort)
+	} else {
+		m.visible = filteredTreeRows(m.sessions, query, m.windowSort)
+	}
 	if len(m.visible) == 0 {
 		m.cursor = 0
 		m.viewport.SetContent("")
@@ -269,7 +375,9 @@ func (m *pickerModel) renderViewport() {
 			pointer = "> "
 		}
 
-		line := pointer + layout.row(row)
+		mark := fmt.Sprintf("%-2s", m.markColumn(row))
+
+		line := pointer + mark + layout.row(row)
 		if rowIndex == m.cursor && row.selectable {
 			line = m.selectedStyle.Render(line)
 		}
@@ -290,7 +398,7 @@ func (m *pickerModel) tableContentWidth() int {
 		width = 80
 	}
 
-	return max(1, width-2) // keep room for line pointer ("> ")
+	return max(1, width-4) // keep room for line pointer ("> ") and mark ("* ")
 }
 
 func (m *pickerModel) ensureCursorVisible() {
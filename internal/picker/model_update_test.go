@@ -3,12 +3,16 @@
 package picker
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
 	"charm.land/bubbles/v2/textinput"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
 
 func TestPickerModelUpdateMovesCursor(t *testing.T) {
@@ -67,3 +71,252 @@ func TestPickerModelViewNoVisible(t *testing.T) {
 		t.Fatalf("unexpected view output: %s", view.Content)
 	}
 }
+
+func TestPickerModelGrepModeSearchesScrollback(t *testing.T) {
+	sessions := []Session{
+		{
+			Record: snapshot.Record{SessionName: "api", Windows: 2},
+			Windows: []snapshot.Window{
+				{Index: 0, Name: "editor", Panes: []snapshot.Pane{{Index: 0}}},
+				{Index: 1, Name: "logs", Panes: []snapshot.Pane{
+					{Index: 0, Scrollback: &snapshot.ScrollbackRef{Content: "ok\n\x1b[31m  panic: nil map\x1b[0m\n"}},
+				}},
+			},
+		},
+		{
+			Record:  snapshot.Record{SessionName: "panic-lab", Windows: 1},
+			Windows: []snapshot.Window{{Index: 0, Name: "shell", Panes: []snapshot.Pane{{Index: 0}}}},
+		},
+	}
+
+	model := newPickerModel(sessions, nil, Actions{})
+
+	next, _ := model.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModCtrl})
+	model = next.(pickerModel)
+
+	if !model.grep || model.queryInput.Prompt != "grep> " {
+		t.Fatalf("expected grep mode, got grep=%v prompt=%q", model.grep, model.queryInput.Prompt)
+	}
+
+	model.queryInput.SetValue("panic")
+	model.applyFilter()
+
+	if len(model.visible) != 2 || model.visible[0].item != "api" {
+		t.Fatalf("expected only the api session and its logs window, got %+v", model.visible)
+	}
+
+	row := model.visible[model.cursor]
+	if row.target.WindowIndex == nil || *row.target.WindowIndex != 1 || row.cmd != "panic: nil map" {
+		t.Fatalf("expected cursor on the matching window with its line, got %+v", row)
+	}
+
+	// The stripped lines are kept for later keystrokes.
+	sessions[0].Windows[1].Panes[0].Scrollback.Content = "changed\n"
+	model.queryInput.SetValue("panic: nil")
+	model.applyFilter()
+
+	if len(model.visible) != 2 {
+		t.Fatalf("expected the kept scrollback to be searched, got %+v", model.visible)
+	}
+
+	model.queryInput.SetValue("panic")
+
+	next, _ = model.Update(tea.KeyPressMsg{Code: 'g', Mod: tea.ModCtrl})
+	model = next.(pickerModel)
+
+	if model.grep || len(model.visible) != 2 || model.visible[0].item != "panic-lab" {
+		t.Fatalf("expected name search after toggling back, got %+v", model.visible)
+	}
+}
+
+func TestPickerModelBulkActionsOnMarkedSessions(t *testing.T) {
+	session := func(name string) Session {
+		return Session{
+			Record:  snapshot.Record{SessionName: name, Windows: 1},
+			Windows: []snapshot.Window{{Index: 0, Name: "shell", Panes: []snapshot.Pane{{Index: 0}}}},
+		}
+	}
+
+	sessions := []Session{session("a"), session("b"), session("c")}
+
+	var slept, deleted []string
+
+	actions := Actions{
+		Sleep: func(name string) error {
+			slept = append(slept, name)
+			if name == "c" {
+				return errors.New("not running")
+			}
+
+			return nil
+		},
+		DeleteSession: func(name string) error {
+			deleted = append(deleted, name)
+			return nil
+		},
+		Reload: func() ([]Session, error) { return sessions, nil },
+	}
+
+	model := newPickerModel(sessions, nil, actions)
+
+	press := func(msg tea.KeyPressMsg) {
+		t.Helper()
+
+		next, _ := model.Update(msg)
+		model = next.(pickerModel)
+	}
+
+	// Mark a, skip b, mark c.
+	press(tea.KeyPressMsg{Code: tea.KeyTab})
+	press(tea.KeyPressMsg{Code: 'j', Mod: tea.ModCtrl})
+	press(tea.KeyPressMsg{Code: tea.KeyTab})
+
+	if !model.marked[markKey{"a", sessionMark}] || model.marked[markKey{"b", sessionMark}] || !model.marked[markKey{"c", sessionMark}] {
+		t.Fatalf("unexpected marks: %v", model.marked)
+	}
+
+	if got := model.markColumn(model.visible[0]); model.visible[0].item != "a" || got != "*" {
+		t.Fatalf("expected a mark on the row of session a, got %q", got)
+	}
+
+	press(tea.KeyPressMsg{Code: 's', Mod: tea.ModAlt})
+
+	if model.mode != modeConfirmBulk || model.promptInput.Prompt != "Sleep 2 sessions? type y: " {
+		t.Fatalf("expected one confirmation for the marked sessions, got mode %v %q", model.mode, model.promptInput.Prompt)
+	}
+
+	model.promptInput.SetValue("y")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	if strings.Join(slept, ",") != "a,c" || len(model.marked) != 0 {
+		t.Fatalf("expected a and c slept and marks cleared, got %v %v", slept, model.marked)
+	}
+
+	if model.statusMsg != "slept 1 of 2 sessions; failed c: not running" {
+		t.Fatalf("unexpected status: %q", model.statusMsg)
+	}
+
+	model.cursor = 1
+
+	press(tea.KeyPressMsg{Code: tea.KeyTab})
+	press(tea.KeyPressMsg{Code: tea.KeyTab})
+	press(tea.KeyPressMsg{Code: 'd', Mod: tea.ModAlt})
+
+	if model.mode != modeConfirmBulk || model.promptInput.Prompt != "Delete 2 sessions? type y: " {
+		t.Fatalf("expected one confirmation for the marked sessions, got mode %v %q", model.mode, model.promptInput.Prompt)
+	}
+
+	model.promptInput.SetValue("y")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	if strings.Join(deleted, ",") != "a,b" || model.statusMsg != "deleted 2 sessions" {
+		t.Fatalf("unexpected delete: %v %q", deleted, model.statusMsg)
+	}
+}
+
+func TestPickerModelBulkActionsOnMarkedWindows(t *testing.T) {
+	windows := []snapshot.Window{
+		{Index: 0, Name: "shell", Panes: []snapshot.Pane{{Index: 0}}},
+		{Index: 1, Name: "logs", Panes: []snapshot.Pane{{Index: 0}}},
+		{Index: 2, Name: "db", Panes: []snapshot.Pane{{Index: 0}}},
+	}
+
+	sessions := []Session{
+		{Record: snapshot.Record{SessionName: "a", Windows: 3, SessionMeta: snapshot.SessionMeta{Tags: []string{"old", "work"}}}, Windows: windows},
+		{Record: snapshot.Record{SessionName: "b", Windows: 3}, Windows: windows},
+	}
+
+	var (
+		deletedWindows []string
+		deletedRuns    []string
+		tagged         []string
+		exported       []string
+	)
+
+	actions := Actions{
+		DeleteWindow: func(name string, index int) error {
+			deletedWindows = append(deletedWindows, fmt.Sprintf("%s:%d", name, index))
+			return nil
+		},
+		DeleteSession: func(name string) error {
+			deletedRuns = append(deletedRuns, name)
+			return nil
+		},
+		SetTags: func(name string, tags []string) error {
+			tagged = append(tagged, name+"="+strings.Join(tags, ","))
+			return nil
+		},
+		Export: func(name string) (string, error) {
+			exported = append(exported, name)
+			return "/data/exports/" + name + ".json", nil
+		},
+		Reload: func() ([]Session, error) { return sessions, nil },
+	}
+
+	model := newPickerModel(sessions, nil, actions)
+
+	press := func(msg tea.KeyPressMsg) {
+		t.Helper()
+
+		next, _ := model.Update(msg)
+		model = next.(pickerModel)
+	}
+
+	shiftTab := tea.KeyPressMsg{Code: tea.KeyTab, Mod: tea.ModShift}
+
+	// Windows a:0 and a:2, and the whole of session b.
+	press(shiftTab)
+	press(tea.KeyPressMsg{Code: 'j', Mod: tea.ModCtrl})
+	press(shiftTab)
+	press(tea.KeyPressMsg{Code: tea.KeyTab})
+
+	want := map[markKey]bool{{"a", 0}: true, {"a", 2}: true, {"b", sessionMark}: true}
+	if fmt.Sprint(model.marked) != fmt.Sprint(want) {
+		t.Fatalf("unexpected marks: %v", model.marked)
+	}
+
+	if model.statusMsg != "1 session and 2 windows marked; alt+d, alt+s, alt+w, alt+t and alt+e apply to all" {
+		t.Fatalf("unexpected mark status: %q", model.statusMsg)
+	}
+
+	if got := model.markColumn(model.visible[2]); got != "" {
+		t.Fatalf("unmarked window a:1 shows mark %q", got)
+	}
+
+	press(tea.KeyPressMsg{Code: 't', Mod: tea.ModAlt})
+	model.promptInput.SetValue("new, -old")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	if strings.Join(tagged, " ") != "a=work,new b=new" || model.statusMsg != "tagged 2 sessions" || len(model.marked) != 0 {
+		t.Fatalf("unexpected bulk tag: %v %q %v", tagged, model.statusMsg, model.marked)
+	}
+
+	model.marked = want
+
+	press(tea.KeyPressMsg{Code: 'e', Mod: tea.ModAlt})
+	model.promptInput.SetValue("y")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	if strings.Join(exported, ",") != "a,b" || model.statusMsg != "exported 2 sessions to /data/exports" {
+		t.Fatalf("unexpected bulk export: %v %q", exported, model.statusMsg)
+	}
+
+	model.marked = want
+
+	press(tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl})
+
+	if model.promptInput.Prompt != "Delete 1 session and 2 windows? type y: " {
+		t.Fatalf("unexpected delete prompt: %q", model.promptInput.Prompt)
+	}
+
+	model.promptInput.SetValue("y")
+	press(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	if strings.Join(deletedRuns, ",") != "b" || strings.Join(deletedWindows, ",") != "a:2,a:0" {
+		t.Fatalf("unexpected bulk delete: sessions %v windows %v", deletedRuns, deletedWindows)
+	}
+
+	if model.statusMsg != "deleted 1 session and 2 windows" {
+		t.Fatalf("unexpected delete status: %q", model.statusMsg)
+	}
+}
@@ -112,6 +112,10 @@ func TestBuildPickerTableLayoutWideShowsAllColumns(t *testing.T) {
 		"captured",
 		"wins",
 		"state",
+		"mem",
+		"pin",
+		"tags",
+		"note",
 	}
 
 	if fmt.Sprint(got) != fmt.Sprint(want) {
@@ -6,18 +6,21 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/alchemmist/lazy-tmux/internal/search"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
 
 func filteredTreeRows(sessions []Session, query string, windowSort []WindowSortKey) []pickerRow {
 	rows := make([]pickerRow, 0)
+	host := ""
 
 	for _, sess := range sessions {
 		windows := make([]snapshot.Window, len(sess.Windows))
 		copy(windows, sess.Windows)
 		sortWindows(windows, windowSort)
 
-		sessionMatch := query == "" || fuzzyMatch(query, strings.ToLower(sess.Record.SessionName))
+		sessionText := strings.ToLower(strings.Join(append([]string{sess.Record.SessionName, sess.Server, sess.Host}, sess.Record.Tags...), " "))
+		sessionMatch := query == "" || fuzzyMatch(query, sessionText)
 		matchedWindows := make([]snapshot.Window, 0, len(windows))
 
 		for _, w := range windows {
@@ -31,38 +34,145 @@ func filteredTreeRows(sessions []Session, query string, windowSort []WindowSortK
 			continue
 		}
 
-		rows = append(rows, pickerRow{
-			target:     Target{SessionName: sess.Record.SessionName},
-			item:       sess.Record.SessionName,
-			captured:   sess.Record.CapturedAt.Local().Format("2006-01-02 15:04:05"),
-			wins:       fmt.Sprintf("%d", sess.Record.Windows),
-			state:      sessionStateIcon(sess.Restored),
-			selectable: false,
-		})
+		rows = appendSessionRows(rows, &host, sess, matchedWindows, nil)
+	}
+
+	return rows
+}
+
+// windowLines maps window indexes to the scrollback lines of their panes.
+type windowLines map[int][]string
+
+// stripScrollback splits the saved scrollback of every window into lines
+// without escape sequences, once per load rather than on every keystroke.
+func stripScrollback(sessions []Session) []windowLines {
+	out := make([]windowLines, len(sessions))
 
-		for idx, win := range matchedWindows {
-			branch := "├─"
-			if idx == len(matchedWindows)-1 {
-				branch = "╰─"
+	for i, sess := range sessions {
+		out[i] = windowLines{}
+
+		for _, w := range sess.Windows {
+			for _, pane := range w.Panes {
+				if pane.Scrollback == nil || pane.Scrollback.Content == "" {
+					continue
+				}
+
+				content := strings.TrimRight(search.StripANSI(pane.Scrollback.Content), "\n")
+				out[i][w.Index] = append(out[i][w.Index], strings.Split(content, "\n")...)
 			}
+		}
+	}
+
+	return out
+}
+
+// scrollbackRows lists the windows whose saved scrollback contains query,
+// showing the first matching line in place of the window command. scrollback
+// holds the lines of each session, see stripScrollback.
+func scrollbackRows(sessions []Session, scrollback []windowLines, query string, windowSort []WindowSortKey) []pickerRow {
+	rows := make([]pickerRow, 0)
+	host := ""
+	match := search.Substring(query)
+
+	for i, sess := range sessions {
+		windows := make([]snapshot.Window, len(sess.Windows))
+		copy(windows, sess.Windows)
+		sortWindows(windows, windowSort)
+
+		matchedWindows := make([]snapshot.Window, 0, len(windows))
+		hits := make(map[int]string)
+
+		for _, w := range windows {
+			if line, ok := firstScrollbackHit(scrollback[i][w.Index], match); ok {
+				matchedWindows = append(matchedWindows, w)
+				hits[w.Index] = line
+			}
+		}
+
+		if len(matchedWindows) == 0 {
+			continue
+		}
+
+		rows = appendSessionRows(rows, &host, sess, matchedWindows, hits)
+	}
+
+	return rows
+}
 
-			wi := win.Index
-			rows = append(rows, pickerRow{
-				target:     Target{SessionName: sess.Record.SessionName, WindowIndex: &wi},
-				item:       fmt.Sprintf("  %s [%d] %s", branch, win.Index, win.Name),
-				captured:   "",
-				wins:       "",
-				state:      "",
-				cmd:        windowPreviewCommand(win),
-				windowName: win.Name,
-				selectable: true,
-			})
+func firstScrollbackHit(lines []string, match search.Matcher) (string, bool) {
+	for _, line := range lines {
+		if match(line) {
+			return strings.TrimSpace(line), true
 		}
 	}
 
+	return "", false
+}
+
+// appendSessionRows adds a session row and its window rows, after a header
+// when the session belongs to another host than the previous one. preview
+// replaces the command shown for the windows it has.
+func appendSessionRows(rows []pickerRow, host *string, sess Session, windows []snapshot.Window, preview map[int]string) []pickerRow {
+	if sess.Host != *host {
+		*host = sess.Host
+		rows = append(rows, pickerRow{item: "── host " + sess.Host + " ──"})
+	}
+
+	rows = append(rows, pickerRow{
+		target:     Target{SessionName: sess.Record.SessionName, Server: sess.Server, Host: sess.Host},
+		item:       sessionLabel(sess),
+		captured:   sess.Record.CapturedAt.Local().Format("2006-01-02 15:04:05"),
+		wins:       fmt.Sprintf("%d", sess.Record.Windows),
+		state:      sessionStateIcon(sess.Restored),
+		mem:        sessionMemory(sess),
+		pinned:     sessionPinIcon(sess.Record.Pinned),
+		tags:       strings.Join(sess.Record.Tags, ","),
+		note:       sess.Record.Note,
+		selectable: false,
+	})
+
+	for idx, win := range windows {
+		branch := "├─"
+		if idx == len(windows)-1 {
+			branch = "╰─"
+		}
+
+		cmd, ok := preview[win.Index]
+		if !ok {
+			cmd = windowPreviewCommand(win)
+		}
+
+		wi := win.Index
+		rows = append(rows, pickerRow{
+			target:     Target{SessionName: sess.Record.SessionName, WindowIndex: &wi, Server: sess.Server, Host: sess.Host},
+			item:       fmt.Sprintf("  %s [%d] %s", branch, win.Index, win.Name),
+			captured:   "",
+			wins:       "",
+			state:      "",
+			cmd:        cmd,
+			windowName: win.Name,
+			selectable: true,
+		})
+	}
+
 	return rows
 }
 
+// sessionLabel names a session, with its host and tmux server when they are
+// not the picker's own.
+func sessionLabel(sess Session) string {
+	switch {
+	case sess.Host != "" && sess.Server != "":
+		return sess.Record.SessionName + " @" + sess.Host + ":" + sess.Server
+	case sess.Host != "":
+		return sess.Record.SessionName + " @" + sess.Host
+	case sess.Server != "":
+		return sess.Record.SessionName + " @" + sess.Server
+	default:
+		return sess.Record.SessionName
+	}
+}
+
 func sessionStateIcon(restored bool) string {
 	if restored {
 		return "✓"
@@ -71,6 +181,22 @@ func sessionStateIcon(restored bool) string {
 	return ""
 }
 
+func sessionMemory(sess Session) string {
+	if !sess.Restored {
+		return ""
+	}
+
+	return sess.Memory
+}
+
+func sessionPinIcon(pinned bool) string {
+	if pinned {
+		return "★"
+	}
+
+	return ""
+}
+
 func fuzzyMatch(query, target string) bool {
 	if query == "" {
 		return true
@@ -38,3 +38,45 @@ func TestFilteredTreeRowsSessionMatchIncludesAllWindows(t *testing.T) {
 		t.Fatalf("unexpected second window: %q", rows[2].windowName)
 	}
 }
+
+func TestFilteredTreeRowsShowsMemoryOfRunningSessions(t *testing.T) {
+	sessions := []Session{
+		{Record: snapshot.Record{SessionName: "live"}, Restored: true, Memory: "300M"},
+		{Record: snapshot.Record{SessionName: "asleep"}, Memory: "300M"},
+	}
+
+	rows := filteredTreeRows(sessions, "", DefaultSortOptions().Window)
+	if len(rows) != 2 || rows[0].mem != "300M" || rows[1].mem != "" {
+		t.Fatalf("expected memory only for the running session, got %+v", rows)
+	}
+}
+
+func TestFilteredTreeRowsGroupsOtherHosts(t *testing.T) {
+	win := []snapshot.Window{{Index: 0, Name: "sh"}}
+	sessions := []Session{
+		{Record: snapshot.Record{SessionName: "work"}, Windows: win},
+		{Record: snapshot.Record{SessionName: "work"}, Windows: win, Host: "desktop"},
+		{Record: snapshot.Record{SessionName: "ops"}, Windows: win, Host: "desktop", Server: "infra"},
+	}
+
+	rows := filteredTreeRows(sessions, "", DefaultSortOptions().Window)
+	if len(rows) != 7 {
+		t.Fatalf("expected a host header and 3 sessions with a window each, got %d rows", len(rows))
+	}
+
+	if rows[2].item != "── host desktop ──" || rows[2].selectable {
+		t.Fatalf("expected host header before desktop sessions, got %+v", rows[2])
+	}
+
+	if rows[3].item != "work @desktop" || rows[5].item != "ops @desktop:infra" {
+		t.Fatalf("unexpected labels: %q %q", rows[3].item, rows[5].item)
+	}
+
+	if rows[6].target.Host != "desktop" || rows[6].target.Server != "infra" {
+		t.Fatalf("expected window target to keep its origin, got %+v", rows[6].target)
+	}
+
+	if rows := filteredTreeRows(sessions, "desktop", DefaultSortOptions().Window); len(rows) != 5 {
+		t.Fatalf("expected host name to match its sessions, got %d rows", len(rows))
+	}
+}
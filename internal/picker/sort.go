@@ -34,6 +34,8 @@ const (
 	SessionSortName     SessionSortField = "name"
 	SessionSortWindows  SessionSortField = "windows"
 	SessionSortPanes    SessionSortField = "panes"
+	SessionSortPinned   SessionSortField = "pinned"
+	SessionSortTags     SessionSortField = "tags"
 )
 
 const (
@@ -237,6 +239,10 @@ func parseSessionField(in string) (SessionSortField, bool) {
 		return SessionSortWindows, true
 	case "panes":
 		return SessionSortPanes, true
+	case "pinned", "pin":
+		return SessionSortPinned, true
+	case "tags", "tag":
+		return SessionSortTags, true
 	default:
 		return "", false
 	}
@@ -259,7 +265,7 @@ func parseWindowField(in string) (WindowSortField, bool) {
 
 func defaultSessionDirection(field SessionSortField) bool {
 	switch field {
-	case SessionSortLastUsed, SessionSortCaptured:
+	case SessionSortLastUsed, SessionSortCaptured, SessionSortPinned:
 		return true
 	default:
 		return false
@@ -307,6 +313,10 @@ func compareSessionField(a, b snapshot.Record, field SessionSortField) int { //n
 		return compareInt(a.Windows, b.Windows)
 	case SessionSortPanes:
 		return compareInt(a.Panes, b.Panes)
+	case SessionSortPinned:
+		return compareBool(a.Pinned, b.Pinned)
+	case SessionSortTags:
+		return strings.Compare(strings.Join(a.Tags, ","), strings.Join(b.Tags, ","))
 	default:
 		return 0
 	}
@@ -335,6 +345,17 @@ func compareInt(a, b int) int {
 	}
 }
 
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	default:
+		return 1
+	}
+}
+
 func sortWindows(windows []snapshot.Window, keys []WindowSortKey) {
 	sort.Slice(windows, func(windowIndexI, windowIndexJ int) bool {
 		for _, key := range keys {
@@ -1,6 +1,11 @@
 package picker
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
 
 func TestParsePickerSortOptions(t *testing.T) {
 	opts, err := ParseSortOptions("name:asc,last-used:desc", "name:desc,index:asc")
@@ -96,3 +101,41 @@ func TestParsePickerSortOptionsDefaultDirections(t *testing.T) {
 		t.Fatalf("expected index to default to asc, got %v desc=%v", win, wdesc)
 	}
 }
+
+func TestSortSessionRecordsByPinnedThenName(t *testing.T) {
+	records := []snapshot.Record{
+		{SessionName: "b"},
+		{SessionName: "c", SessionMeta: snapshot.SessionMeta{Pinned: true}},
+		{SessionName: "a"},
+	}
+
+	opts, err := ParseSortOptions("pinned,name", "")
+	if err != nil {
+		t.Fatalf("ParseSortOptions error: %v", err)
+	}
+
+	SortSessionRecords(records, opts.Session)
+
+	got := []string{records[0].SessionName, records[1].SessionName, records[2].SessionName}
+	if strings.Join(got, ",") != "c,a,b" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+}
+
+func TestSortSessionRecordsByTags(t *testing.T) {
+	records := []snapshot.Record{
+		{SessionName: "x", SessionMeta: snapshot.SessionMeta{Tags: []string{"work"}}},
+		{SessionName: "y", SessionMeta: snapshot.SessionMeta{Tags: []string{"client"}}},
+	}
+
+	opts, err := ParseSortOptions("tags:asc", "")
+	if err != nil {
+		t.Fatalf("ParseSortOptions error: %v", err)
+	}
+
+	SortSessionRecords(records, opts.Session)
+
+	if records[0].SessionName != "y" {
+		t.Fatalf("expected client-tagged session first, got %s", records[0].SessionName)
+	}
+}
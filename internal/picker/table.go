@@ -76,6 +76,42 @@ var pickerColumnSpecs = []pickerColumnSpec{
 			return r.state
 		},
 	},
+	{
+		ID:       "mem",
+		Title:    "Mem",
+		MinWidth: 5,
+		Priority: 8,
+		Value: func(r pickerRow) string {
+			return r.mem
+		},
+	},
+	{
+		ID:       "pin",
+		Title:    "Pin",
+		MinWidth: 3,
+		Priority: 5,
+		Value: func(r pickerRow) string {
+			return r.pinned
+		},
+	},
+	{
+		ID:       "tags",
+		Title:    "Tags",
+		MinWidth: 8,
+		Priority: 6,
+		Value: func(r pickerRow) string {
+			return r.tags
+		},
+	},
+	{
+		ID:       "note",
+		Title:    "Note",
+		MinWidth: 10,
+		Priority: 7,
+		Value: func(r pickerRow) string {
+			return r.note
+		},
+	},
 }
 
 func buildPickerTableLayout(totalWidth int) pickerTableLayout {
@@ -5,12 +5,25 @@ import "github.com/alchemmist/lazy-tmux/internal/snapshot"
 type Target struct {
 	SessionName string
 	WindowIndex *int
+	// Server is the tmux server of a session from another server (see
+	// tmux.Server.String); empty for the picker's own server.
+	Server string
+	// Host is the host namespace of a session saved on another machine;
+	// Server is then the tmux server it was saved from there.
+	Host string
 }
 
 type Session struct {
 	Record   snapshot.Record
 	Windows  []snapshot.Window
 	Restored bool
+	// Server is set for sessions saved from another tmux server.
+	Server string
+	// Host is set for sessions saved on another machine.
+	Host string
+	// Memory is the RSS of a running session's pane processes, formatted
+	// like "1.2G"; empty when unknown.
+	Memory string
 }
 
 type Actions struct {
@@ -23,4 +36,10 @@ type Actions struct {
 	Reload        func() ([]Session, error)
 	Wakeup        func(session string) error
 	Sleep         func(session string) error
+	Undo          func() error
+	SetPinned     func(session string, pinned bool) error
+	SetTags       func(session string, tags []string) error
+	SetNote       func(session, note string) error
+	// Export writes a session to a file and returns its path.
+	Export func(session string) (string, error)
 }
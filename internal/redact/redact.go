@@ -0,0 +1,179 @@
+// Package redact masks secrets in captured scrollback and commands before a
+// snapshot is written to disk.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// markerPrefix starts every replacement. Values that already start with it
+// are left alone, so scrollback replayed from a redacted snapshot is not
+// counted again on the next save.
+const markerPrefix = "[REDACTED"
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	// group is the submatch holding the secret; 0 is the whole match.
+	group int
+}
+
+// builtinRules detect common credentials. Where a secret follows a key or
+// flag, only the secret is replaced so the context stays readable.
+var builtinRules = []rule{
+	{name: "pem", re: regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?(?:-----END [A-Z0-9 ]*PRIVATE KEY-----|\z)`)},
+	{name: "aws-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`)},
+	{
+		name:  "aws-secret",
+		re:    regexp.MustCompile(`(?i)\baws_secret_access_key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`),
+		group: 1,
+	},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)},
+	{
+		name:  "bearer",
+		re:    regexp.MustCompile(`(?i)(?:\bauthorization:\s*[a-z]+\s+|\bbearer\s+)([A-Za-z0-9._~+/=-]{8,})`),
+		group: 1,
+	},
+	{
+		name:  "password",
+		re:    regexp.MustCompile(`(?i)--(?:password|passwd|token|secret|api-key)=(\S+)`),
+		group: 1,
+	},
+	// -pSECRET only for the MySQL clients; elsewhere -p is rarely a password.
+	{
+		name:  "password",
+		re:    regexp.MustCompile(`\b(?:mysql|mysqldump|mysqladmin|mariadb|mariadb-dump)\b[^\n]*?\s-p([^\s-]\S*)`),
+		group: 1,
+	},
+	{
+		name:  "env-secret",
+		re:    regexp.MustCompile(`\b[A-Z0-9_]*(?:PASSWORD|PASSWD|SECRET|TOKEN|API_KEY)[A-Z0-9_]*=(\S+)`),
+		group: 1,
+	},
+}
+
+// Redactor replaces secrets with "[REDACTED:<detector>]" markers.
+type Redactor struct {
+	rules []rule
+}
+
+// New returns a redactor with the built-in detectors and the given extra
+// regular expressions. An extra pattern with a capture group only redacts the
+// first group.
+func New(patterns []string) (*Redactor, error) {
+	rules := append([]rule(nil), builtinRules...)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+
+		r := rule{name: "custom", re: re}
+		if re.NumSubexp() > 0 {
+			r.group = 1
+		}
+
+		rules = append(rules, r)
+	}
+
+	return &Redactor{rules: rules}, nil
+}
+
+// String returns s with secrets replaced and how many were.
+func (r *Redactor) String(s string) (string, int) {
+	total := 0
+
+	for _, rule := range r.rules {
+		var n int
+
+		s, n = rule.replace(s)
+		total += n
+	}
+
+	return s, total
+}
+
+func (r rule) replace(s string) (string, int) {
+	matches := r.re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, 0
+	}
+
+	var b strings.Builder
+
+	last, count := 0, 0
+
+	for _, m := range matches {
+		start, end := m[2*r.group], m[2*r.group+1]
+		if start < 0 || start == end || strings.HasPrefix(s[start:end], markerPrefix) {
+			continue
+		}
+
+		b.WriteString(s[last:start])
+		b.WriteString(markerPrefix + ":" + r.name + "]")
+
+		last = end
+		count++
+	}
+
+	b.WriteString(s[last:])
+
+	return b.String(), count
+}
+
+// Masked reports whether s holds a redaction marker.
+func Masked(s string) bool {
+	return strings.Contains(s, markerPrefix)
+}
+
+// Session redacts pane scrollback, restore commands and the session
+// environment in place. Each pane's count is stored in Pane.Redactions and
+// Pane.CommandRedacted tells whether the restore command holds a marker; the
+// total, environment included, is returned.
+func (r *Redactor) Session(snap *snapshot.SessionSnapshot) int {
+	total := 0
+
+	for wi := range snap.Windows {
+		for pi := range snap.Windows[wi].Panes {
+			pane := &snap.Windows[wi].Panes[pi]
+
+			var n, count int
+
+			pane.RestoreCmd, count = r.String(pane.RestoreCmd)
+			pane.CommandRedacted = Masked(pane.RestoreCmd)
+			n += count
+
+			if pane.Scrollback != nil {
+				pane.Scrollback.Content, count = r.String(pane.Scrollback.Content)
+				n += count
+			}
+
+			pane.Redactions = n
+			total += n
+		}
+	}
+
+	// Variables are checked as NAME=value so that detectors keyed on the
+	// name, like env-secret, apply.
+	for name, value := range snap.Environment {
+		redacted, n := r.String(name + "=" + value)
+		if n == 0 {
+			continue
+		}
+
+		value, ok := strings.CutPrefix(redacted, name+"=")
+		if !ok {
+			value = markerPrefix + ":custom]"
+		}
+
+		snap.Environment[name] = value
+		total += n
+	}
+
+	return total
+}
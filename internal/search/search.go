@@ -0,0 +1,141 @@
+// Package search finds text in saved pane scrollback.
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// ansiPattern matches CSI sequences (colors, cursor movement), OSC sequences
+// (titles, hyperlinks) ended by BEL or ST, charset designations such as the
+// "ESC ( B" of tput sgr0, and other two-byte escapes.
+var ansiPattern = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[()*+][0-~]|[@-_])`)
+
+// StripANSI removes terminal escape sequences and carriage returns.
+func StripANSI(s string) string {
+	return strings.ReplaceAll(ansiPattern.ReplaceAllString(s, ""), "\r", "")
+}
+
+// Matcher reports whether a line of scrollback matches.
+type Matcher func(line string) bool
+
+// Compile returns a matcher for a regular expression.
+func Compile(pattern string, ignoreCase bool) (Matcher, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	return re.MatchString, nil
+}
+
+// Substring returns a case-insensitive substring matcher.
+func Substring(query string) Matcher {
+	query = strings.ToLower(query)
+
+	return func(line string) bool {
+		return strings.Contains(strings.ToLower(line), query)
+	}
+}
+
+// Hit is a matching line with its context. Line numbers start at 1. Context
+// lines already shown with the previous hit are left out, so the hits of one
+// pane print without repeating lines.
+type Hit struct {
+	Line   int
+	Text   string
+	Before []string
+	After  []string
+}
+
+// First returns the number of the first line shown for the hit.
+func (h Hit) First() int {
+	return h.Line - len(h.Before)
+}
+
+// Last returns the number of the last line shown for the hit.
+func (h Hit) Last() int {
+	return h.Line + len(h.After)
+}
+
+// Lines returns the lines of content that match, with up to context lines
+// around each. Escape sequences are stripped before matching.
+func Lines(content string, match Matcher, context int) []Hit {
+	lines := strings.Split(strings.TrimRight(StripANSI(content), "\n"), "\n")
+	context = max(context, 0)
+
+	var matched []int
+
+	for i, line := range lines {
+		if match(line) {
+			matched = append(matched, i)
+		}
+	}
+
+	hits := make([]Hit, 0, len(matched))
+	shown := -1
+
+	for n, i := range matched {
+		from := max(i-context, shown+1)
+
+		to := min(i+context, len(lines)-1)
+		if n+1 < len(matched) {
+			to = min(to, matched[n+1]-1)
+		}
+
+		hits = append(hits, Hit{
+			Line:   i + 1,
+			Text:   lines[i],
+			Before: lines[from:i],
+			After:  lines[i+1 : to+1],
+		})
+		shown = to
+	}
+
+	return hits
+}
+
+// Match is a hit in the scrollback of one pane.
+type Match struct {
+	Session     string
+	WindowIndex int
+	WindowName  string
+	PaneIndex   int
+	Hit
+}
+
+// Session searches the scrollback of every pane of a session snapshot.
+func Session(snap snapshot.SessionSnapshot, match Matcher, context int) []Match {
+	var out []Match
+
+	for _, w := range snap.Windows {
+		for _, p := range w.Panes {
+			if p.Scrollback == nil || p.Scrollback.Content == "" {
+				continue
+			}
+
+			for _, hit := range Lines(p.Scrollback.Content, match, context) {
+				out = append(out, Match{
+					Session:     snap.SessionName,
+					WindowIndex: w.Index,
+					WindowName:  w.Name,
+					PaneIndex:   p.Index,
+					Hit:         hit,
+				})
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func TestStripANSI(t *testing.T) {
+	in := "\x1b[1;32mok\x1b[0m done\r\n\x1b]0;title\x07plain \x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\\x1b(B"
+
+	if got := StripANSI(in); got != "ok done\nplain link" {
+		t.Fatalf("StripANSI = %q", got)
+	}
+}
+
+func TestLinesKeepsContextWithoutRepeats(t *testing.T) {
+	content := "a\nb\nERR one\nc\nERR two\nd\ne\nf\ng\nERR three\n"
+
+	match, err := Compile("err", true)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	hits := Lines(content, match, 2)
+	if len(hits) != 3 {
+		t.Fatalf("expected 3 hits, got %+v", hits)
+	}
+
+	if hits[0].Line != 3 || strings.Join(hits[0].Before, ",") != "a,b" || strings.Join(hits[0].After, ",") != "c" {
+		t.Fatalf("unexpected first hit: %+v", hits[0])
+	}
+
+	if len(hits[1].Before) != 0 || strings.Join(hits[1].After, ",") != "d,e" {
+		t.Fatalf("unexpected second hit: %+v", hits[1])
+	}
+
+	if strings.Join(hits[2].Before, ",") != "f,g" || hits[2].First() != 8 || hits[2].Last() != 10 {
+		t.Fatalf("unexpected third hit: %+v", hits[2])
+	}
+}
+
+func TestSessionReportsPanes(t *testing.T) {
+	snap := snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows: []snapshot.Window{{
+			Index: 2,
+			Name:  "build",
+			Panes: []snapshot.Pane{
+				{Index: 0},
+				{Index: 1, Scrollback: &snapshot.ScrollbackRef{Content: "make\n\x1b[31mfatal: boom\x1b[0m\n"}},
+			},
+		}},
+	}
+
+	matches := Session(snap, Substring("FATAL"), 0)
+	if len(matches) != 1 {
+		t.Fatalf("expected one match, got %+v", matches)
+	}
+
+	m := matches[0]
+	if m.Session != "demo" || m.WindowIndex != 2 || m.PaneIndex != 1 || m.Line != 2 || m.Text != "fatal: boom" {
+		t.Fatalf("unexpected match: %+v", m)
+	}
+}
+
+func TestCompileRejectsBadPattern(t *testing.T) {
+	if _, err := Compile("(", false); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+
+	if _, err := Compile("", false); err == nil {
+		t.Fatal("expected error for empty pattern")
+	}
+}
@@ -11,6 +11,10 @@ type SessionSnapshot struct {
 	CurrentWin  int       `json:"current_window"`
 	CurrentPane int       `json:"current_pane"`
 	Windows     []Window  `json:"windows"`
+	// Options holds session options set on the session itself.
+	Options map[string]string `json:"options,omitempty"`
+	// Environment holds the allowlisted session environment variables.
+	Environment map[string]string `json:"environment,omitempty"`
 }
 
 type Window struct {
@@ -20,6 +24,9 @@ type Window struct {
 	IsActive   bool   `json:"is_active"`
 	ActivePane int    `json:"active_pane"`
 	Panes      []Pane `json:"panes"`
+	Zoomed     bool   `json:"zoomed,omitempty"`
+	// Options holds window options set on the window itself.
+	Options map[string]string `json:"options,omitempty"`
 }
 
 type Pane struct {
@@ -29,13 +36,25 @@ type Pane struct {
 	RestoreCmd  string         `json:"restore_cmd,omitempty"`
 	Scrollback  *ScrollbackRef `json:"scrollback,omitempty"`
 	IsActive    bool           `json:"is_active"`
+	Title       string         `json:"title,omitempty"`
+	Marked      bool           `json:"marked,omitempty"`
+	// Redactions counts the secrets masked in the pane's scrollback and
+	// restore command when it was saved.
+	Redactions int `json:"redactions,omitempty"`
+	// CommandRedacted marks a RestoreCmd with secrets masked. Such a command
+	// cannot work, so restore leaves the pane at its shell instead.
+	CommandRedacted bool `json:"command_redacted,omitempty"`
 }
 
 type ScrollbackRef struct {
-	Ref     string `json:"ref,omitempty"`
-	Lines   int    `json:"lines,omitempty"`
-	Bytes   int    `json:"bytes,omitempty"`
-	Content string `json:"-"`
+	Ref   string `json:"ref,omitempty"`
+	Hash  string `json:"hash,omitempty"`
+	Codec string `json:"codec,omitempty"`
+	Lines int    `json:"lines,omitempty"`
+	Bytes int    `json:"bytes,omitempty"`
+	// Encrypted blobs are sealed with the store's key (see store.Encryption).
+	Encrypted bool   `json:"encrypted,omitempty"`
+	Content   string `json:"-"`
 }
 
 type Index struct {
@@ -51,4 +70,27 @@ type Record struct {
 	LastAccessed time.Time `json:"last_accessed,omitempty"`
 	Windows      int       `json:"windows"`
 	Panes        int       `json:"panes"`
+	SessionMeta
+}
+
+// SessionMeta is user-provided metadata kept in the index across re-saves.
+type SessionMeta struct {
+	Pinned bool     `json:"pinned,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Note   string   `json:"note,omitempty"`
+}
+
+const (
+	TrashKindSession = "session"
+	TrashKindWindow  = "window"
+)
+
+type TrashEntry struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	SessionName string    `json:"session_name"`
+	WindowIndex int       `json:"window_index,omitempty"`
+	WindowName  string    `json:"window_name,omitempty"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	Record      Record    `json:"record"`
 }
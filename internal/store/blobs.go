@@ -0,0 +1,378 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// Scrollback is stored as content-addressed blobs shared by sessions and trash
+// entries: blobs/<first two hex digits>/<sha256>.log[.gz]. A blob is written
+// only when no blob with the same hash and codec exists yet, and removed once
+// no session snapshot or trash entry references it anymore, as recorded in
+// the blob owners index.
+const blobsDirName = "blobs"
+
+func hashScrollback(content string) string {
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func blobRef(hash, codec string, encrypted bool) string {
+	name := hash + ".log" + codecExt(codec)
+	if encrypted {
+		name += encExt
+	}
+
+	return filepath.Join(blobsDirName, hash[:2], name)
+}
+
+// writeBlobUnlocked stores content as a blob and returns a ref describing it
+// and whether a new file was written.
+func (s *Store) writeBlobUnlocked(content string) (snapshot.ScrollbackRef, bool, error) {
+	hash, err := s.blobHashUnlocked(content)
+	if err != nil {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("encrypt scrollback: %w", err)
+	}
+
+	ref := snapshot.ScrollbackRef{
+		Ref:       blobRef(hash, s.codec, s.enc.Scrollback),
+		Hash:      hash,
+		Codec:     refCodec(s.codec),
+		Lines:     countLines(content),
+		Bytes:     len(content),
+		Encrypted: s.enc.Scrollback,
+	}
+
+	path := filepath.Join(s.baseDir, ref.Ref)
+	if _, err := os.Stat(path); err == nil {
+		return ref, false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("stat scrollback blob: %w", err)
+	}
+
+	data, err := encodeScrollback(s.codec, content)
+	if err != nil {
+		return snapshot.ScrollbackRef{}, false, err
+	}
+
+	if s.enc.Scrollback {
+		if data, err = s.sealUnlocked(data); err != nil {
+			return snapshot.ScrollbackRef{}, false, fmt.Errorf("encrypt scrollback: %w", err)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, scrollbackDirPerm); err != nil {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("create blob dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".blob-*")
+	if err != nil {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("create blob temp file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("write scrollback blob: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("write scrollback blob: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), scrollbackFilePerm); err != nil {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("chmod scrollback blob: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return snapshot.ScrollbackRef{}, false, fmt.Errorf("rename scrollback blob: %w", err)
+	}
+
+	return ref, true, nil
+}
+
+// storeScrollbackUnlocked replaces pane scrollback content with blob refs and
+// returns how many new blobs were written.
+func (s *Store) storeScrollbackUnlocked(sessionSnapshot *snapshot.SessionSnapshot) (int, error) {
+	windows := make([]snapshot.Window, len(sessionSnapshot.Windows))
+	written := 0
+
+	for wi, window := range sessionSnapshot.Windows {
+		panes := make([]snapshot.Pane, len(window.Panes))
+		copy(panes, window.Panes)
+
+		for pi := range panes {
+			pane := &panes[pi]
+			if pane.Scrollback == nil || strings.TrimSpace(pane.Scrollback.Content) == "" {
+				pane.Scrollback = nil
+				continue
+			}
+
+			ref, isNew, err := s.writeBlobUnlocked(pane.Scrollback.Content)
+			if err != nil {
+				return written, err
+			}
+
+			if isNew {
+				written++
+			}
+
+			pane.Scrollback = &ref
+		}
+
+		window.Panes = panes
+		windows[wi] = window
+	}
+
+	sessionSnapshot.Windows = windows
+
+	return written, nil
+}
+
+func blobRefs(sessionSnapshot snapshot.SessionSnapshot) map[string]struct{} {
+	refs := map[string]struct{}{}
+
+	for _, window := range sessionSnapshot.Windows {
+		for _, pane := range window.Panes {
+			if pane.Scrollback == nil || !isBlobRef(pane.Scrollback.Ref) {
+				continue
+			}
+
+			refs[filepath.Clean(pane.Scrollback.Ref)] = struct{}{}
+		}
+	}
+
+	return refs
+}
+
+func isBlobRef(ref string) bool {
+	return strings.HasPrefix(filepath.Clean(ref), blobsDirName+string(os.PathSeparator))
+}
+
+// blobOwnersFileName indexes which snapshot files reference each blob, so a
+// save or delete releases blobs without reading every other snapshot.
+const blobOwnersFileName = "blob-owners.json"
+
+type blobOwners struct {
+	// Complete is false when some snapshot could not be read while the index
+	// was rebuilt; blobs are then only removed by GCBlobs.
+	Complete bool                `json:"complete"`
+	Owners   map[string][]string `json:"owners"`
+}
+
+func (s *Store) blobOwnersPath() string {
+	return filepath.Join(s.baseDir, blobOwnersFileName)
+}
+
+// blobOwner names a snapshot file in the owners index.
+func (s *Store) blobOwner(path string) string {
+	rel, err := filepath.Rel(s.baseDir, path)
+	if err != nil {
+		return path
+	}
+
+	return rel
+}
+
+// scanBlobOwnersUnlocked reads every session and trash snapshot. Snapshots
+// that cannot be read are reported in the error; the index covers the rest.
+func (s *Store) scanBlobOwnersUnlocked() (blobOwners, error) {
+	index := blobOwners{Complete: true, Owners: map[string][]string{}}
+
+	sessionFiles, err := filepath.Glob(filepath.Join(s.baseDir, sessionsDirName, "*.json"))
+	if err != nil {
+		return blobOwners{}, fmt.Errorf("list session files: %w", err)
+	}
+
+	trashFiles, err := filepath.Glob(filepath.Join(s.baseDir, trashDirName, "*", trashSnapshotFileName))
+	if err != nil {
+		return blobOwners{}, fmt.Errorf("list trash files: %w", err)
+	}
+
+	var errs []error
+
+	for _, path := range append(sessionFiles, trashFiles...) {
+		sessionSnapshot, err := s.readSnapshotUnlocked(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				index.Complete = false
+				errs = append(errs, fmt.Errorf("read snapshot %s: %w", s.blobOwner(path), err))
+			}
+
+			continue
+		}
+
+		owner := s.blobOwner(path)
+		for ref := range blobRefs(sessionSnapshot) {
+			index.Owners[ref] = append(index.Owners[ref], owner)
+		}
+	}
+
+	return index, errors.Join(errs...)
+}
+
+// loadBlobOwnersUnlocked reads the owners index, rebuilding it from the
+// snapshots when it is missing or unreadable.
+func (s *Store) loadBlobOwnersUnlocked() blobOwners {
+	var index blobOwners
+
+	data, err := os.ReadFile(s.blobOwnersPath())
+	if err == nil {
+		if err = json.Unmarshal(data, &index); err == nil && index.Owners != nil {
+			return index
+		}
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		s.logger.Warn("blob owners index unreadable, rebuilding", "error", err)
+	}
+
+	index, err = s.scanBlobOwnersUnlocked()
+	if err != nil {
+		s.logger.Warn("blob owners index incomplete, unreferenced blobs are left for gc", "error", err)
+	}
+
+	if index.Owners == nil {
+		index.Owners = map[string][]string{}
+	}
+
+	return index
+}
+
+// setBlobOwnerUnlocked records refs as every blob the snapshot file owner
+// references and removes the blobs no snapshot references anymore. Failures
+// are logged and only leave blobs behind for GCBlobs.
+func (s *Store) setBlobOwnerUnlocked(owner string, refs map[string]struct{}) {
+	index := s.loadBlobOwnersUnlocked()
+
+	var released []string
+
+	for ref, owners := range index.Owners {
+		if _, keep := refs[ref]; keep {
+			continue
+		}
+
+		i := slices.Index(owners, owner)
+		if i < 0 {
+			continue
+		}
+
+		if owners = slices.Delete(owners, i, i+1); len(owners) > 0 {
+			index.Owners[ref] = owners
+			continue
+		}
+
+		delete(index.Owners, ref)
+		released = append(released, ref)
+	}
+
+	for ref := range refs {
+		if !slices.Contains(index.Owners[ref], owner) {
+			index.Owners[ref] = append(index.Owners[ref], owner)
+		}
+	}
+
+	if err := writeJSONAtomic(s.blobOwnersPath(), index); err != nil {
+		s.logger.Warn("blob owners index not updated", "owner", owner, "error", err)
+		// A stale index could release blobs still in use; rebuild it instead.
+		_ = os.Remove(s.blobOwnersPath())
+
+		return
+	}
+
+	if !index.Complete {
+		return
+	}
+
+	removed := 0
+
+	for _, ref := range released {
+		if err := os.Remove(filepath.Join(s.baseDir, ref)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("scrollback blob not removed", "ref", ref, "error", err)
+			continue
+		}
+
+		removed++
+	}
+
+	if removed > 0 {
+		s.logger.Debug("scrollback blobs released", "owner", owner, "removed", removed)
+	}
+}
+
+// GCBlobs removes every blob that is not referenced by a session or trash
+// snapshot, including ones orphaned by interrupted saves.
+func (s *Store) GCBlobs() (int, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return s.gcBlobsUnlocked()
+}
+
+func (s *Store) gcBlobsUnlocked() (int, error) {
+	index, err := s.scanBlobOwnersUnlocked()
+	if err != nil {
+		return 0, fmt.Errorf("gc scrollback blobs: %w", err)
+	}
+
+	if err := writeJSONAtomic(s.blobOwnersPath(), index); err != nil {
+		return 0, err
+	}
+
+	root := filepath.Join(s.baseDir, blobsDirName)
+	removed := 0
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.baseDir, path)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := index.Owners[rel]; ok {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		removed++
+
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("gc scrollback blobs: %w", err)
+	}
+
+	s.logger.Info("scrollback blobs collected", "removed", removed)
+
+	return removed, nil
+}
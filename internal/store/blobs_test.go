@@ -0,0 +1,305 @@
+package store
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSaveSessionSharesIdenticalScrollbackBlobs(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	for _, name := range []string{"one", "two"} {
+		if err := s.SaveSession(scrollbackSnapshot(name, "same output\n")); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	refOne := readRawSession(t, s, "one").Windows[0].Panes[0].Scrollback
+	refTwo := readRawSession(t, s, "two").Windows[0].Panes[0].Scrollback
+
+	if refOne.Ref != refTwo.Ref || refOne.Hash == "" || refOne.Hash != refTwo.Hash {
+		t.Fatalf("expected shared blob, got %+v and %+v", refOne, refTwo)
+	}
+
+	if got := countBlobs(t, base); got != 1 {
+		t.Fatalf("expected 1 blob, got %d", got)
+	}
+}
+
+func TestSaveSessionDoesNotRewriteUnchangedBlob(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "unchanged\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	path := filepath.Join(base, readRawSession(t, s, "demo").Windows[0].Panes[0].Scrollback.Ref)
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "unchanged\n")); err != nil {
+		t.Fatalf("resave: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat blob: %v", err)
+	}
+
+	if !info.ModTime().Equal(old) {
+		t.Fatalf("expected blob to be left untouched, mtime changed to %s", info.ModTime())
+	}
+}
+
+func TestSaveSessionReleasesReplacedBlob(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "first\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	firstRef := readRawSession(t, s, "demo").Windows[0].Panes[0].Scrollback.Ref
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "second\n")); err != nil {
+		t.Fatalf("resave: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(base, firstRef)); !os.IsNotExist(err) {
+		t.Fatalf("expected replaced blob to be removed, got err=%v", err)
+	}
+
+	if got := countBlobs(t, base); got != 1 {
+		t.Fatalf("expected 1 blob, got %d", got)
+	}
+}
+
+func TestDeleteSessionKeepsBlobsStillReferenced(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	for _, name := range []string{"one", "two"} {
+		if err := s.SaveSession(scrollbackSnapshot(name, "shared\n")); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	if err := s.DeleteSession("one"); err != nil {
+		t.Fatalf("delete one: %v", err)
+	}
+
+	if got := countBlobs(t, base); got != 1 {
+		t.Fatalf("expected shared blob to survive, got %d blobs", got)
+	}
+
+	if err := s.DeleteSession("two"); err != nil {
+		t.Fatalf("delete two: %v", err)
+	}
+
+	if got := countBlobs(t, base); got != 0 {
+		t.Fatalf("expected no blobs after deleting all sessions, got %d", got)
+	}
+}
+
+func TestTrashKeepsBlobsUntilEmptied(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "keep me\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := s.TrashSession("demo"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	if got := countBlobs(t, base); got != 1 {
+		t.Fatalf("expected trashed blob to survive, got %d blobs", got)
+	}
+
+	if _, err := s.EmptyTrash(time.Time{}); err != nil {
+		t.Fatalf("empty trash: %v", err)
+	}
+
+	if got := countBlobs(t, base); got != 0 {
+		t.Fatalf("expected no blobs after emptying trash, got %d", got)
+	}
+}
+
+func TestGCBlobsRemovesOrphans(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "live\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	orphan := filepath.Join(base, blobRef(hashScrollback("orphan\n"), CodecGzip, false))
+	if err := os.MkdirAll(filepath.Dir(orphan), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(orphan, []byte("x"), 0o600); err != nil {
+		t.Fatalf("write orphan: %v", err)
+	}
+
+	removed, err := s.GCBlobs()
+	if err != nil {
+		t.Fatalf("GCBlobs error: %v", err)
+	}
+
+	if removed != 1 || countBlobs(t, base) != 1 {
+		t.Fatalf("expected only the orphan to be removed, removed=%d", removed)
+	}
+}
+
+func TestSaveSessionToleratesUnreadableSnapshots(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	for _, name := range []string{"one", "two"} {
+		if err := s.SaveSession(scrollbackSnapshot(name, "shared\n")); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	if err := os.WriteFile(s.sessionPath("two"), []byte("{broken"), 0o600); err != nil {
+		t.Fatalf("corrupt two: %v", err)
+	}
+
+	// The owners index still knows two references the shared blob.
+	if err := s.SaveSession(scrollbackSnapshot("one", "changed\n")); err != nil {
+		t.Fatalf("resave with index: %v", err)
+	}
+
+	if got := countBlobs(t, base); got != 2 {
+		t.Fatalf("expected the blob of two to survive, got %d blobs", got)
+	}
+
+	// Rebuilt without two, the index cannot vouch for any blob, so nothing
+	// is removed until GCBlobs can read every snapshot.
+	if err := os.Remove(filepath.Join(base, blobOwnersFileName)); err != nil {
+		t.Fatalf("remove owners index: %v", err)
+	}
+
+	if err := s.SaveSession(scrollbackSnapshot("one", "changed again\n")); err != nil {
+		t.Fatalf("resave without index: %v", err)
+	}
+
+	if got := countBlobs(t, base); got != 3 {
+		t.Fatalf("expected no blob removed by an incomplete index, got %d blobs", got)
+	}
+
+	if _, err := s.GCBlobs(); err == nil {
+		t.Fatal("expected GCBlobs to refuse an unreadable snapshot")
+	}
+}
+
+func TestSaveSessionRemovesLegacyScrollbackDir(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	legacyDir := filepath.Join(base, scrollbackDir, sanitizeName("demo"))
+	if err := os.MkdirAll(legacyDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(legacyDir, "w0_p0.log"), []byte("old\n"), 0o600); err != nil {
+		t.Fatalf("write legacy: %v", err)
+	}
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "new\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := os.Stat(legacyDir); !os.IsNotExist(err) {
+		t.Fatalf("expected legacy scrollback dir to be removed, got err=%v", err)
+	}
+}
+
+func countBlobs(t *testing.T, base string) int {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(base, blobsDirName, "*", "*.log*"))
+	if err != nil {
+		t.Fatalf("glob blobs: %v", err)
+	}
+
+	return len(matches)
+}
+
+func TestLoadSessionLogsMissingScrollbackBlob(t *testing.T) {
+	base := t.TempDir()
+
+	var logs bytes.Buffer
+
+	s := NewWithOptions(base, Options{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "lost output\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	ref := readRawSession(t, s, "demo").Windows[0].Panes[0].Scrollback.Ref
+	if err := os.Remove(filepath.Join(base, ref)); err != nil {
+		t.Fatalf("remove blob: %v", err)
+	}
+
+	snap, err := s.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if snap.Windows[0].Panes[0].Scrollback.Content != "" {
+		t.Fatalf("expected no scrollback content, got %q", snap.Windows[0].Panes[0].Scrollback.Content)
+	}
+
+	if !strings.Contains(logs.String(), "scrollback file missing") || !strings.Contains(logs.String(), "session=demo") {
+		t.Fatalf("expected a warning for the missing blob, got %q", logs.String())
+	}
+}
+
+func TestSaveSessionWaitsForDataDirLock(t *testing.T) {
+	base := t.TempDir()
+	s := New(base)
+
+	// A second open file description stands in for another process.
+	held, err := os.OpenFile(filepath.Join(base, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("open lock: %v", err)
+	}
+
+	defer held.Close()
+
+	if err := syscall.Flock(int(held.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatalf("flock: %v", err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() { done <- s.SaveSession(scrollbackSnapshot("demo", "output\n")) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("save finished while the data dir was locked: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := syscall.Flock(int(held.Fd()), syscall.LOCK_UN); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
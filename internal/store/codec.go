@@ -0,0 +1,93 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// CodecNone stores scrollback as raw text. Refs written before compression
+	// existed carry no codec and are read the same way.
+	CodecNone = "none"
+	CodecGzip = "gzip"
+
+	DefaultCodec = CodecGzip
+)
+
+func ParseCodec(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return DefaultCodec, nil
+	case CodecNone, "raw":
+		return CodecNone, nil
+	case CodecGzip, "gz":
+		return CodecGzip, nil
+	default:
+		return "", fmt.Errorf("unsupported scrollback codec %q (expected gzip|none)", name)
+	}
+}
+
+// refCodec is the value recorded in snapshot.ScrollbackRef.Codec.
+func refCodec(codec string) string {
+	if codec == CodecNone {
+		return ""
+	}
+
+	return codec
+}
+
+func codecExt(codec string) string {
+	if codec == CodecGzip {
+		return ".gz"
+	}
+
+	return ""
+}
+
+func encodeScrollback(codec, content string) ([]byte, error) {
+	switch codec {
+	case CodecNone, "":
+		return []byte(content), nil
+	case CodecGzip:
+		var buf bytes.Buffer
+
+		zw := gzip.NewWriter(&buf)
+		if _, err := io.WriteString(zw, content); err != nil {
+			return nil, fmt.Errorf("gzip scrollback: %w", err)
+		}
+
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("gzip scrollback: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported scrollback codec %q", codec)
+	}
+}
+
+func decodeScrollback(codec string, data []byte) (string, error) {
+	switch codec {
+	case CodecNone, "":
+		return string(data), nil
+	case CodecGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("gunzip scrollback: %w", err)
+		}
+
+		defer zr.Close()
+
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			return "", fmt.Errorf("gunzip scrollback: %w", err)
+		}
+
+		return string(out), nil
+	default:
+		return "", fmt.Errorf("unsupported scrollback codec %q", codec)
+	}
+}
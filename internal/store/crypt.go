@@ -0,0 +1,252 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// Encrypted files start with encMagic followed by a random nonce and the
+// AES-256-GCM sealed content. Scrollback is compressed before it is sealed.
+const (
+	encMagic = "LZTENC1\n"
+	encExt   = ".enc"
+)
+
+// ErrNoKey is returned when encrypted data is read or written without a
+// usable key.
+var ErrNoKey = errors.New("encryption key not available")
+
+// Encryption selects what the store encrypts and where its key comes from.
+type Encryption struct {
+	// Scrollback encrypts newly written scrollback blobs.
+	Scrollback bool
+	// Sessions encrypts newly written session and trash snapshots. The index
+	// (names, times, tags and notes) stays readable.
+	Sessions bool
+	// Key returns the 32-byte key. It is called when encrypted data is first
+	// read or written, so commands that never touch it do not ask for it.
+	Key func() ([]byte, error)
+}
+
+// LoadKey reads a key from a file, an environment variable or the output of
+// a shell command, the first one set. The key must be 32 bytes written as
+// hex or base64, e.g. from "openssl rand -hex 32".
+func LoadKey(file, env, command string) ([]byte, error) {
+	var raw string
+
+	switch {
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+
+		raw = string(b)
+	case env != "":
+		raw = os.Getenv(env)
+		if strings.TrimSpace(raw) == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", env)
+		}
+	case command != "":
+		out, err := exec.Command("sh", "-c", command).Output()
+		if err != nil {
+			return nil, fmt.Errorf("run key command: %w", err)
+		}
+
+		raw = string(out)
+	default:
+		return nil, errors.New("no key source configured (set encryption.key_file, key_env or key_command)")
+	}
+
+	return parseKey(raw)
+}
+
+func parseKey(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+
+	if key, err := hex.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	return nil, errors.New("key must be 32 bytes in hex (64 digits) or base64")
+}
+
+// The loaded key is never used directly: HKDF-SHA256 derives one subkey for
+// sealing and one for naming encrypted blobs, so neither primitive shares a
+// key with the other.
+const (
+	sealKeyInfo     = "lazy-tmux seal"
+	blobNameKeyInfo = "lazy-tmux blob-name"
+)
+
+// aeadUnlocked returns the cipher, loading the key on first use.
+func (s *Store) aeadUnlocked() (cipher.AEAD, error) {
+	if s.aead != nil {
+		return s.aead, nil
+	}
+
+	if s.enc.Key == nil {
+		return nil, fmt.Errorf("%w: no key source configured (set encryption.key_file, key_env or key_command)", ErrNoKey)
+	}
+
+	key, err := s.enc.Key()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoKey, err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: key must be 32 bytes, got %d", ErrNoKey, len(key))
+	}
+
+	sealKey, err := hkdf.Key(sha256.New, key, nil, sealKeyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive seal key: %w", err)
+	}
+
+	nameKey, err := hkdf.Key(sha256.New, key, nil, blobNameKeyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive blob name key: %w", err)
+	}
+
+	block, err := aes.NewCipher(sealKey)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+
+	s.aead = aead
+	s.nameKey = nameKey
+
+	return aead, nil
+}
+
+func (s *Store) sealUnlocked(data []byte) ([]byte, error) {
+	aead, err := s.aeadUnlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	out := append([]byte(encMagic), nonce...)
+
+	return aead.Seal(out, nonce, data, nil), nil
+}
+
+func (s *Store) openUnlocked(data []byte) ([]byte, error) {
+	aead, err := s.aeadUnlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[len(encMagic):]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("decrypt: truncated data")
+	}
+
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("decrypt: wrong key or corrupted data")
+	}
+
+	return plain, nil
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encMagic))
+}
+
+// blobHashUnlocked names an encrypted blob by a keyed hash, so that blob
+// names do not reveal the hash of the plaintext.
+func (s *Store) blobHashUnlocked(content string) (string, error) {
+	if !s.enc.Scrollback {
+		return hashScrollback(content), nil
+	}
+
+	if _, err := s.aeadUnlocked(); err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, s.nameKey)
+	mac.Write([]byte(content))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeSnapshotUnlocked writes a session or trash snapshot, sealed when
+// session encryption is on.
+func (s *Store) writeSnapshotUnlocked(path string, sessionSnapshot snapshot.SessionSnapshot) error {
+	if !s.enc.Sessions {
+		return writeJSONAtomic(path, sessionSnapshot)
+	}
+
+	data, err := json.Marshal(sessionSnapshot)
+	if err != nil {
+		return fmt.Errorf("marshal json: %w", err)
+	}
+
+	sealed, err := s.sealUnlocked(data)
+	if err != nil {
+		return fmt.Errorf("encrypt snapshot: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, scrollbackFilePerm); err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename tmp file: %w", err)
+	}
+
+	return nil
+}
+
+// readSnapshotUnlocked reads a session or trash snapshot, encrypted or not,
+// without hydrating its scrollback.
+func (s *Store) readSnapshotUnlocked(path string) (snapshot.SessionSnapshot, error) {
+	var out snapshot.SessionSnapshot
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return out, err
+	}
+
+	if isEncrypted(b) {
+		if b, err = s.openUnlocked(b); err != nil {
+			return out, fmt.Errorf("snapshot %s is encrypted: %w", path, err)
+		}
+	}
+
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKeyHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func testKey(hexKey string) func() ([]byte, error) {
+	return func() ([]byte, error) { return parseKey(hexKey) }
+}
+
+func TestEncryptedScrollbackRoundTrip(t *testing.T) {
+	base := t.TempDir()
+	s := NewWithOptions(base, Options{Encryption: Encryption{Scrollback: true, Key: testKey(testKeyHex)}})
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "top secret output\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	ref := readRawSession(t, s, "demo").Windows[0].Panes[0].Scrollback
+	if !ref.Encrypted || !strings.HasSuffix(ref.Ref, ".gz"+encExt) || ref.Hash == hashScrollback("top secret output\n") {
+		t.Fatalf("expected an encrypted blob named by a keyed hash, got %+v", ref)
+	}
+
+	data, err := os.ReadFile(filepath.Join(base, ref.Ref))
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+
+	if !isEncrypted(data) || bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("expected sealed blob, got %q", data)
+	}
+
+	loaded, err := s.LoadSession("demo")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if got := loaded.Windows[0].Panes[0].Scrollback.Content; got != "top secret output\n" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
+
+func TestEncryptionDerivesSeparateKeys(t *testing.T) {
+	s := NewWithOptions(t.TempDir(), Options{Encryption: Encryption{Scrollback: true, Key: testKey(testKeyHex)}})
+
+	raw, _ := parseKey(testKeyHex)
+
+	hash, err := s.blobHashUnlocked("output\n")
+	if err != nil {
+		t.Fatalf("blob hash: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, raw)
+	mac.Write([]byte("output\n"))
+
+	if hash == hex.EncodeToString(mac.Sum(nil)) || bytes.Equal(s.nameKey, raw) {
+		t.Fatal("expected blob names keyed by a derived key")
+	}
+
+	sealed, err := s.sealUnlocked([]byte("output\n"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	block, _ := aes.NewCipher(raw)
+	aead, _ := cipher.NewGCM(block)
+	data := sealed[len(encMagic):]
+
+	if _, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil); err == nil {
+		t.Fatal("expected data sealed with a derived key, not the loaded one")
+	}
+}
+
+func TestEncryptedSessionRoundTrip(t *testing.T) {
+	base := t.TempDir()
+	enc := Encryption{Scrollback: true, Sessions: true, Key: testKey(testKeyHex)}
+	s := NewWithOptions(base, Options{Encryption: enc})
+
+	if err := s.SaveSession(scrollbackSnapshot("demo", "output\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	path, _ := s.SessionPath("demo")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read session: %v", err)
+	}
+
+	if !isEncrypted(data) || bytes.Contains(data, []byte("zsh")) {
+		t.Fatalf("expected sealed session file, got %q", data)
+	}
+
+	if _, err := s.TrashSession("demo"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	entries, err := s.ListTrash()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("list trash: %v %v", entries, err)
+	}
+
+	_, snap, err := s.LoadTrash(entries[0].ID)
+	if err != nil {
+		t.Fatalf("load trash: %v", err)
+	}
+
+	if snap.Windows[0].Panes[0].Scrollback.Content != "output\n" {
+		t.Fatalf("unexpected trash snapshot: %+v", snap)
+	}
+
+	// Blobs referenced from encrypted snapshots stay live.
+	if removed, err := s.GCBlobs(); err != nil || removed != 0 {
+		t.Fatalf("GCBlobs = %d, %v", removed, err)
+	}
+}
+
+func TestEncryptedDataWithoutKeyFails(t *testing.T) {
+	base := t.TempDir()
+	enc := Encryption{Scrollback: true, Key: testKey(testKeyHex)}
+
+	if err := NewWithOptions(base, Options{Encryption: enc}).SaveSession(scrollbackSnapshot("demo", "output\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := New(base).LoadSession("demo"); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+
+	wrong := strings.Repeat("ff", 32)
+	if _, err := NewWithOptions(base, Options{Encryption: Encryption{Key: testKey(wrong)}}).LoadSession("demo"); err == nil ||
+		!strings.Contains(err.Error(), "wrong key") {
+		t.Fatalf("expected wrong key error, got %v", err)
+	}
+
+	failing := Encryption{Scrollback: true, Key: func() ([]byte, error) { return nil, errors.New("pass: locked") }}
+	if err := NewWithOptions(base, Options{Encryption: failing}).SaveSession(scrollbackSnapshot("other", "x\n")); !errors.Is(err, ErrNoKey) ||
+		!strings.Contains(err.Error(), "pass: locked") {
+		t.Fatalf("expected save to fail without key, got %v", err)
+	}
+}
+
+func TestLoadKeySources(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(file, []byte(testKeyHex+"\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	t.Setenv("LAZY_TMUX_TEST_KEY", "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+
+	want, _ := parseKey(testKeyHex)
+
+	for name, load := range map[string]func() ([]byte, error){
+		"file":    func() ([]byte, error) { return LoadKey(file, "", "") },
+		"env":     func() ([]byte, error) { return LoadKey("", "LAZY_TMUX_TEST_KEY", "") },
+		"command": func() ([]byte, error) { return LoadKey("", "", "echo "+testKeyHex) },
+	} {
+		key, err := load()
+		if err != nil || !bytes.Equal(key, want) {
+			t.Fatalf("%s: LoadKey = %x, %v", name, key, err)
+		}
+	}
+
+	if _, err := LoadKey("", "", "echo short"); err == nil {
+		t.Fatal("expected error for a key of the wrong size")
+	}
+
+	if _, err := LoadKey("", "LAZY_TMUX_MISSING_KEY", ""); err == nil {
+		t.Fatal("expected error for an empty variable")
+	}
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockFileName is flocked around every change to the data dir. The daemon,
+// CLI commands and the picker share one store, and the blob owners index and
+// session index are read, changed and rewritten as a whole, so s.mu alone
+// would let another process lose an update or remove a blob still in use.
+const lockFileName = ".lock"
+
+// lock takes the in-process mutex and the data dir lock, blocking until other
+// processes release it, and returns a function releasing both.
+func (s *Store) lock() (func(), error) {
+	s.mu.Lock()
+
+	if err := os.MkdirAll(s.baseDir, defaultDirPerm); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.baseDir, lockFileName), os.O_CREATE|os.O_RDWR, defaultFilePerm)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("open store lock: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		_ = file.Close()
+
+		s.mu.Unlock()
+
+		return nil, fmt.Errorf("lock store: %w", err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		_ = file.Close()
+
+		s.mu.Unlock()
+	}, nil
+}
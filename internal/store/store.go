@@ -1,9 +1,11 @@
 package store
 
 import (
+	"crypto/cipher"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -11,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alchemmist/lazy-tmux/internal/logging"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
 
@@ -26,11 +29,39 @@ const (
 
 type Store struct {
 	baseDir string
+	codec   string
+	logger  *slog.Logger
 	mu      sync.Mutex
+	enc     Encryption
+	aead    cipher.AEAD
+	nameKey []byte
+}
+
+type Options struct {
+	// Codec used for newly written scrollback files (see ParseCodec).
+	Codec      string
+	Logger     *slog.Logger
+	Encryption Encryption
 }
 
 func New(baseDir string) *Store {
-	return &Store{baseDir: baseDir}
+	return NewWithOptions(baseDir, Options{})
+}
+
+func NewWithOptions(baseDir string, opts Options) *Store {
+	codec := strings.ToLower(strings.TrimSpace(opts.Codec))
+	if codec == "" {
+		codec = DefaultCodec
+	}
+
+	return &Store{baseDir: baseDir, codec: codec, logger: logging.Or(opts.Logger), enc: opts.Encryption}
+}
+
+func (s *Store) SetLogger(logger *slog.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger = logging.Or(logger)
 }
 
 func DefaultDataDir() string {
@@ -55,35 +86,49 @@ func (s *Store) SaveSession(sessionSnapshot snapshot.SessionSnapshot) error {
 		sessionSnapshot.CapturedAt = time.Now().UTC()
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
+	return s.saveSessionUnlocked(sessionSnapshot)
+}
+
+func (s *Store) saveSessionUnlocked(sessionSnapshot snapshot.SessionSnapshot) error {
 	if err := s.ensureLayout(); err != nil {
 		return err
 	}
 
-	safeName, entries, err := s.planScrollbackUnlocked(&sessionSnapshot)
+	safeName, err := safeScrollbackSessionName(sessionSnapshot.SessionName)
 	if err != nil {
 		return err
 	}
 
 	path := s.sessionPath(sessionSnapshot.SessionName)
 
-	jsonTmp, err := writeJSONTemp(path, sessionSnapshot, defaultFilePerm)
+	written, err := s.storeScrollbackUnlocked(&sessionSnapshot)
 	if err != nil {
 		return err
 	}
 
-	defer func() { _ = os.Remove(jsonTmp) }()
+	s.logger.Debug(
+		"snapshot stored",
+		"session", sessionSnapshot.SessionName,
+		"windows", len(sessionSnapshot.Windows),
+		"blobs_written", written,
+	)
 
-	if err := s.persistScrollbackUnlocked(sessionSnapshot.SessionName, safeName, entries); err != nil {
+	if err := s.writeSnapshotUnlocked(path, sessionSnapshot); err != nil {
 		return err
 	}
 
-	if err := os.Rename(jsonTmp, path); err != nil {
-		return fmt.Errorf("rename tmp file: %w", err)
+	if err := s.removeLegacyScrollbackUnlocked(sessionSnapshot.SessionName, safeName); err != nil {
+		return err
 	}
 
+	s.setBlobOwnerUnlocked(s.blobOwner(path), blobRefs(sessionSnapshot))
+
 	idx, err := s.loadIndexUnlocked()
 	if err != nil {
 		return err
@@ -101,6 +146,7 @@ func (s *Store) SaveSession(sessionSnapshot snapshot.SessionSnapshot) error {
 		LastAccessed: idx.Sessions[sessionSnapshot.SessionName].LastAccessed,
 		Windows:      len(sessionSnapshot.Windows),
 		Panes:        panes,
+		SessionMeta:  idx.Sessions[sessionSnapshot.SessionName].SessionMeta,
 	}
 	idx.Updated = time.Now().UTC()
 
@@ -113,10 +159,18 @@ func (s *Store) DeleteSession(name string) error {
 		return errors.New("empty session name")
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
+	return s.deleteSessionUnlocked(name)
+}
+
+func (s *Store) deleteSessionUnlocked(name string) error {
 	path := s.sessionPath(name)
+
 	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("remove session file: %w", err)
 	}
@@ -126,16 +180,11 @@ func (s *Store) DeleteSession(name string) error {
 		return err
 	}
 
-	scrollRoot := filepath.Clean(filepath.Join(s.baseDir, scrollbackDir))
-	sessionDir := filepath.Clean(filepath.Join(scrollRoot, safeName))
-
-	if err := ensureUnderDir(scrollRoot, sessionDir, name); err != nil {
+	if err := s.removeLegacyScrollbackUnlocked(name, safeName); err != nil {
 		return err
 	}
 
-	if err := os.RemoveAll(sessionDir); err != nil {
-		return fmt.Errorf("remove scrollback dir: %w", err)
-	}
+	s.setBlobOwnerUnlocked(s.blobOwner(path), nil)
 
 	idx, err := s.loadIndexUnlocked()
 	if err != nil {
@@ -152,23 +201,19 @@ func (s *Store) DeleteSession(name string) error {
 }
 
 func (s *Store) LoadSession(name string) (snapshot.SessionSnapshot, error) {
-	var out snapshot.SessionSnapshot
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	path := s.sessionPath(name)
+	return s.loadSessionUnlocked(name)
+}
 
-	b, err := os.ReadFile(path)
+func (s *Store) loadSessionUnlocked(name string) (snapshot.SessionSnapshot, error) {
+	out, err := s.readSnapshotUnlocked(s.sessionPath(name))
 	if err != nil {
 		return out, fmt.Errorf("read session file: %w", err)
 	}
 
-	if err := json.Unmarshal(b, &out); err != nil {
-		return out, fmt.Errorf("unmarshal session: %w", err)
-	}
-
-	if err := s.hydrateScrollback(&out); err != nil {
+	if err := s.hydrateScrollback(&out, scrollbackDir); err != nil {
 		return out, err
 	}
 
@@ -257,8 +302,11 @@ func (s *Store) MarkSessionAccessed(name string, accessTime time.Time) error {
 		accessTime = time.Now().UTC()
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	idx, err := s.loadIndexUnlocked()
 	if err != nil {
@@ -277,13 +325,100 @@ func (s *Store) MarkSessionAccessed(name string, accessTime time.Time) error {
 	return writeJSONAtomic(s.indexPath(), idx)
 }
 
+func (s *Store) Record(name string) (snapshot.Record, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return snapshot.Record{}, errors.New("empty session name")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndexUnlocked()
+	if err != nil {
+		return snapshot.Record{}, err
+	}
+
+	rec, ok := idx.Sessions[name]
+	if !ok {
+		return snapshot.Record{}, fmt.Errorf("session %q not found: %w", name, os.ErrNotExist)
+	}
+
+	return rec, nil
+}
+
+func (s *Store) SetSessionMeta(name string, meta snapshot.SessionMeta) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("empty session name")
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	idx, err := s.loadIndexUnlocked()
+	if err != nil {
+		return err
+	}
+
+	rec, ok := idx.Sessions[name]
+	if !ok {
+		return fmt.Errorf("session %q not found: %w", name, os.ErrNotExist)
+	}
+
+	meta.Tags = NormalizeTags(meta.Tags)
+	meta.Note = strings.TrimSpace(meta.Note)
+	rec.SessionMeta = meta
+	idx.Sessions[name] = rec
+	idx.Updated = time.Now().UTC()
+
+	return writeJSONAtomic(s.indexPath(), idx)
+}
+
+// ParseTags splits a comma separated tag list, as typed on the command line
+// or in the picker, and normalizes it.
+func ParseTags(expr string) []string {
+	return NormalizeTags(strings.Split(expr, ","))
+}
+
+// NormalizeTags trims, deduplicates and sorts tags, dropping empty ones.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	out := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
 func (s *Store) ensureLayout() error {
 	if err := os.MkdirAll(filepath.Join(s.baseDir, sessionsDirName), defaultDirPerm); err != nil {
 		return fmt.Errorf("create sessions dir: %w", err)
 	}
 
-	if err := os.MkdirAll(filepath.Join(s.baseDir, scrollbackDir), scrollbackDirPerm); err != nil {
-		return fmt.Errorf("create scrollback dir: %w", err)
+	if err := os.MkdirAll(filepath.Join(s.baseDir, blobsDirName), scrollbackDirPerm); err != nil {
+		return fmt.Errorf("create blobs dir: %w", err)
 	}
 
 	return nil
@@ -340,61 +475,9 @@ func sanitizeName(name string) string {
 	return out
 }
 
-type scrollbackEntry struct {
-	FileName string
-	Content  string
-	Ref      string
-	Bytes    int
-	Lines    int
-}
-
-func (s *Store) planScrollbackUnlocked(
-	sessionSnapshot *snapshot.SessionSnapshot,
-) (string, []scrollbackEntry, error) {
-	safeName, err := safeScrollbackSessionName(sessionSnapshot.SessionName)
-	if err != nil {
-		return "", nil, err
-	}
-
-	entries := make([]scrollbackEntry, 0)
-
-	for windowIndex := range sessionSnapshot.Windows {
-		for pi := range sessionSnapshot.Windows[windowIndex].Panes {
-			pane := &sessionSnapshot.Windows[windowIndex].Panes[pi]
-			if pane.Scrollback == nil {
-				continue
-			}
-
-			content := pane.Scrollback.Content
-			if strings.TrimSpace(content) == "" {
-				pane.Scrollback = nil
-				continue
-			}
-
-			fileName := fmt.Sprintf("w%d_p%d.log", sessionSnapshot.Windows[windowIndex].Index, pane.Index)
-			ref := filepath.Join(scrollbackDir, safeName, fileName)
-			pane.Scrollback.Ref = ref
-			pane.Scrollback.Bytes = len(content)
-			pane.Scrollback.Lines = countLines(content)
-			pane.Scrollback.Content = ""
-
-			entries = append(entries, scrollbackEntry{
-				FileName: fileName,
-				Content:  content,
-				Ref:      ref,
-				Bytes:    len(content),
-				Lines:    countLines(content),
-			})
-		}
-	}
-
-	return safeName, entries, nil
-}
-
-func (s *Store) persistScrollbackUnlocked(
-	sessionName, safeName string,
-	entries []scrollbackEntry,
-) error {
+// removeLegacyScrollbackUnlocked drops the per-session scrollback dir used
+// before blobs; its files are no longer referenced once the session is re-saved.
+func (s *Store) removeLegacyScrollbackUnlocked(sessionName, safeName string) error {
 	scrollRoot := filepath.Clean(filepath.Join(s.baseDir, scrollbackDir))
 	sessionDir := filepath.Clean(filepath.Join(scrollRoot, safeName))
 
@@ -402,74 +485,16 @@ func (s *Store) persistScrollbackUnlocked(
 		return err
 	}
 
-	stageDir := sessionDir + ".tmp"
-	_ = os.RemoveAll(stageDir)
-
-	defer func() { _ = os.RemoveAll(stageDir) }()
-
-	if len(entries) == 0 {
-		_ = os.RemoveAll(sessionDir)
-		_ = os.RemoveAll(stageDir)
-
-		return nil
-	}
-
-	if err := os.MkdirAll(stageDir, scrollbackDirPerm); err != nil {
-		return fmt.Errorf("create stage dir: %w", err)
-	}
-
-	for _, ent := range entries {
-		path := filepath.Join(stageDir, ent.FileName)
-		if err := os.WriteFile(path, []byte(ent.Content), scrollbackFilePerm); err != nil {
-			return fmt.Errorf("write scrollback file: %w", err)
-		}
-	}
-
-	if err := promoteScrollbackStage(sessionDir, stageDir); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func promoteScrollbackStage(sessionDir, stageDir string) error {
-	backupDir := sessionDir + ".bak"
-	_ = os.RemoveAll(backupDir)
-
-	hadSessionDir := false
-	if _, err := os.Stat(sessionDir); err == nil {
-		hadSessionDir = true
-	} else if !errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("stat session dir: %w", err)
-	}
-
-	if hadSessionDir {
-		if err := os.Rename(sessionDir, backupDir); err != nil {
-			return fmt.Errorf("backup session dir: %w", err)
-		}
-	}
-
-	if err := os.Rename(stageDir, sessionDir); err != nil {
-		if hadSessionDir {
-			_ = os.Rename(backupDir, sessionDir)
-		}
-
-		return fmt.Errorf("promote stage dir: %w", err)
-	}
-
-	if hadSessionDir {
-		_ = os.RemoveAll(backupDir)
+	if err := os.RemoveAll(sessionDir); err != nil {
+		return fmt.Errorf("remove scrollback dir: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Store) hydrateScrollback(sessionSnapshot *snapshot.SessionSnapshot) error {
-	baseRoot, err := filepath.Abs(filepath.Clean(filepath.Join(s.baseDir, scrollbackDir)))
-	if err != nil {
-		return fmt.Errorf("get base root: %w", err)
-	}
-
+// hydrateScrollback loads pane scrollback content for refs that resolve under
+// the blobs dir or legacyRoot, a directory relative to the store base dir.
+func (s *Store) hydrateScrollback(sessionSnapshot *snapshot.SessionSnapshot, legacyRoot string) error {
 	for wi := range sessionSnapshot.Windows {
 		for pi := range sessionSnapshot.Windows[wi].Panes {
 			pane := &sessionSnapshot.Windows[wi].Panes[pi]
@@ -477,6 +502,16 @@ func (s *Store) hydrateScrollback(sessionSnapshot *snapshot.SessionSnapshot) err
 				continue
 			}
 
+			root := legacyRoot
+			if isBlobRef(pane.Scrollback.Ref) {
+				root = blobsDirName
+			}
+
+			baseRoot, err := filepath.Abs(filepath.Clean(filepath.Join(s.baseDir, root)))
+			if err != nil {
+				return fmt.Errorf("get base root: %w", err)
+			}
+
 			path, err := safeScrollbackPath(baseRoot, s.baseDir, pane.Scrollback.Ref)
 			if err != nil {
 				return err
@@ -485,19 +520,38 @@ func (s *Store) hydrateScrollback(sessionSnapshot *snapshot.SessionSnapshot) err
 			fileContent, err := os.ReadFile(path)
 			if err != nil {
 				if errors.Is(err, os.ErrNotExist) {
+					s.logger.Warn(
+						"scrollback file missing",
+						"session", sessionSnapshot.SessionName,
+						"window", sessionSnapshot.Windows[wi].Index,
+						"pane", pane.Index,
+						"ref", pane.Scrollback.Ref,
+					)
+
 					continue
 				}
 
 				return fmt.Errorf("read scrollback file: %w", err)
 			}
 
-			pane.Scrollback.Content = string(fileContent)
+			if pane.Scrollback.Encrypted || isEncrypted(fileContent) {
+				if fileContent, err = s.openUnlocked(fileContent); err != nil {
+					return fmt.Errorf("scrollback %s is encrypted: %w", pane.Scrollback.Ref, err)
+				}
+			}
+
+			content, err := decodeScrollback(pane.Scrollback.Codec, fileContent)
+			if err != nil {
+				return fmt.Errorf("decode scrollback %s: %w", pane.Scrollback.Ref, err)
+			}
+
+			pane.Scrollback.Content = content
 			if pane.Scrollback.Bytes == 0 {
-				pane.Scrollback.Bytes = len(fileContent)
+				pane.Scrollback.Bytes = len(content)
 			}
 
 			if pane.Scrollback.Lines == 0 {
-				pane.Scrollback.Lines = countLines(string(fileContent))
+				pane.Scrollback.Lines = countLines(content)
 			}
 		}
 	}
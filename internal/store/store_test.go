@@ -461,3 +461,203 @@ func TestDeleteSessionRemovesIndexEntry(t *testing.T) {
 		t.Fatalf("expected no records, got %d", len(recs))
 	}
 }
+
+func TestSessionMetaSurvivesResave(t *testing.T) {
+	s := New(t.TempDir())
+	snap := snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		Windows:     []snapshot.Window{{Index: 0}},
+	}
+
+	if err := s.SaveSession(snap); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := s.SetSessionMeta("demo", snapshot.SessionMeta{
+		Pinned: true,
+		Tags:   []string{" work", "client-x", "work", ""},
+		Note:   "  release prep ",
+	}); err != nil {
+		t.Fatalf("SetSessionMeta error: %v", err)
+	}
+
+	if err := s.SaveSession(snap); err != nil {
+		t.Fatalf("resave: %v", err)
+	}
+
+	rec, err := s.Record("demo")
+	if err != nil {
+		t.Fatalf("Record error: %v", err)
+	}
+
+	if !rec.Pinned || rec.Note != "release prep" {
+		t.Fatalf("unexpected meta after resave: %+v", rec.SessionMeta)
+	}
+
+	if strings.Join(rec.Tags, ",") != "client-x,work" {
+		t.Fatalf("expected normalized tags, got %v", rec.Tags)
+	}
+
+	if err := s.SetSessionMeta("missing", snapshot.SessionMeta{}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestSaveSessionCompressesScrollback(t *testing.T) {
+	base := t.TempDir()
+	store := New(base)
+	content := strings.Repeat("make test\nok\n", 200)
+
+	if err := store.SaveSession(scrollbackSnapshot("zip", content)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	raw := readRawSession(t, store, "zip")
+
+	ref := raw.Windows[0].Panes[0].Scrollback
+	if ref == nil || ref.Codec != CodecGzip || !strings.HasSuffix(ref.Ref, ".log.gz") {
+		t.Fatalf("expected gzip scrollback ref, got %+v", ref)
+	}
+
+	data, err := os.ReadFile(filepath.Join(base, ref.Ref))
+	if err != nil {
+		t.Fatalf("read scrollback file: %v", err)
+	}
+
+	if len(data) >= len(content) || strings.Contains(string(data), "make test") {
+		t.Fatalf("expected compressed scrollback file, got %d bytes", len(data))
+	}
+
+	loaded, err := store.LoadSession("zip")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if got := loaded.Windows[0].Panes[0].Scrollback.Content; got != content {
+		t.Fatalf("unexpected scrollback content: %q", got)
+	}
+}
+
+func TestSaveSessionWithoutCompression(t *testing.T) {
+	base := t.TempDir()
+	store := NewWithOptions(base, Options{Codec: CodecNone})
+
+	if err := store.SaveSession(scrollbackSnapshot("plain", "echo hi\nhi\n")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	raw := readRawSession(t, store, "plain")
+
+	ref := raw.Windows[0].Panes[0].Scrollback
+	if ref == nil || ref.Codec != "" || !strings.HasSuffix(ref.Ref, ".log") {
+		t.Fatalf("expected raw scrollback ref, got %+v", ref)
+	}
+
+	data, err := os.ReadFile(filepath.Join(base, ref.Ref))
+	if err != nil {
+		t.Fatalf("read scrollback file: %v", err)
+	}
+
+	if string(data) != "echo hi\nhi\n" {
+		t.Fatalf("unexpected raw scrollback file: %q", data)
+	}
+}
+
+func TestLoadSessionReadsLegacyUncompressedScrollback(t *testing.T) {
+	base := t.TempDir()
+	store := New(base)
+
+	legacyDir := filepath.Join(base, scrollbackDir, sanitizeName("old"))
+	if err := os.MkdirAll(legacyDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(legacyDir, "w0_p0.log"), []byte("legacy\n"), 0o600); err != nil {
+		t.Fatalf("write legacy scrollback: %v", err)
+	}
+
+	sessionSnapshot := scrollbackSnapshot("old", "")
+	sessionSnapshot.Windows[0].Panes[0].Scrollback = &snapshot.ScrollbackRef{
+		Ref:   filepath.Join(scrollbackDir, sanitizeName("old"), "w0_p0.log"),
+		Lines: 1,
+		Bytes: 7,
+	}
+
+	path, err := store.SessionPath("old")
+	if err != nil {
+		t.Fatalf("session path: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("mkdir sessions: %v", err)
+	}
+
+	if err := writeJSONAtomic(path, sessionSnapshot); err != nil {
+		t.Fatalf("write legacy session: %v", err)
+	}
+
+	loaded, err := store.LoadSession("old")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if got := loaded.Windows[0].Panes[0].Scrollback.Content; got != "legacy\n" {
+		t.Fatalf("unexpected legacy content: %q", got)
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	cases := map[string]string{"": DefaultCodec, "gz": CodecGzip, "GZIP": CodecGzip, "raw": CodecNone, "none": CodecNone}
+	for in, want := range cases {
+		got, err := ParseCodec(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseCodec(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseCodec("lz4"); err == nil {
+		t.Fatal("expected error for unsupported codec")
+	}
+}
+
+func scrollbackSnapshot(name, content string) snapshot.SessionSnapshot {
+	return snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: name,
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{
+			{
+				Index: 0,
+				Panes: []snapshot.Pane{
+					{
+						Index:      0,
+						CurrentCmd: "zsh",
+						Scrollback: &snapshot.ScrollbackRef{Content: content},
+					},
+				},
+			},
+		},
+	}
+}
+
+func readRawSession(t *testing.T, store *Store, name string) snapshot.SessionSnapshot {
+	t.Helper()
+
+	path, err := store.SessionPath(name)
+	if err != nil {
+		t.Fatalf("session path: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read raw session: %v", err)
+	}
+
+	var sessionSnapshot snapshot.SessionSnapshot
+	if err := json.Unmarshal(b, &sessionSnapshot); err != nil {
+		t.Fatalf("unmarshal raw session: %v", err)
+	}
+
+	return sessionSnapshot
+}
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+const (
+	trashDirName          = "trash"
+	trashEntryFileName    = "entry.json"
+	trashSnapshotFileName = "snapshot.json"
+)
+
+// TrashSession moves a stored session and its scrollback into the trash and
+// removes it from the index.
+func (s *Store) TrashSession(name string) (snapshot.TrashEntry, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return snapshot.TrashEntry{}, errors.New("empty session name")
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+	defer unlock()
+
+	sessionSnapshot, err := s.loadSessionUnlocked(name)
+	if err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	idx, err := s.loadIndexUnlocked()
+	if err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	entry := snapshot.TrashEntry{
+		Kind:        snapshot.TrashKindSession,
+		SessionName: name,
+		DeletedAt:   time.Now().UTC(),
+		Record:      idx.Sessions[name],
+	}
+
+	if err := s.writeTrashEntryUnlocked(&entry, sessionSnapshot); err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	if err := s.deleteSessionUnlocked(name); err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// TrashWindow stores a copy of one window of a hydrated snapshot in the trash.
+// The caller is responsible for removing the window from the session.
+func (s *Store) TrashWindow(
+	sessionSnapshot snapshot.SessionSnapshot,
+	windowIndex int,
+) (snapshot.TrashEntry, error) {
+	if sessionSnapshot.SessionName == "" {
+		return snapshot.TrashEntry{}, errors.New("empty session name")
+	}
+
+	var (
+		window snapshot.Window
+		found  bool
+	)
+
+	for _, w := range sessionSnapshot.Windows {
+		if w.Index == windowIndex {
+			window = w
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		return snapshot.TrashEntry{}, fmt.Errorf("window %d not found in snapshot", windowIndex)
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+	defer unlock()
+
+	idx, err := s.loadIndexUnlocked()
+	if err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	sessionSnapshot.Windows = []snapshot.Window{window}
+	sessionSnapshot.CurrentWin = window.Index
+	sessionSnapshot.CurrentPane = window.ActivePane
+
+	entry := snapshot.TrashEntry{
+		Kind:        snapshot.TrashKindWindow,
+		SessionName: sessionSnapshot.SessionName,
+		WindowIndex: window.Index,
+		WindowName:  window.Name,
+		DeletedAt:   time.Now().UTC(),
+		Record:      idx.Sessions[sessionSnapshot.SessionName],
+	}
+
+	if err := s.writeTrashEntryUnlocked(&entry, sessionSnapshot); err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (s *Store) ListTrash() ([]snapshot.TrashEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listTrashUnlocked()
+}
+
+func (s *Store) listTrashUnlocked() ([]snapshot.TrashEntry, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(s.baseDir, trashDirName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read trash dir: %w", err)
+	}
+
+	entries := make([]snapshot.TrashEntry, 0, len(dirEntries))
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		entry, err := s.readTrashEntryUnlocked(dirEntry.Name())
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].ID > entries[j].ID
+		}
+
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+
+	return entries, nil
+}
+
+// LoadTrash returns a trash entry together with its hydrated snapshot.
+func (s *Store) LoadTrash(id string) (snapshot.TrashEntry, snapshot.SessionSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.readTrashEntryUnlocked(id)
+	if err != nil {
+		return snapshot.TrashEntry{}, snapshot.SessionSnapshot{}, err
+	}
+
+	sessionSnapshot, err := s.readSnapshotUnlocked(filepath.Join(s.baseDir, trashDirName, entry.ID, trashSnapshotFileName))
+	if err != nil {
+		return snapshot.TrashEntry{}, snapshot.SessionSnapshot{}, fmt.Errorf("read trash snapshot: %w", err)
+	}
+
+	if err := s.hydrateScrollback(&sessionSnapshot, filepath.Join(trashDirName, entry.ID)); err != nil {
+		return snapshot.TrashEntry{}, snapshot.SessionSnapshot{}, err
+	}
+
+	return entry, sessionSnapshot, nil
+}
+
+func (s *Store) RemoveTrash(id string) error {
+	if err := validateTrashID(id); err != nil {
+		return err
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entryDir := filepath.Join(s.baseDir, trashDirName, id)
+
+	if err := os.RemoveAll(entryDir); err != nil {
+		return fmt.Errorf("remove trash entry: %w", err)
+	}
+
+	s.setBlobOwnerUnlocked(s.blobOwner(filepath.Join(entryDir, trashSnapshotFileName)), nil)
+
+	return nil
+}
+
+// EmptyTrash permanently removes trash entries deleted before the given time.
+// A zero time removes every entry.
+func (s *Store) EmptyTrash(before time.Time) (int, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := s.listTrashUnlocked()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, entry := range entries {
+		if !before.IsZero() && !entry.DeletedAt.Before(before) {
+			continue
+		}
+
+		entryDir := filepath.Join(s.baseDir, trashDirName, entry.ID)
+		if err := os.RemoveAll(entryDir); err != nil {
+			return removed, fmt.Errorf("remove trash entry: %w", err)
+		}
+
+		s.setBlobOwnerUnlocked(s.blobOwner(filepath.Join(entryDir, trashSnapshotFileName)), nil)
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (s *Store) readTrashEntryUnlocked(id string) (snapshot.TrashEntry, error) {
+	if err := validateTrashID(id); err != nil {
+		return snapshot.TrashEntry{}, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(s.baseDir, trashDirName, id, trashEntryFileName))
+	if err != nil {
+		return snapshot.TrashEntry{}, fmt.Errorf("read trash entry: %w", err)
+	}
+
+	var entry snapshot.TrashEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return snapshot.TrashEntry{}, fmt.Errorf("unmarshal trash entry: %w", err)
+	}
+
+	entry.ID = id
+
+	return entry, nil
+}
+
+func (s *Store) writeTrashEntryUnlocked(
+	entry *snapshot.TrashEntry,
+	sessionSnapshot snapshot.SessionSnapshot,
+) error {
+	entry.ID = trashID(*entry)
+	entryDir := filepath.Join(s.baseDir, trashDirName, entry.ID)
+
+	if err := os.MkdirAll(entryDir, scrollbackDirPerm); err != nil {
+		return fmt.Errorf("create trash entry dir: %w", err)
+	}
+
+	if err := s.writeTrashFiles(entryDir, *entry, sessionSnapshot); err != nil {
+		_ = os.RemoveAll(entryDir)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) writeTrashFiles(
+	entryDir string,
+	entry snapshot.TrashEntry,
+	sessionSnapshot snapshot.SessionSnapshot,
+) error {
+	if _, err := s.storeScrollbackUnlocked(&sessionSnapshot); err != nil {
+		return err
+	}
+
+	path := filepath.Join(entryDir, trashSnapshotFileName)
+	if err := s.writeSnapshotUnlocked(path, sessionSnapshot); err != nil {
+		return err
+	}
+
+	s.setBlobOwnerUnlocked(s.blobOwner(path), blobRefs(sessionSnapshot))
+
+	return writeJSONAtomic(filepath.Join(entryDir, trashEntryFileName), entry)
+}
+
+func trashID(entry snapshot.TrashEntry) string {
+	id := entry.DeletedAt.UTC().Format("20060102T150405.000000000Z") + "-" + sanitizeName(entry.SessionName)
+	if entry.Kind == snapshot.TrashKindWindow {
+		id += fmt.Sprintf("-w%d", entry.WindowIndex)
+	}
+
+	return id
+}
+
+func validateTrashID(id string) error {
+	if id == "" || id == "." || id == ".." || id != filepath.Base(id) || strings.Contains(id, "\\") {
+		return fmt.Errorf("invalid trash id: %q", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func TestTrashSessionMovesSnapshotAndScrollback(t *testing.T) {
+	s := New(t.TempDir())
+
+	if err := s.SaveSession(snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		CapturedAt:  time.Now().UTC(),
+		Windows: []snapshot.Window{{
+			Index: 0,
+			Panes: []snapshot.Pane{{Index: 0, Scrollback: &snapshot.ScrollbackRef{Content: "hello\n"}}},
+		}},
+	}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	entry, err := s.TrashSession("demo")
+	if err != nil {
+		t.Fatalf("TrashSession error: %v", err)
+	}
+
+	if entry.Kind != snapshot.TrashKindSession || entry.Record.SessionName != "demo" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	if _, err := s.LoadSession("demo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected session to be removed, got %v", err)
+	}
+
+	entries, err := s.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash error: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].ID != entry.ID {
+		t.Fatalf("unexpected trash entries: %+v", entries)
+	}
+
+	_, snap, err := s.LoadTrash(entry.ID)
+	if err != nil {
+		t.Fatalf("LoadTrash error: %v", err)
+	}
+
+	scrollback := snap.Windows[0].Panes[0].Scrollback
+	if scrollback == nil || scrollback.Content != "hello\n" {
+		t.Fatalf("expected trashed scrollback to be hydrated, got %+v", scrollback)
+	}
+}
+
+func TestTrashWindowKeepsOnlyThatWindow(t *testing.T) {
+	s := New(t.TempDir())
+	snap := snapshot.SessionSnapshot{
+		Version:     snapshot.FormatVersion,
+		SessionName: "demo",
+		Windows: []snapshot.Window{
+			{Index: 0, Name: "editor", Panes: []snapshot.Pane{{Index: 0}}},
+			{
+				Index: 2,
+				Name:  "logs",
+				Panes: []snapshot.Pane{{Index: 0, Scrollback: &snapshot.ScrollbackRef{Content: "tail\n"}}},
+			},
+		},
+	}
+
+	entry, err := s.TrashWindow(snap, 2)
+	if err != nil {
+		t.Fatalf("TrashWindow error: %v", err)
+	}
+
+	if entry.Kind != snapshot.TrashKindWindow || entry.WindowIndex != 2 || entry.WindowName != "logs" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	if snap.Windows[1].Panes[0].Scrollback.Content != "tail\n" {
+		t.Fatal("TrashWindow must not modify the caller snapshot")
+	}
+
+	_, trashed, err := s.LoadTrash(entry.ID)
+	if err != nil {
+		t.Fatalf("LoadTrash error: %v", err)
+	}
+
+	if len(trashed.Windows) != 1 || trashed.Windows[0].Name != "logs" {
+		t.Fatalf("unexpected trashed windows: %+v", trashed.Windows)
+	}
+
+	if _, err := s.TrashWindow(snap, 7); err == nil {
+		t.Fatal("expected error for missing window")
+	}
+}
+
+func TestEmptyTrashOlderThan(t *testing.T) {
+	s := New(t.TempDir())
+	snap := snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows:     []snapshot.Window{{Index: 0}, {Index: 1}},
+	}
+
+	old, err := s.TrashWindow(snap, 0)
+	if err != nil {
+		t.Fatalf("TrashWindow error: %v", err)
+	}
+
+	cutoff := time.Now().UTC()
+
+	if _, err := s.TrashWindow(snap, 1); err != nil {
+		t.Fatalf("TrashWindow error: %v", err)
+	}
+
+	removed, err := s.EmptyTrash(cutoff)
+	if err != nil {
+		t.Fatalf("EmptyTrash error: %v", err)
+	}
+
+	if removed != 1 {
+		t.Fatalf("expected 1 removed entry, got %d", removed)
+	}
+
+	entries, err := s.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash error: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].ID == old.ID {
+		t.Fatalf("unexpected remaining entries: %+v", entries)
+	}
+
+	if removed, err := s.EmptyTrash(time.Time{}); err != nil || removed != 1 {
+		t.Fatalf("expected remaining entry to be removed, got %d %v", removed, err)
+	}
+}
+
+func TestLoadTrashRejectsInvalidID(t *testing.T) {
+	s := New(t.TempDir())
+
+	for _, id := range []string{"", "..", "a/b"} {
+		_, _, err := s.LoadTrash(id)
+		if err == nil || !strings.Contains(err.Error(), "invalid trash id") {
+			t.Fatalf("expected invalid id error for %q, got %v", id, err)
+		}
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/alchemmist/lazy-tmux/internal/logging"
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
 
@@ -25,15 +28,54 @@ var (
 var paneTTYWriter = writePaneTTY
 
 type Client struct {
-	bin string
+	bin         string
+	server      Server
+	logger      *slog.Logger
+	options     OptionNames
+	environment []string
+	replay      string
 }
 
 func NewClient(bin string) *Client {
+	return NewClientForServer(bin, Server{})
+}
+
+// NewClientForServer returns a client that talks to the given tmux server.
+func NewClientForServer(bin string, server Server) *Client {
 	if strings.TrimSpace(bin) == "" {
 		bin = "tmux"
 	}
 
-	return &Client{bin: bin}
+	return &Client{bin: bin, server: server, logger: logging.Discard, options: DefaultOptionNames(),
+		environment: DefaultEnvironment(), replay: DefaultReplay,
+	}
+}
+
+// Server returns the tmux server the client talks to.
+func (c *Client) Server() Server {
+	return c.server
+}
+
+func (c *Client) command(args ...string) *exec.Cmd {
+	return exec.Command(c.bin, append(c.server.Args(), args...)...)
+}
+
+// SetLogger sets where tmux invocations are logged (at debug level).
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.logger = logging.Or(logger)
+}
+
+func (c *Client) logCommand(args []string, started time.Time, err error) {
+	if c.logger == nil {
+		return
+	}
+
+	attrs := []any{"args", strings.Join(args, " "), "duration", time.Since(started)}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+
+	c.logger.Debug("tmux command", attrs...)
 }
 
 func sessionTarget(name string) string {
@@ -62,11 +104,15 @@ func sessionWindowBaseTarget(name string) string {
 }
 
 func (c *Client) Run(args ...string) error {
-	cmd := exec.Command(c.bin, args...)
+	cmd := c.command(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
+	started := time.Now()
+	err := cmd.Run()
+	c.logCommand(args, started, err)
+
+	if err != nil {
 		return fmt.Errorf("run tmux: %w", err)
 	}
 
@@ -74,9 +120,21 @@ func (c *Client) Run(args ...string) error {
 }
 
 func (c *Client) Output(args ...string) (string, error) {
-	cmd := exec.Command(c.bin, args...)
+	return c.outputWithInput("", args...)
+}
 
+// outputWithInput runs a tmux command with input on its stdin, e.g. for
+// load-buffer -.
+func (c *Client) outputWithInput(input string, args ...string) (string, error) {
+	cmd := c.command(args...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+
+	started := time.Now()
 	out, err := cmd.CombinedOutput()
+	c.logCommand(args, started, err)
+
 	if err != nil {
 		return "", fmt.Errorf(
 			"tmux %s: %w (%s)",
@@ -90,10 +148,22 @@ func (c *Client) Output(args ...string) (string, error) {
 }
 
 func (c *Client) SessionExists(name string) bool {
-	err := exec.Command(c.bin, "has-session", "-t", sessionTarget(name)).Run()
+	args := []string{"has-session", "-t", sessionTarget(name)}
+	started := time.Now()
+	err := c.command(args...).Run()
+	c.logCommand(args, started, err)
+
 	return err == nil
 }
 
+// Probe asks the server for its sessions and returns the error when that
+// fails, e.g. because the server exited or was killed.
+func (c *Client) Probe() error {
+	_, err := c.Output("list-sessions", "-F", "#{session_name}")
+
+	return err
+}
+
 func (c *Client) ListSessions() ([]string, error) {
 	out, err := c.Output("list-sessions", "-F", "#{session_name}")
 	if err != nil {
@@ -110,6 +180,78 @@ func (c *Client) ListSessions() ([]string, error) {
 	return lines, nil
 }
 
+// SessionInfo is the live state of a running session.
+type SessionInfo struct {
+	Name string
+	// Activity is the time of the last input or output in the session.
+	Activity time.Time
+	// Attached is the number of clients attached to the session.
+	Attached int
+}
+
+func (c *Client) ListSessionInfo() ([]SessionInfo, error) {
+	out, err := c.Output(
+		"list-sessions",
+		"-F",
+		"#{session_name}"+fieldSep+"#{session_activity}"+fieldSep+"#{session_attached}",
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "no server running") {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	sessions := make([]SessionInfo, 0)
+
+	for _, line := range splitLines(out) {
+		parts := strings.Split(line, fieldSep)
+		if len(parts) != 3 {
+			continue
+		}
+
+		info := SessionInfo{Name: parts[0]}
+		if sec, err := strconv.ParseInt(parts[1], 10, 64); err == nil && sec > 0 {
+			info.Activity = time.Unix(sec, 0).UTC()
+		}
+
+		info.Attached, _ = strconv.Atoi(parts[2])
+		sessions = append(sessions, info)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+
+	return sessions, nil
+}
+
+// ListPanePIDs returns the pids of every pane's process, keyed by session.
+func (c *Client) ListPanePIDs() (map[string][]int, error) {
+	out, err := c.Output("list-panes", "-a", "-F", "#{session_name}"+fieldSep+"#{pane_pid}")
+	if err != nil {
+		if strings.Contains(err.Error(), "no server running") {
+			return map[string][]int{}, nil
+		}
+
+		return nil, err
+	}
+
+	pids := map[string][]int{}
+
+	for _, line := range splitLines(out) {
+		session, pidText, ok := strings.Cut(line, fieldSep)
+		if !ok {
+			continue
+		}
+
+		if pid, err := strconv.Atoi(strings.TrimSpace(pidText)); err == nil && pid > 0 {
+			pids[session] = append(pids[session], pid)
+		}
+	}
+
+	return pids, nil
+}
+
 func (c *Client) CurrentSession() (string, error) {
 	out, err := c.Output("display-message", "-p", "#S")
 	if err != nil {
@@ -143,6 +285,25 @@ func (c *Client) SwitchClient(session string) error {
 	return err
 }
 
+// DisplayMessage shows msg in the status line of every attached client.
+func (c *Client) DisplayMessage(msg string) error {
+	out, err := c.Output("list-clients", "-F", "#{client_name}")
+	if err != nil {
+		return err
+	}
+
+	// display-message expands formats; keep literal #s in msg.
+	msg = strings.ReplaceAll(msg, "#", "##")
+
+	for _, client := range splitLines(out) {
+		if _, err := c.Output("display-message", "-c", client, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *Client) KillWindow(session string, windowIndex int) error {
 	_, err := c.Output("kill-window", "-t", sessionWindowTarget(session, windowIndex))
 	return err
@@ -211,7 +372,8 @@ func (c *Client) CaptureSession(name string) (snapshot.SessionSnapshot, error) {
 		"-t",
 		sessionTarget(name),
 		"-F",
-		"#{window_index}"+fieldSep+"#{window_name}"+fieldSep+"#{window_layout}"+fieldSep+"#{window_active}",
+		"#{window_index}"+fieldSep+"#{window_name}"+fieldSep+"#{window_layout}"+fieldSep+"#{window_active}"+
+			fieldSep+"#{window_zoomed_flag}",
 	)
 	if err != nil {
 		return snapshot.SessionSnapshot{}, err
@@ -221,7 +383,7 @@ func (c *Client) CaptureSession(name string) (snapshot.SessionSnapshot, error) {
 
 	for _, line := range splitLines(wOut) {
 		parts := strings.Split(line, fieldSep)
-		if len(parts) != 4 {
+		if len(parts) < 4 || len(parts) > 5 {
 			continue
 		}
 
@@ -231,6 +393,8 @@ func (c *Client) CaptureSession(name string) (snapshot.SessionSnapshot, error) {
 			Name:     parts[1],
 			Layout:   parts[2],
 			IsActive: parts[3] == "1",
+			Zoomed:   len(parts) == 5 && parts[4] == "1",
+			Options:  c.captureOptions("-w", sessionWindowTarget(name, idx), c.options.Window),
 		}
 
 		pOut, err := c.Output("list-panes", "-t", sessionWindowTarget(name, idx), "-F",
@@ -239,15 +403,19 @@ func (c *Client) CaptureSession(name string) (snapshot.SessionSnapshot, error) {
 				"#{pane_current_command}"+fieldSep+
 				"#{pane_active}"+fieldSep+
 				"#{pane_pid}"+fieldSep+
-				"#{pane_tty}",
+				"#{pane_tty}"+fieldSep+
+				"#{pane_marked}"+fieldSep+
+				"#{pane_title}",
 		)
 		if err != nil {
 			return snapshot.SessionSnapshot{}, err
 		}
 
 		for _, pLine := range splitLines(pOut) {
-			parts := strings.Split(pLine, fieldSep)
-			if len(parts) != 6 {
+			// The title goes last since it may contain anything; missing
+			// trailing fields are left unset.
+			parts := strings.SplitN(pLine, fieldSep, 8)
+			if len(parts) < 6 {
 				continue
 			}
 
@@ -262,6 +430,14 @@ func (c *Client) CaptureSession(name string) (snapshot.SessionSnapshot, error) {
 				IsActive:    parts[3] == "1",
 				RestoreCmd:  strings.TrimSpace(restoreCmd),
 			}
+			if len(parts) > 6 {
+				pane.Marked = parts[6] == "1"
+			}
+
+			if len(parts) > 7 && !defaultPaneTitle(parts[7]) {
+				pane.Title = parts[7]
+			}
+
 			if pane.IsActive {
 				window.ActivePane = pane.Index
 			}
@@ -282,6 +458,8 @@ func (c *Client) CaptureSession(name string) (snapshot.SessionSnapshot, error) {
 		CurrentWin:  currentWin,
 		CurrentPane: currentPane,
 		Windows:     windows,
+		Options:     c.captureOptions("", sessionWindowBaseTarget(name), c.options.Session),
+		Environment: c.captureEnvironment(name),
 	}, nil
 }
 
@@ -303,10 +481,28 @@ func (c *Client) RestoreSession(sessionSnapshot snapshot.SessionSnapshot) error
 	sort.Slice(windows, func(i, j int) bool { return windows[i].Index < windows[j].Index })
 
 	first := windows[0]
-	if _, err := c.runWithShellFallback(newSessionArgs(sessionSnapshot.SessionName, first), ""); err != nil {
-		return err
+	// new-session -e puts the environment in place before the first shell
+	// starts; every later pane inherits it from the session.
+	args := newSessionArgs(sessionSnapshot.SessionName, first, sessionSnapshot.Environment)
+
+	_, buffer, err := c.newPane(args, firstPane(first))
+	if err != nil {
+		if len(sessionSnapshot.Environment) == 0 {
+			return err
+		}
+
+		// tmux before 3.2 has no -e; set the environment right after.
+		if _, buffer, err = c.newPane(stripOptionPair(args, "-e"), firstPane(first)); err != nil {
+			return err
+		}
+
+		c.setEnvironment(sessionSnapshot.SessionName, sessionSnapshot.Environment)
 	}
 
+	// Session options such as default-command also shape the panes created
+	// below, so they go first.
+	c.restoreOptions("", sessionWindowBaseTarget(sessionSnapshot.SessionName), sessionSnapshot.Options)
+
 	// tmux creates the first window at server default index (often 0 or 1).
 	// If snapshot index differs (e.g. sparse/non-renumbered windows), move it.
 	if createdIdx, err := c.createdFirstWindowIndex(
@@ -323,7 +519,7 @@ func (c *Client) RestoreSession(sessionSnapshot snapshot.SessionSnapshot) error
 		}
 	}
 
-	if err := c.populateWindow(sessionSnapshot.SessionName, first, first.Index); err != nil {
+	if err := c.populateWindow(sessionSnapshot.SessionName, first, first.Index, buffer); err != nil {
 		return err
 	}
 
@@ -335,22 +531,88 @@ func (c *Client) RestoreSession(sessionSnapshot snapshot.SessionSnapshot) error
 	}
 
 	_, _ = c.Output("select-window", "-t", sessionWindowTarget(sessionSnapshot.SessionName, sessionSnapshot.CurrentWin))
-	_, _ = c.Output(
-		"select-pane",
-		"-t",
-		sessionPaneTarget(sessionSnapshot.SessionName, sessionSnapshot.CurrentWin, sessionSnapshot.CurrentPane),
-	)
+
+	// Selecting a pane unzooms its window; zooming already selected it.
+	if !windowZoomed(windows, sessionSnapshot.CurrentWin) {
+		_, _ = c.Output(
+			"select-pane",
+			"-t",
+			sessionPaneTarget(sessionSnapshot.SessionName, sessionSnapshot.CurrentWin, sessionSnapshot.CurrentPane),
+		)
+	}
 
 	return nil
 }
 
-func newSessionArgs(sessionName string, w snapshot.Window) []string {
+func windowZoomed(windows []snapshot.Window, index int) bool {
+	for _, w := range windows {
+		if w.Index == index {
+			return w.Zoomed && len(w.Panes) > 1
+		}
+	}
+
+	return false
+}
+
+// RestoreWindow recreates one snapshot window inside a running session. If the
+// window index is taken, the window is appended after the last one instead.
+func (c *Client) RestoreWindow(sessionName string, window snapshot.Window) error {
+	if !c.SessionExists(sessionName) {
+		return ErrSessionNotFound
+	}
+
+	indexes, err := c.windowIndexes(sessionName)
+	if err != nil {
+		return err
+	}
+
+	maxIdx := -1
+	taken := false
+
+	for _, idx := range indexes {
+		if idx == window.Index {
+			taken = true
+		}
+
+		if idx > maxIdx {
+			maxIdx = idx
+		}
+	}
+
+	if taken {
+		window.Index = maxIdx + 1
+	}
+
+	return c.createAndPopulateWindow(sessionName, window)
+}
+
+func (c *Client) windowIndexes(session string) ([]int, error) {
+	out, err := c.Output("list-windows", "-t", sessionTarget(session), "-F", "#{window_index}")
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0)
+
+	for _, line := range splitLines(out) {
+		idx, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("parse window index: %w", err)
+		}
+
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, nil
+}
+
+func newSessionArgs(sessionName string, w snapshot.Window, env map[string]string) []string {
 	args := []string{"new-session", "-d", "-s", sessionName, "-n", w.Name}
 	if path := firstPanePath(w); path != "" {
 		args = append(args, "-c", path)
 	}
 
-	return args
+	return append(args, environmentArgs(env)...)
 }
 
 func newWindowArgs(sessionName string, win snapshot.Window) []string {
@@ -370,19 +632,27 @@ func newWindowArgs(sessionName string, win snapshot.Window) []string {
 }
 
 func (c *Client) createAndPopulateWindow(sessionName string, w snapshot.Window) error {
-	if _, err := c.runWithShellFallback(newWindowArgs(sessionName, w), ""); err != nil {
+	_, buffer, err := c.newPane(newWindowArgs(sessionName, w), firstPane(w))
+	if err != nil {
 		return err
 	}
 
-	return c.populateWindow(sessionName, w, w.Index)
+	return c.populateWindow(sessionName, w, w.Index, buffer)
 }
 
-func (c *Client) populateWindow(sessionName string, window snapshot.Window, windowIndex int) error {
-	if err := c.ensurePaneCount(sessionName, window, windowIndex); err != nil {
+// populateWindow fills a window whose first pane exists; firstBuffer names
+// the buffer that pane replays its scrollback from, if any.
+func (c *Client) populateWindow(sessionName string, window snapshot.Window, windowIndex int, firstBuffer string) error {
+	replayed := map[int]string{}
+	if firstBuffer != "" {
+		replayed[firstPane(window).Index] = firstBuffer
+	}
+
+	if err := c.ensurePaneCount(sessionName, window, windowIndex, replayed); err != nil {
 		return err
 	}
 
-	c.restoreWindowScrollback(sessionName, window, windowIndex)
+	c.restoreWindowScrollback(sessionName, window, windowIndex, replayed)
 
 	if err := c.restoreWindowCommands(sessionName, window, windowIndex); err != nil {
 		return err
@@ -397,6 +667,8 @@ func (c *Client) populateWindow(sessionName string, window snapshot.Window, wind
 		)
 	}
 
+	c.restoreWindowState(sessionName, window, windowIndex)
+
 	return nil
 }
 
@@ -408,12 +680,27 @@ func (c *Client) CapturePaneScrollback(target string, lines int) (string, error)
 	return c.Output("capture-pane", "-p", "-e", "-S", fmt.Sprintf("-%d", lines), "-t", target)
 }
 
-func (c *Client) ensurePaneCount(sessionName string, window snapshot.Window, windowIndex int) error {
+// ensurePaneCount creates the window's missing panes and records in replayed
+// the buffers of the ones that replay their scrollback themselves.
+func (c *Client) ensurePaneCount(sessionName string, window snapshot.Window, windowIndex int, replayed map[int]string) error {
 	if len(window.Panes) <= 1 {
 		return nil
 	}
 
-	for i := 1; i < len(window.Panes); i++ {
+	created := 1
+
+	if root, err := parseLayout(window.Layout); err == nil {
+		err = c.restoreLayoutTree(sessionName, root, window.Panes, windowIndex, replayed)
+		if err == nil {
+			return nil
+		}
+
+		c.logger.Debug("layout tree restore failed, splitting plainly", "window", windowIndex, "error", err)
+
+		created = c.paneCount(sessionName, windowIndex)
+	}
+
+	for i := max(created, 1); i < len(window.Panes); i++ {
 		pane := window.Panes[i]
 		args := []string{"split-window", "-d", "-t", sessionWindowTarget(sessionName, windowIndex)}
 
@@ -421,14 +708,57 @@ func (c *Client) ensurePaneCount(sessionName string, window snapshot.Window, win
 			args = append(args, "-c", pane.CurrentPath)
 		}
 
-		if _, err := c.runWithShellFallback(args, ""); err != nil {
+		_, buffer, err := c.newPane(args, pane)
+		if err != nil {
 			return err
 		}
+
+		if buffer != "" {
+			replayed[pane.Index] = buffer
+		}
 	}
 
 	return nil
 }
 
+func (c *Client) restoreLayoutTree(
+	sessionName string,
+	root *layoutCell,
+	panes []snapshot.Pane,
+	windowIndex int,
+	replayed map[int]string,
+) error {
+	out, err := c.Output("display-message", "-p", "-t", sessionWindowTarget(sessionName, windowIndex), "#{pane_id}")
+	if err != nil {
+		return err
+	}
+
+	id := strings.TrimSpace(out)
+	if !strings.HasPrefix(id, "%") {
+		return fmt.Errorf("unexpected pane id %q", id)
+	}
+
+	return c.buildLayout(root, panes, id, replayed)
+}
+
+func (c *Client) paneCount(sessionName string, windowIndex int) int {
+	out, err := c.Output("list-panes", "-t", sessionWindowTarget(sessionName, windowIndex), "-F", "#{pane_id}")
+	if err != nil {
+		return 1
+	}
+
+	return len(splitLines(out))
+}
+
+// firstPane returns the pane a new window or session starts with.
+func firstPane(w snapshot.Window) snapshot.Pane {
+	if len(w.Panes) == 0 {
+		return snapshot.Pane{}
+	}
+
+	return w.Panes[0]
+}
+
 func firstPanePath(w snapshot.Window) string {
 	if len(w.Panes) == 0 {
 		return ""
@@ -516,6 +846,14 @@ func (c *Client) restoreWindowCommands(
 			continue
 		}
 
+		// A masked secret would run as "[REDACTED:...]"; keep the shell.
+		if pane.CommandRedacted {
+			c.logger.Debug("redacted restore command skipped", "session", sessionName, "window", windowIndex,
+				"pane", pane.Index)
+
+			continue
+		}
+
 		target := sessionPaneTarget(sessionName, windowIndex, pane.Index)
 		if _, err := c.Output("send-keys", "-t", target, cmd, "C-m"); err != nil {
 			return err
@@ -525,17 +863,21 @@ func (c *Client) restoreWindowCommands(
 	return nil
 }
 
-func (c *Client) restoreWindowScrollback(sessionName string, window snapshot.Window, windowIndex int) {
-	if len(window.Panes) == 0 {
+// restoreWindowScrollback writes saved scrollback to the ttys of the panes
+// that do not replay it themselves, or did not pick up their replay buffer.
+func (c *Client) restoreWindowScrollback(sessionName string, window snapshot.Window, windowIndex int, replayed map[int]string) {
+	if len(window.Panes) == 0 || c.replay == ReplayNone {
 		return
 	}
 
+	unclaimed := c.unclaimedReplays(replayed)
+
 	panes := make([]snapshot.Pane, len(window.Panes))
 	copy(panes, window.Panes)
 	sort.Slice(panes, func(i, j int) bool { return panes[i].Index < panes[j].Index })
 
 	for _, pane := range panes {
-		if pane.Scrollback == nil || strings.TrimSpace(pane.Scrollback.Content) == "" {
+		if !hasScrollback(pane) || (replayed[pane.Index] != "" && !unclaimed[pane.Index]) {
 			continue
 		}
 
@@ -1,10 +1,12 @@
 package tmux
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alchemmist/lazy-tmux/internal/snapshot"
 )
@@ -138,6 +140,48 @@ exit 0
 	}
 }
 
+func TestListSessionInfoParsesActivityAndClients(t *testing.T) {
+	fake := writeFakeTmux(t, `
+if [ "$1" = "list-sessions" ]; then
+  printf "work\0371700000000\0371\nidle\0371600000000\0370\nbad-line\n"
+  exit 0
+fi
+exit 0
+`)
+
+	got, err := NewClient(fake).ListSessionInfo()
+	if err != nil {
+		t.Fatalf("ListSessionInfo error: %v", err)
+	}
+
+	want := []SessionInfo{
+		{Name: "idle", Activity: time.Unix(1600000000, 0).UTC()},
+		{Name: "work", Activity: time.Unix(1700000000, 0).UTC(), Attached: 1},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unexpected sessions: %#v", got)
+	}
+}
+
+func TestListPanePIDsGroupsBySession(t *testing.T) {
+	fake := writeFakeTmux(t, `
+if [ "$1" = "list-panes" ] && [ "$2" = "-a" ]; then
+  printf "work\037101\nwork\037102\nidle\037201\nidle\037\n"
+  exit 0
+fi
+exit 1
+`)
+
+	got, err := NewClient(fake).ListPanePIDs()
+	if err != nil {
+		t.Fatalf("ListPanePIDs error: %v", err)
+	}
+
+	if fmt.Sprint(got) != "map[idle:[201] work:[101 102]]" {
+		t.Fatalf("unexpected pane pids: %v", got)
+	}
+}
+
 func TestRestoreSessionBuildsExpectedTmuxCommands(t *testing.T) {
 	logPath := filepath.Join(t.TempDir(), "tmux.log")
 	fake := writeFakeTmux(t, `
@@ -356,6 +400,48 @@ exit 0
 	}
 }
 
+func TestRestoreSessionSkipsRedactedCommands(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+if [ "$1" = "list-windows" ]; then
+  echo "0"
+  exit 0
+fi
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	err := NewClient(fake).RestoreSession(snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows: []snapshot.Window{{
+			Index: 0,
+			Name:  "db",
+			Panes: []snapshot.Pane{
+				{Index: 0, CurrentPath: "/tmp", CurrentCmd: "mysql", RestoreCmd: "mysql -p[REDACTED:password] db", CommandRedacted: true},
+				{Index: 1, CurrentPath: "/tmp", CurrentCmd: "htop", RestoreCmd: "htop"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	out := string(b)
+	if strings.Contains(out, "REDACTED") || !strings.Contains(out, "send-keys -t =demo:0.1 htop C-m") {
+		t.Fatalf("expected only the unredacted command to run, got:\n%s", out)
+	}
+}
+
 func TestRestoreSessionFallsBackWithoutPathWhenPathFails(t *testing.T) {
 	logPath := filepath.Join(t.TempDir(), "tmux.log")
 	fake := writeFakeTmux(t, `
@@ -507,3 +593,67 @@ exit 0
 		t.Fatalf("expected numeric target to be escaped, got:\n%s", string(fileContent))
 	}
 }
+
+func TestDisplayMessageShowsOnEveryClient(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "list-clients" ]; then
+  printf "/dev/pts/1\n/dev/pts/2\n"
+fi
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	c := NewClient(fake)
+	if err := c.DisplayMessage("lazy-tmux: disk #1 full"); err != nil {
+		t.Fatalf("DisplayMessage error: %v", err)
+	}
+
+	fileContent, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	for _, want := range []string{
+		"display-message -c /dev/pts/1 lazy-tmux: disk ##1 full",
+		"display-message -c /dev/pts/2 lazy-tmux: disk ##1 full",
+	} {
+		if !strings.Contains(string(fileContent), want) {
+			t.Fatalf("expected %q, got:\n%s", want, string(fileContent))
+		}
+	}
+}
+
+func TestRestoreWindowAppendsWhenIndexTaken(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "list-windows" ]; then
+  printf "0\n1\n"
+  exit 0
+fi
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	c := NewClient(fake)
+	if err := c.RestoreWindow("demo", snapshot.Window{
+		Index: 1,
+		Name:  "logs",
+		Panes: []snapshot.Pane{{Index: 0, CurrentPath: "/tmp"}},
+	}); err != nil {
+		t.Fatalf("RestoreWindow error: %v", err)
+	}
+
+	fileContent, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	if !strings.Contains(string(fileContent), "new-window -d -t =demo:2 -n logs -c /tmp") {
+		t.Fatalf("expected window to be appended at index 2, got:\n%s", string(fileContent))
+	}
+}
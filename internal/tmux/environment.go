@@ -0,0 +1,87 @@
+package tmux
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultEnvironment is the allowlist of session environment variables
+// captured unless configured otherwise. Variables that commonly hold secrets
+// are left out on purpose.
+func DefaultEnvironment() []string {
+	return []string{"PATH", "KUBECONFIG", "DOCKER_HOST", "DOCKER_CONTEXT", "AWS_PROFILE", "VIRTUAL_ENV"}
+}
+
+// SetEnvironment sets the name globs of session environment variables
+// CaptureSession records.
+func (c *Client) SetEnvironment(allow []string) {
+	c.environment = allow
+}
+
+// captureEnvironment returns the allowed variables set in the session
+// environment. Variables marked for removal ("-NAME") are skipped.
+func (c *Client) captureEnvironment(session string) map[string]string {
+	if len(c.environment) == 0 {
+		return nil
+	}
+
+	out, err := c.Output("show-environment", "-t", sessionWindowBaseTarget(session))
+	if err != nil {
+		return nil
+	}
+
+	var env map[string]string
+
+	for _, line := range strings.Split(out, "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || name == "" || !envAllowed(c.environment, name) {
+			continue
+		}
+
+		if env == nil {
+			env = map[string]string{}
+		}
+
+		env[name] = value
+	}
+
+	return env
+}
+
+func envAllowed(allow []string, name string) bool {
+	for _, pattern := range allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// environmentArgs returns "-e NAME=value" pairs in name order.
+func environmentArgs(env map[string]string) []string {
+	args := make([]string, 0, 2*len(env))
+	for _, name := range sortedKeys(env) {
+		args = append(args, "-e", name+"="+env[name])
+	}
+
+	return args
+}
+
+func (c *Client) setEnvironment(session string, env map[string]string) {
+	for _, name := range sortedKeys(env) {
+		_, _ = c.Output("set-environment", "-t", sessionWindowBaseTarget(session), name, env[name])
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,333 @@
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// layoutCell is one node of a tmux layout string such as
+// "b25d,80x24,0,0{40x24,0,0,1,39x24,41,0[39x12,41,0,2,39x11,41,13,3]}".
+// Containers have children laid out side by side (horizontal, "{}") or
+// stacked ("[]"); leaves are panes.
+type layoutCell struct {
+	width, height int
+	horizontal    bool
+	children      []*layoutCell
+}
+
+// parseLayout parses a custom tmux layout string. Named layouts such as
+// "tiled" are not custom layouts and fail to parse.
+func parseLayout(layout string) (*layoutCell, error) {
+	checksum, body, ok := strings.Cut(layout, ",")
+	if !ok || len(checksum) != 4 {
+		return nil, fmt.Errorf("invalid layout %q", layout)
+	}
+
+	if _, err := strconv.ParseUint(checksum, 16, 16); err != nil {
+		return nil, fmt.Errorf("invalid layout checksum %q", checksum)
+	}
+
+	p := layoutParser{in: body}
+
+	cell, err := p.cell()
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", layout, err)
+	}
+
+	if p.pos != len(p.in) {
+		return nil, fmt.Errorf("invalid layout %q: trailing %q", layout, p.in[p.pos:])
+	}
+
+	return cell, nil
+}
+
+// namedLayouts are the preset layouts select-layout accepts by name.
+var namedLayouts = map[string]bool{
+	"even-horizontal":          true,
+	"even-vertical":            true,
+	"main-horizontal":          true,
+	"main-horizontal-mirrored": true,
+	"main-vertical":            true,
+	"main-vertical-mirrored":   true,
+	"tiled":                    true,
+}
+
+// ValidateLayout checks that layout is empty, a named layout, or a custom
+// layout with a correct checksum and one cell per pane. tmux refuses custom
+// layouts whose checksum does not match, so hand-edited layouts need a new
+// one.
+func ValidateLayout(layout string, panes int) error {
+	if layout == "" || namedLayouts[layout] {
+		return nil
+	}
+
+	root, err := parseLayout(layout)
+	if err != nil {
+		return err
+	}
+
+	checksum, body, _ := strings.Cut(layout, ",")
+	if want := layoutChecksum(body); !strings.EqualFold(checksum, want) {
+		return fmt.Errorf("layout checksum is %s, want %s", checksum, want)
+	}
+
+	if cells := len(root.leaves()); cells != panes {
+		return fmt.Errorf("layout has %d panes, window has %d", cells, panes)
+	}
+
+	return nil
+}
+
+// layoutChecksum computes the checksum tmux puts in front of a layout.
+func layoutChecksum(body string) string {
+	var csum uint16
+	for i := 0; i < len(body); i++ {
+		csum = (csum >> 1) + ((csum & 1) << 15)
+		csum += uint16(body[i])
+	}
+
+	return fmt.Sprintf("%04x", csum)
+}
+
+type layoutParser struct {
+	in  string
+	pos int
+}
+
+// cell parses "WxH,X,Y" followed by ",ID", "{cells}" or "[cells]".
+func (p *layoutParser) cell() (*layoutCell, error) {
+	width, err := p.number('x')
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := p.number(',')
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.number(','); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.number(0); err != nil {
+		return nil, err
+	}
+
+	cell := &layoutCell{width: width, height: height}
+
+	if p.pos == len(p.in) {
+		return nil, errors.New("missing pane id")
+	}
+
+	switch p.in[p.pos] {
+	case ',':
+		p.pos++
+		if _, err := p.number(0); err != nil {
+			return nil, err
+		}
+
+		return cell, nil
+	case '{', '[':
+		cell.horizontal = p.in[p.pos] == '{'
+		closing := byte(']')
+		if cell.horizontal {
+			closing = '}'
+		}
+
+		p.pos++
+
+		for {
+			child, err := p.cell()
+			if err != nil {
+				return nil, err
+			}
+
+			cell.children = append(cell.children, child)
+
+			if p.pos == len(p.in) {
+				return nil, errors.New("unterminated cell list")
+			}
+
+			if p.in[p.pos] == closing {
+				p.pos++
+				return cell, nil
+			}
+
+			if p.in[p.pos] != ',' {
+				return nil, fmt.Errorf("unexpected %q at %d", p.in[p.pos], p.pos)
+			}
+
+			p.pos++
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %q at %d", p.in[p.pos], p.pos)
+	}
+}
+
+// number reads digits, then skips sep unless it is 0.
+func (p *layoutParser) number(sep byte) (int, error) {
+	start := p.pos
+	for p.pos < len(p.in) && p.in[p.pos] >= '0' && p.in[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return 0, fmt.Errorf("expected number at %d", start)
+	}
+
+	n, err := strconv.Atoi(p.in[start:p.pos])
+	if err != nil {
+		return 0, err
+	}
+
+	if sep != 0 {
+		if p.pos == len(p.in) || p.in[p.pos] != sep {
+			return 0, fmt.Errorf("expected %q at %d", sep, p.pos)
+		}
+
+		p.pos++
+	}
+
+	return n, nil
+}
+
+// leaves returns the panes of the layout in tmux pane order.
+func (l *layoutCell) leaves() []*layoutCell {
+	if len(l.children) == 0 {
+		return []*layoutCell{l}
+	}
+
+	var out []*layoutCell
+	for _, child := range l.children {
+		out = append(out, child.leaves()...)
+	}
+
+	return out
+}
+
+// size returns the cell's extent along its parent's split axis.
+func (l *layoutCell) size(horizontal bool) int {
+	if horizontal {
+		return l.width
+	}
+
+	return l.height
+}
+
+// splitPercent returns how much of the area of children[from:] the new pane
+// for children[from+1:] takes. Cells are separated by one cell wide borders;
+// the border of the split comes out of the pane that is split.
+func splitPercent(parent *layoutCell, from int) int {
+	total, rest := 0, 0
+
+	for i, child := range parent.children[from:] {
+		total += child.size(parent.horizontal)
+		if i > 0 {
+			total++
+			rest += child.size(parent.horizontal)
+		}
+
+		if i > 1 {
+			rest++
+		}
+	}
+
+	if total == 0 {
+		return 50
+	}
+
+	return min(max((rest*100+total/2)/total, 1), 99)
+}
+
+// buildLayout recreates the pane tree of layout in a window with a single
+// pane, in tmux pane order, so that snapshot panes sorted by index land in
+// their original cells. Splits are sized in percent, which keeps the
+// proportions when the window size differs from the captured one.
+func (c *Client) buildLayout(root *layoutCell, panes []snapshot.Pane, firstPaneID string, replayed map[int]string) error {
+	leaves := root.leaves()
+	if len(leaves) != len(panes) {
+		return fmt.Errorf("layout has %d panes, snapshot %d", len(leaves), len(panes))
+	}
+
+	sorted := make([]snapshot.Pane, len(panes))
+	copy(sorted, panes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	leafPanes := make(map[*layoutCell]snapshot.Pane, len(leaves))
+	for i, leaf := range leaves {
+		leafPanes[leaf] = sorted[i]
+	}
+
+	return c.splitCell(root, firstPaneID, leafPanes, replayed)
+}
+
+// splitCell splits paneID, which covers cell, into the cell's children.
+func (c *Client) splitCell(
+	cell *layoutCell,
+	paneID string,
+	panes map[*layoutCell]snapshot.Pane,
+	replayed map[int]string,
+) error {
+	ids := []string{paneID}
+
+	// Each split carves the children after i out of the pane holding
+	// children[i:], leaving children[i] in place.
+	for i := 0; i < len(cell.children)-1; i++ {
+		next := cell.children[i+1]
+
+		pane := panes[next.leaves()[0]]
+
+		id, buffer, err := c.splitPane(ids[i], cell.horizontal, splitPercent(cell, i), pane)
+		if err != nil {
+			return err
+		}
+
+		if buffer != "" {
+			replayed[pane.Index] = buffer
+		}
+		ids = append(ids, id)
+	}
+
+	for i, child := range cell.children {
+		if err := c.splitCell(child, ids[i], panes, replayed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitPane splits target into a new pane for pane and returns its id and
+// the buffer it replays its scrollback from, if any. A size that does not fit
+// is dropped before giving up.
+func (c *Client) splitPane(target string, horizontal bool, percent int, pane snapshot.Pane) (string, string, error) {
+	dir := "-v"
+	if horizontal {
+		dir = "-h"
+	}
+
+	args := []string{"split-window", "-d", "-P", "-F", "#{pane_id}", dir, "-t", target, "-l", fmt.Sprintf("%d%%", percent)}
+	if pane.CurrentPath != "" {
+		args = append(args, "-c", pane.CurrentPath)
+	}
+
+	out, buffer, err := c.newPane(args, pane)
+	if err != nil {
+		if out, buffer, err = c.newPane(stripOptionPair(args, "-l"), pane); err != nil {
+			return "", "", err
+		}
+	}
+
+	id := strings.TrimSpace(out)
+	if !strings.HasPrefix(id, "%") {
+		return "", "", fmt.Errorf("split-window returned no pane id: %q", id)
+	}
+
+	return id, buffer, nil
+}
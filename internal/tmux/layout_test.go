@@ -0,0 +1,168 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+const nestedLayout = "f600,200x50,0,0{139x50,0,0[139x25,0,0,0,139x24,0,26,2],60x50,140,0[60x39,140,0,1,60x10,140,40,3]}"
+
+func TestParseLayoutBuildsTree(t *testing.T) {
+	root, err := parseLayout(nestedLayout)
+	if err != nil {
+		t.Fatalf("parseLayout error: %v", err)
+	}
+
+	if !root.horizontal || len(root.children) != 2 || root.children[0].horizontal || len(root.leaves()) != 4 {
+		t.Fatalf("unexpected tree: %+v", root)
+	}
+
+	if got := splitPercent(root, 0); got != 30 {
+		t.Fatalf("expected right column to take 30%%, got %d", got)
+	}
+
+	if got := splitPercent(root.children[1], 0); got != 20 {
+		t.Fatalf("expected bottom pane to take 20%%, got %d", got)
+	}
+
+	single, err := parseLayout("c2c3,80x24,0,0,5")
+	if err != nil || len(single.leaves()) != 1 {
+		t.Fatalf("unexpected single pane layout: %+v, %v", single, err)
+	}
+
+	for _, bad := range []string{"tiled", "even-horizontal", "zzzz,80x24,0,0,1", "c2c3,80x24,0,0{40x24,0,0,1", "c2c3,80x24,0,0,1x"} {
+		if _, err := parseLayout(bad); err == nil {
+			t.Fatalf("expected parse error for %q", bad)
+		}
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+	// Captured from tmux 3.x after two splits.
+	captured := "d67e,80x24,0,0{40x24,0,0,0,39x24,41,0[39x12,41,0,1,39x11,41,13,2]}"
+
+	for _, tc := range []struct {
+		layout string
+		panes  int
+		want   string
+	}{
+		{layout: "", panes: 2},
+		{layout: "tiled", panes: 4},
+		{layout: captured, panes: 3},
+		{layout: captured, panes: 2, want: "layout has 3 panes, window has 2"},
+		{layout: "d67e,80x24,0,0{50x24,0,0,0,29x24,51,0[29x12,51,0,1,29x11,51,13,2]}", panes: 3, want: "checksum is d67e"},
+		{layout: "sideways", panes: 1, want: "invalid layout"},
+	} {
+		err := ValidateLayout(tc.layout, tc.panes)
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Fatalf("ValidateLayout(%q, %d) = %v, want %q", tc.layout, tc.panes, err, tc.want)
+		}
+	}
+}
+
+func TestRestoreSessionRebuildsNestedLayout(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+case "$1" in
+has-session) exit 1 ;;
+list-windows) echo "0"; exit 0 ;;
+display-message) echo "%0"; exit 0 ;;
+split-window)
+  n=$(cat "$TMUX_LOG.ids" 2>/dev/null || echo 0)
+  n=$((n + 1))
+  echo "$n" > "$TMUX_LOG.ids"
+  echo "%$n"
+  exit 0 ;;
+esac
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	err := NewClient(fake).RestoreSession(snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows: []snapshot.Window{{
+			Index:  0,
+			Name:   "work",
+			Layout: nestedLayout,
+			Panes: []snapshot.Pane{
+				{Index: 3, CurrentPath: "/d"},
+				{Index: 0, CurrentPath: "/a"},
+				{Index: 2, CurrentPath: "/b"},
+				{Index: 1, CurrentPath: "/c"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	var splits []string
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "split-window") {
+			splits = append(splits, line)
+		}
+	}
+
+	want := []string{
+		"split-window -d -P -F #{pane_id} -h -t %0 -l 30% -c /b",
+		"split-window -d -P -F #{pane_id} -v -t %0 -l 48% -c /c",
+		"split-window -d -P -F #{pane_id} -v -t %1 -l 20% -c /d",
+	}
+	if strings.Join(splits, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected splits:\n%s", strings.Join(splits, "\n"))
+	}
+}
+
+func TestRestoreSessionFallsBackWhenLayoutSplitFails(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+case "$*" in
+has-session*) exit 1 ;;
+list-windows*) echo "0"; exit 0 ;;
+display-message*) echo "%0"; exit 0 ;;
+list-panes*) echo "%0"; exit 0 ;;
+*"-P -F"*) echo "no space for new pane" >&2; exit 1 ;;
+esac
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	err := NewClient(fake).RestoreSession(snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows: []snapshot.Window{{
+			Index:  0,
+			Name:   "work",
+			Layout: "c2c3,80x24,0,0{40x24,0,0,1,39x24,41,0,2}",
+			Panes:  []snapshot.Pane{{Index: 0, CurrentPath: "/a"}, {Index: 1, CurrentPath: "/b"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	out := string(b)
+	if !strings.Contains(out, "split-window -d -t =demo:0 -c /b") ||
+		!strings.Contains(out, "select-layout -t =demo:0 c2c3,80x24,0,0{40x24,0,0,1,39x24,41,0,2}") {
+		t.Fatalf("expected plain split fallback, got:\n%s", out)
+	}
+}
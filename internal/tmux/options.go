@@ -0,0 +1,177 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// OptionNames lists the session and window options captured with a snapshot.
+// Only values set on the session or window itself are recorded, not
+// inherited global ones.
+type OptionNames struct {
+	Session []string
+	Window  []string
+}
+
+// DefaultOptionNames are captured unless configured otherwise.
+func DefaultOptionNames() OptionNames {
+	return OptionNames{
+		Session: []string{"default-command"},
+		Window:  []string{"synchronize-panes", "remain-on-exit", "automatic-rename"},
+	}
+}
+
+// ValidateOptionName reports names that cannot be a tmux option.
+func ValidateOptionName(name string) error {
+	if name == "" || strings.Trim(name, "@-_abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+		return fmt.Errorf("invalid tmux option name %q", name)
+	}
+
+	return nil
+}
+
+// SetOptionNames sets which options CaptureSession records.
+func (c *Client) SetOptionNames(names OptionNames) {
+	c.options = names
+}
+
+// captureOptions returns the options in names set locally on target, read
+// with a single show-options call per scope.
+func (c *Client) captureOptions(scope, target string, names []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	listed, err := c.Output(scopedArgs("show-options", scope, "-q", "-t", target)...)
+	if err != nil {
+		return nil
+	}
+
+	var out map[string]string
+
+	for _, line := range splitLines(listed) {
+		name, value, ok := strings.Cut(line, " ")
+		if !ok || !slices.Contains(names, name) {
+			continue
+		}
+
+		value = unquoteOptionValue(value)
+		if value == "" {
+			continue
+		}
+
+		if out == nil {
+			out = make(map[string]string, len(names))
+		}
+
+		out[name] = value
+	}
+
+	return out
+}
+
+// unquoteOptionValue undoes the quoting show-options applies to values:
+// surrounding single or double quotes and C-style or octal backslash escapes.
+func unquoteOptionValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		i++
+
+		switch ch := value[i]; {
+		case ch >= '0' && ch <= '7' && i+2 < len(value):
+			if n, err := strconv.ParseUint(value[i:i+3], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 2
+
+				continue
+			}
+
+			b.WriteByte(ch)
+		case ch == 'n':
+			b.WriteByte('\n')
+		case ch == 't':
+			b.WriteByte('\t')
+		case ch == 'r':
+			b.WriteByte('\r')
+		case ch == 's':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(ch)
+		}
+	}
+
+	return b.String()
+}
+
+// restoreOptions sets options on target in name order. Options the running
+// tmux rejects are skipped.
+func (c *Client) restoreOptions(scope, target string, options map[string]string) {
+	for _, name := range sortedKeys(options) {
+		_, _ = c.Output(scopedArgs("set-option", scope, "-t", target, name, options[name])...)
+	}
+}
+
+// scopedArgs builds an option command; scope is "" for session options or
+// "-w" for window options.
+func scopedArgs(command, scope string, args ...string) []string {
+	out := []string{command}
+	if scope != "" {
+		out = append(out, scope)
+	}
+
+	return append(out, args...)
+}
+
+// defaultPaneTitle reports whether title is the one tmux gives new panes,
+// the hostname, so it is not worth restoring.
+func defaultPaneTitle(title string) bool {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return false
+	}
+
+	short, _, _ := strings.Cut(host, ".")
+
+	return title == host || title == short
+}
+
+// restoreWindowState reapplies window options and pane titles, marks and zoom
+// once the window has its final layout.
+func (c *Client) restoreWindowState(sessionName string, window snapshot.Window, windowIndex int) {
+	c.restoreOptions("-w", sessionWindowTarget(sessionName, windowIndex), window.Options)
+
+	for _, pane := range window.Panes {
+		target := sessionPaneTarget(sessionName, windowIndex, pane.Index)
+
+		if pane.Title != "" {
+			_, _ = c.Output("select-pane", "-t", target, "-T", pane.Title)
+		}
+
+		if pane.Marked {
+			_, _ = c.Output("select-pane", "-m", "-t", target)
+		}
+	}
+
+	if window.Zoomed && len(window.Panes) > 1 {
+		_, _ = c.Output("resize-pane", "-Z", "-t", sessionPaneTarget(sessionName, windowIndex, window.ActivePane))
+	}
+}
@@ -0,0 +1,233 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func TestCaptureSessionRecordsOptionsAndPaneState(t *testing.T) {
+	fake := writeFakeTmux(t, `
+case "$1" in
+has-session) exit 0 ;;
+display-message) printf "1\0371\n"; exit 0 ;;
+list-windows) printf "1\037logs\037tiled\0371\0371\n"; exit 0 ;;
+list-panes)
+  printf "0\037/var/log\037zsh\0370\037\037\0370\037\n"
+  printf "1\037/var/log\037zsh\0371\037\037\0371\037tail: app.log\n"
+  exit 0 ;;
+show-environment)
+  printf "PATH=/opt/proj/bin:/usr/bin\nAWS_SECRET_ACCESS_KEY=nope\n-SSH_AUTH_SOCK\nKUBECONFIG=/home/a/.kube/proj\n"
+  exit 0 ;;
+show-options)
+  if [ "$#" -ne 5 ] && [ "$#" -ne 4 ]; then echo "too many arguments" >&2; exit 1; fi
+  if [ "$2" = "-w" ]; then printf 'synchronize-panes on\nmonitor-activity on\n'; exit 0; fi
+  printf '%s\n' 'default-command "exec zsh -l"' '@note "it'"'"'s \"q\" \$HOME"' 'status off'
+  exit 0 ;;
+esac
+exit 0
+`)
+
+	client := NewClient(fake)
+	client.SetOptionNames(OptionNames{
+		Session: []string{"default-command"},
+		Window:  []string{"synchronize-panes"},
+	})
+
+	snap, err := client.CaptureSession("demo")
+	if err != nil {
+		t.Fatalf("CaptureSession error: %v", err)
+	}
+
+	if snap.Options["default-command"] != "exec zsh -l" || len(snap.Options) != 1 {
+		t.Fatalf("unexpected session options: %v", snap.Options)
+	}
+
+	if len(snap.Environment) != 2 || snap.Environment["PATH"] != "/opt/proj/bin:/usr/bin" ||
+		snap.Environment["KUBECONFIG"] != "/home/a/.kube/proj" {
+		t.Fatalf("expected allowlisted environment only, got %v", snap.Environment)
+	}
+
+	win := snap.Windows[0]
+	if !win.Zoomed || win.Options["synchronize-panes"] != "on" || len(win.Options) != 1 {
+		t.Fatalf("unexpected window state: %+v", win)
+	}
+
+	if win.Panes[0].Marked || win.Panes[0].Title != "" {
+		t.Fatalf("unexpected first pane: %+v", win.Panes[0])
+	}
+
+	if !win.Panes[1].Marked || win.Panes[1].Title != "tail: app.log" {
+		t.Fatalf("unexpected second pane: %+v", win.Panes[1])
+	}
+}
+
+func TestRestoreSessionReappliesOptionsAfterLayout(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+if [ "$1" = "list-windows" ]; then
+  echo "0"
+  exit 0
+fi
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	err := NewClient(fake).RestoreSession(snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Options:     map[string]string{"default-command": "zsh -l"},
+		Environment: map[string]string{"PATH": "/opt/bin", "KUBECONFIG": "/k"},
+		Windows: []snapshot.Window{{
+			Index:      0,
+			Name:       "logs",
+			Layout:     "tiled",
+			ActivePane: 1,
+			Zoomed:     true,
+			Options:    map[string]string{"synchronize-panes": "on", "automatic-rename": "off"},
+			Panes: []snapshot.Pane{
+				{Index: 0, CurrentPath: "/var/log"},
+				{Index: 1, CurrentPath: "/var/log", Title: "tail", Marked: true},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	out := string(b)
+	order := []string{
+		"new-session -d -s demo -n logs -c /var/log -e KUBECONFIG=/k -e PATH=/opt/bin",
+		"set-option -t =demo: default-command zsh -l",
+		"split-window -d -t =demo:0 -c /var/log",
+		"select-layout -t =demo:0 tiled",
+		"set-option -w -t =demo:0 automatic-rename off",
+		"set-option -w -t =demo:0 synchronize-panes on",
+		"select-pane -t =demo:0.1 -T tail",
+		"select-pane -m -t =demo:0.1",
+		"resize-pane -Z -t =demo:0.1",
+	}
+
+	last := -1
+	for _, cmd := range order {
+		idx := strings.Index(out, cmd)
+		if idx <= last {
+			t.Fatalf("expected %q after the previous commands, got:\n%s", cmd, out)
+		}
+
+		last = idx
+	}
+
+	if strings.Contains(out, "select-pane -t =demo:0.0\n") {
+		t.Fatalf("expected the zoomed window not to be unzoomed by selecting a pane, got:\n%s", out)
+	}
+}
+
+func TestRestoreSessionSetsEnvironmentWithoutNewSessionE(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+case "$*" in
+has-session*) exit 1 ;;
+*" -e "*) echo "unknown flag -e" >&2; exit 1 ;;
+list-windows*) echo "0"; exit 0 ;;
+esac
+exit 0
+`)
+
+	t.Setenv("TMUX_LOG", logPath)
+
+	err := NewClient(fake).RestoreSession(snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Environment: map[string]string{"KUBECONFIG": "/k"},
+		Windows:     []snapshot.Window{{Index: 0, Name: "sh", Panes: []snapshot.Pane{{Index: 0, CurrentPath: "/tmp"}}}},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	out := string(b)
+	if !strings.Contains(out, "new-session -d -s demo -n sh -c /tmp\n") ||
+		!strings.Contains(out, "set-environment -t =demo: KUBECONFIG /k") {
+		t.Fatalf("expected fallback to set-environment, got:\n%s", out)
+	}
+}
+
+func TestUnquoteOptionValue(t *testing.T) {
+	cases := map[string]string{
+		`plain`:               "plain",
+		`''`:                  "",
+		`"it's \"q\" \$HOME"`: `it's "q" $HOME`,
+		`'a"b'`:               `a"b`,
+		`\~home`:              "~home",
+		`tab\tx`:              "tab\tx",
+		`back\\slash`:         `back\slash`,
+		`x\001y`:              "x\001y",
+		`"#{pane_id};x"`:      "#{pane_id};x",
+	}
+
+	for in, want := range cases {
+		if got := unquoteOptionValue(in); got != want {
+			t.Fatalf("unquoteOptionValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCaptureSessionSkipsHostnamePaneTitle(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		t.Skip("no hostname")
+	}
+
+	fake := writeFakeTmux(t, `
+case "$1" in
+has-session) exit 0 ;;
+display-message) printf "0\0370\n"; exit 0 ;;
+list-windows) printf "0\037sh\037tiled\0371\n"; exit 0 ;;
+list-panes) printf "0\037/tmp\037sh\0371\037\0370\037%s\n" "$PANE_TITLE"; exit 0 ;;
+esac
+exit 0
+`)
+
+	t.Setenv("PANE_TITLE", host)
+
+	snap, err := NewClient(fake).CaptureSession("demo")
+	if err != nil {
+		t.Fatalf("CaptureSession error: %v", err)
+	}
+
+	if title := snap.Windows[0].Panes[0].Title; title != "" {
+		t.Fatalf("expected the default hostname title to be dropped, got %q", title)
+	}
+}
+
+func TestValidateOptionName(t *testing.T) {
+	for _, ok := range []string{"remain-on-exit", "@my_option"} {
+		if err := ValidateOptionName(ok); err != nil {
+			t.Fatalf("unexpected error for %q: %v", ok, err)
+		}
+	}
+
+	for _, bad := range []string{"", "status left", "Mouse"} {
+		if err := ValidateOptionName(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
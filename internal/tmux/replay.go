@@ -0,0 +1,169 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+// Scrollback replay modes select how saved scrollback is shown again in a
+// restored pane.
+const (
+	// ReplayTTY writes the scrollback straight to the pane's tty. It needs
+	// the tty to be writable by lazy-tmux, which fails when the tmux server
+	// runs as another user or in a container.
+	ReplayTTY = "tty"
+	// ReplayWrapper hands the scrollback to the tmux server with load-buffer
+	// and starts the pane with a small sh wrapper that prints the buffer,
+	// deletes it and runs the pane's default-command or default-shell. Panes
+	// whose wrapper does not pick its buffer up fall back to ReplayTTY.
+	ReplayWrapper = "wrapper"
+	// ReplayNone restores panes without their scrollback.
+	ReplayNone = "none"
+
+	DefaultReplay = ReplayTTY
+)
+
+// tmux paste-buffer is no replay mode: it writes the buffer to the pane's
+// input, not its output, so the shell reads the saved lines and runs them as
+// commands (or, with bracketed paste, leaves them on its command line).
+// Only the load-buffer half is used, to move scrollback to the server.
+
+// replayClaimTimeout bounds how long a restore waits for wrapper panes to
+// pick up their buffers before writing the scrollback to their ttys.
+var replayClaimTimeout = time.Second
+
+// replaySeq numbers the replay buffers of this process.
+var replaySeq atomic.Uint64
+
+// replayScript runs in a restored pane. It claims its buffer by renaming it,
+// so a restore that gave up waiting cannot replay the same scrollback again,
+// prints and deletes it, then starts what tmux would have started.
+const replayScript = `b=$1; ` +
+	`if tmux set-buffer -b "$b" -n "$b.claimed" 2>/dev/null; then ` +
+	`tmux save-buffer -b "$b.claimed" -; tmux delete-buffer -b "$b.claimed"; fi; ` +
+	`cmd=$(tmux display-message -p -t "$TMUX_PANE" '#{default-command}' 2>/dev/null); ` +
+	`sh=$(tmux display-message -p -t "$TMUX_PANE" '#{default-shell}' 2>/dev/null); ` +
+	`[ -n "$sh" ] || sh=${SHELL:-/bin/sh}; ` +
+	`[ -z "$cmd" ] || exec "$sh" -c "$cmd"; ` +
+	`exec "$sh" -l`
+
+// ParseReplay validates a scrollback replay mode; "" selects DefaultReplay.
+func ParseReplay(mode string) (string, error) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "":
+		return DefaultReplay, nil
+	case ReplayTTY, ReplayWrapper, ReplayNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid scrollback replay mode %q (want tty|wrapper|none)", mode)
+	}
+}
+
+// SetScrollbackReplay sets how restored panes replay saved scrollback.
+func (c *Client) SetScrollbackReplay(mode string) {
+	c.replay = mode
+}
+
+// replayCommand loads the scrollback of pane into a tmux buffer and returns
+// the command that starts the pane replaying it in wrapper mode, and the
+// buffer. It returns "" when the pane has nothing to replay that way.
+func (c *Client) replayCommand(pane snapshot.Pane) (string, string) {
+	if c.replay != ReplayWrapper || !hasScrollback(pane) {
+		return "", ""
+	}
+
+	buffer := fmt.Sprintf("lazy-tmux-replay-%d-%d", os.Getpid(), replaySeq.Add(1))
+
+	// The content goes over the tmux socket, so it reaches servers running
+	// as another user or in a container, and never touches a shared /tmp.
+	if _, err := c.outputWithInput(pane.Scrollback.Content, "load-buffer", "-b", buffer, "-"); err != nil {
+		c.logger.Debug("scrollback replay buffer", "error", err)
+		return "", ""
+	}
+
+	// tmux runs the command with its default-shell, which need not be sh.
+	return "sh -c " + shellQuote(replayScript) + " lazy-tmux " + shellQuote(buffer), buffer
+}
+
+// newPane runs a command that creates a pane for pane. In wrapper mode the
+// pane starts by replaying its scrollback from the returned buffer.
+// Otherwise the pane gets the default shell like runWithShellFallback.
+func (c *Client) newPane(args []string, pane snapshot.Pane) (out string, buffer string, err error) {
+	if cmd, buffer := c.replayCommand(pane); cmd != "" {
+		withCmd := append(append([]string(nil), args...), cmd)
+		if out, err := c.Output(withCmd...); err == nil {
+			return out, buffer, nil
+		}
+
+		_, _ = c.Output("delete-buffer", "-b", buffer)
+	}
+
+	out, err = c.runWithShellFallback(args, "")
+
+	return out, "", err
+}
+
+// unclaimedReplays waits up to replayClaimTimeout for the wrapper panes to
+// claim their buffers and returns the panes that did not. Their buffers are
+// deleted, so a late wrapper finds nothing and the caller may write the
+// scrollback to their ttys instead.
+func (c *Client) unclaimedReplays(buffers map[int]string) map[int]bool {
+	if len(buffers) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(replayClaimTimeout)
+
+	for {
+		out, err := c.Output("list-buffers", "-F", "#{buffer_name}")
+		if err != nil {
+			break
+		}
+
+		waiting := false
+
+		for _, name := range splitLines(out) {
+			for _, buffer := range buffers {
+				waiting = waiting || name == buffer
+			}
+		}
+
+		if !waiting || !time.Now().Before(deadline) {
+			break
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	var unclaimed map[int]bool
+
+	for index, buffer := range buffers {
+		// Only a buffer no wrapper claimed can still be deleted.
+		if _, err := c.Output("delete-buffer", "-b", buffer); err != nil {
+			continue
+		}
+
+		c.logger.Debug("scrollback replay not picked up, writing to tty", "pane", index)
+
+		if unclaimed == nil {
+			unclaimed = map[int]bool{}
+		}
+
+		unclaimed[index] = true
+	}
+
+	return unclaimed
+}
+
+func hasScrollback(pane snapshot.Pane) bool {
+	return pane.Scrollback != nil && strings.TrimSpace(pane.Scrollback.Content) != ""
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,229 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alchemmist/lazy-tmux/internal/snapshot"
+)
+
+func replaySnapshot() snapshot.SessionSnapshot {
+	return snapshot.SessionSnapshot{
+		SessionName: "demo",
+		Windows: []snapshot.Window{{
+			Index:  0,
+			Name:   "shell",
+			Layout: "even-horizontal",
+			Panes: []snapshot.Pane{
+				{Index: 0, CurrentCmd: "zsh", Scrollback: &snapshot.ScrollbackRef{Content: "first pane\n"}},
+				{Index: 1, CurrentCmd: "zsh", Scrollback: &snapshot.ScrollbackRef{Content: "it's the second\n"}},
+			},
+		}},
+	}
+}
+
+func stubPaneTTYWriter(t *testing.T) *[]string {
+	t.Helper()
+
+	var written []string
+
+	orig := paneTTYWriter
+	paneTTYWriter = func(path, content string) error {
+		written = append(written, content)
+		return nil
+	}
+
+	t.Cleanup(func() { paneTTYWriter = orig })
+
+	return &written
+}
+
+func TestRestoreSessionReplaysScrollbackThroughWrapper(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	t.Setenv("TMUX_LOG", logPath)
+
+	written := stubPaneTTYWriter(t)
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+if [ "$1" = "load-buffer" ]; then
+  cat >> "$TMUX_LOG"
+fi
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+if [ "$1" = "list-windows" ]; then
+  echo "0"
+fi
+if [ "$1" = "display-message" ]; then
+  echo "/dev/pts/42"
+fi
+if [ "$1" = "delete-buffer" ]; then
+  echo "unknown buffer: $3" >&2
+  exit 1
+fi
+exit 0
+`)
+
+	client := NewClient(fake)
+	client.SetScrollbackReplay(ReplayWrapper)
+
+	if err := client.RestoreSession(replaySnapshot()); err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	if len(*written) != 0 {
+		t.Fatalf("expected no tty writes, got %q", *written)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	out := string(b)
+	for _, want := range []string{"first pane\n", "it's the second\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q to be loaded into a buffer, got:\n%s", want, out)
+		}
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(os.TempDir(), "lazy-tmux-scrollback-*")); len(files) != 0 {
+		t.Fatalf("expected no replay files, got %v", files)
+	}
+
+	for _, prefix := range []string{"new-session -d -s demo -n shell", "split-window -d -t =demo:0"} {
+		started := false
+
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, prefix) && strings.Contains(line, "sh -c 'b=$1") &&
+				strings.Contains(line, "lazy-tmux 'lazy-tmux-replay-") {
+				started = true
+			}
+		}
+
+		if !started {
+			t.Fatalf("expected %q to start the wrapper, got:\n%s", prefix, out)
+		}
+	}
+}
+
+func TestRestoreSessionFallsBackToTTYWhenWrapperFails(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tmux.log")
+	t.Setenv("TMUX_LOG", logPath)
+
+	written := stubPaneTTYWriter(t)
+	fake := writeFakeTmux(t, `
+echo "$*" >> "$TMUX_LOG"
+case "$*" in
+  *"sh -c"*) exit 1 ;;
+esac
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+if [ "$1" = "list-windows" ]; then
+  echo "0"
+fi
+if [ "$1" = "display-message" ]; then
+  echo "/dev/pts/42"
+fi
+exit 0
+`)
+
+	client := NewClient(fake)
+	client.SetScrollbackReplay(ReplayWrapper)
+
+	if err := client.RestoreSession(replaySnapshot()); err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	if strings.Join(*written, "") != "first pane\nit's the second\n" {
+		t.Fatalf("expected tty fallback for both panes, got %q", *written)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+
+	if got := strings.Count(string(b), "delete-buffer -b lazy-tmux-replay-"); got != 2 {
+		t.Fatalf("expected both replay buffers to be deleted, got %d in:\n%s", got, b)
+	}
+}
+
+func TestRestoreSessionFallsBackToTTYWhenReplayIsNotPickedUp(t *testing.T) {
+	orig := replayClaimTimeout
+	replayClaimTimeout = 50 * time.Millisecond
+
+	t.Cleanup(func() { replayClaimTimeout = orig })
+
+	buffers := filepath.Join(t.TempDir(), "buffers")
+	t.Setenv("TMUX_BUFFERS", buffers)
+
+	written := stubPaneTTYWriter(t)
+	// The panes start, but their wrapper never claims its buffer.
+	fake := writeFakeTmux(t, `
+case "$1" in
+has-session) exit 1 ;;
+list-windows) echo "0" ;;
+display-message) echo "/dev/pts/42" ;;
+load-buffer) cat >/dev/null; echo "$3" >> "$TMUX_BUFFERS" ;;
+list-buffers) cat "$TMUX_BUFFERS" ;;
+delete-buffer) grep -qx -- "$3" "$TMUX_BUFFERS" ;;
+esac
+exit 0
+`)
+
+	client := NewClient(fake)
+	client.SetScrollbackReplay(ReplayWrapper)
+
+	if err := client.RestoreSession(replaySnapshot()); err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	if strings.Join(*written, "") != "first pane\nit's the second\n" {
+		t.Fatalf("expected tty fallback for both panes, got %q", *written)
+	}
+}
+
+func TestRestoreSessionSkipsScrollbackWithReplayNone(t *testing.T) {
+	written := stubPaneTTYWriter(t)
+	fake := writeFakeTmux(t, `
+if [ "$1" = "has-session" ]; then
+  exit 1
+fi
+if [ "$1" = "list-windows" ]; then
+  echo "0"
+fi
+if [ "$1" = "display-message" ]; then
+  echo "/dev/pts/42"
+fi
+exit 0
+`)
+
+	client := NewClient(fake)
+	client.SetScrollbackReplay(ReplayNone)
+
+	if err := client.RestoreSession(replaySnapshot()); err != nil {
+		t.Fatalf("RestoreSession error: %v", err)
+	}
+
+	if len(*written) != 0 {
+		t.Fatalf("expected no replay, got %q", *written)
+	}
+}
+
+func TestParseReplay(t *testing.T) {
+	for in, want := range map[string]string{"": ReplayTTY, "Wrapper": ReplayWrapper, " none ": ReplayNone} {
+		got, err := ParseReplay(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseReplay(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseReplay("buffer"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,145 @@
+package tmux
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Server identifies a tmux server by socket name (tmux -L) or socket path
+// (tmux -S). The zero value lets tmux pick the server: the one in $TMUX, or
+// the default one.
+type Server struct {
+	SocketName string
+	SocketPath string
+}
+
+func (s Server) IsDefault() bool {
+	return s.SocketPath == "" && (s.SocketName == "" || s.SocketName == "default")
+}
+
+// Args returns the tmux flags selecting the server.
+func (s Server) Args() []string {
+	switch {
+	case s.SocketPath != "":
+		return []string{"-S", s.SocketPath}
+	case s.SocketName != "":
+		return []string{"-L", s.SocketName}
+	default:
+		return nil
+	}
+}
+
+// String returns a short label: the socket name, the socket path or
+// "default".
+func (s Server) String() string {
+	switch {
+	case s.SocketPath != "":
+		return s.SocketPath
+	case !s.IsDefault():
+		return s.SocketName
+	default:
+		return "default"
+	}
+}
+
+// ID returns a file name identifying the server: the socket name, or the
+// escaped socket path (which starts with "%"). The default server has no ID.
+func (s Server) ID() string {
+	switch {
+	case s.SocketPath != "":
+		return url.PathEscape(s.SocketPath)
+	case !s.IsDefault():
+		return s.SocketName
+	default:
+		return ""
+	}
+}
+
+// ParseServer reverses Server.String.
+func ParseServer(label string) Server {
+	switch label = strings.TrimSpace(label); {
+	case label == "":
+		return Server{}
+	case filepath.IsAbs(label):
+		return Server{SocketPath: label}
+	default:
+		return Server{SocketName: label}
+	}
+}
+
+// ServerFromID reverses Server.ID.
+func ServerFromID(id string) (Server, error) {
+	if strings.HasPrefix(id, "%") {
+		path, err := url.PathUnescape(id)
+		if err != nil {
+			return Server{}, fmt.Errorf("invalid tmux server id %q: %w", id, err)
+		}
+
+		return Server{SocketPath: path}, nil
+	}
+
+	return NewServer(id, "")
+}
+
+// NewServer validates a socket name or path as given on the command line.
+// Relative socket paths are made absolute.
+func NewServer(socketName, socketPath string) (Server, error) {
+	socketName = strings.TrimSpace(socketName)
+	socketPath = strings.TrimSpace(socketPath)
+
+	if socketName != "" && socketPath != "" {
+		return Server{}, fmt.Errorf("use either a tmux socket name or a socket path, not both")
+	}
+
+	if socketName != "" && (strings.ContainsRune(socketName, os.PathSeparator) || strings.HasPrefix(socketName, "%") ||
+		socketName == "." || socketName == "..") {
+		return Server{}, fmt.Errorf("invalid tmux socket name %q", socketName)
+	}
+
+	if socketPath != "" {
+		abs, err := filepath.Abs(socketPath)
+		if err != nil {
+			return Server{}, fmt.Errorf("resolve tmux socket path: %w", err)
+		}
+
+		socketPath = abs
+	}
+
+	return Server{SocketName: socketName, SocketPath: socketPath}, nil
+}
+
+// ServerFromEnv returns the server of the tmux client lazy-tmux runs in, from
+// $TMUX. Sockets in tmux's default socket directory map to their -L name.
+func ServerFromEnv() Server {
+	env := strings.TrimSpace(os.Getenv("TMUX"))
+	if env == "" {
+		return Server{}
+	}
+
+	path, _, _ := strings.Cut(env, ",")
+	if path == "" {
+		return Server{}
+	}
+
+	if filepath.Dir(path) == defaultSocketDir() {
+		if name := filepath.Base(path); name != "default" {
+			return Server{SocketName: name}
+		}
+
+		return Server{}
+	}
+
+	return Server{SocketPath: path}
+}
+
+func defaultSocketDir() string {
+	tmpDir := os.Getenv("TMUX_TMPDIR")
+	if tmpDir == "" {
+		tmpDir = "/tmp"
+	}
+
+	return filepath.Join(tmpDir, fmt.Sprintf("tmux-%d", os.Getuid()))
+}
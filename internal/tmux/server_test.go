@@ -0,0 +1,115 @@
+package tmux
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerArgsAndIDs(t *testing.T) {
+	cases := []struct {
+		server Server
+		args   string
+		label  string
+		id     string
+	}{
+		{Server{}, "[]", "default", ""},
+		{Server{SocketName: "default"}, "[-L default]", "default", ""},
+		{Server{SocketName: "work"}, "[-L work]", "work", "work"},
+		{Server{SocketPath: "/run/tmux/a b"}, "[-S /run/tmux/a b]", "/run/tmux/a b", "%2Frun%2Ftmux%2Fa%20b"},
+	}
+
+	for _, tc := range cases {
+		if got := fmt.Sprint(tc.server.Args()); got != tc.args {
+			t.Fatalf("%+v: unexpected args %s", tc.server, got)
+		}
+
+		if got := tc.server.String(); got != tc.label {
+			t.Fatalf("%+v: unexpected label %q", tc.server, got)
+		}
+
+		if got := tc.server.ID(); got != tc.id {
+			t.Fatalf("%+v: unexpected id %q", tc.server, got)
+		}
+
+		if tc.id == "" {
+			continue
+		}
+
+		back, err := ServerFromID(tc.id)
+		if err != nil || back.ID() != tc.id {
+			t.Fatalf("ServerFromID(%q) = %+v, %v", tc.id, back, err)
+		}
+
+		if got := ParseServer(tc.label); got != tc.server {
+			t.Fatalf("ParseServer(%q) = %+v", tc.label, got)
+		}
+	}
+}
+
+func TestNewServerValidates(t *testing.T) {
+	if _, err := NewServer("work", "/tmp/sock"); err == nil {
+		t.Fatal("expected error for name and path together")
+	}
+
+	for _, bad := range []string{"a/b", "%2F", ".", ".."} {
+		if _, err := NewServer(bad, ""); err == nil {
+			t.Fatalf("expected error for socket name %q", bad)
+		}
+	}
+
+	server, err := NewServer("", "sock")
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+
+	if !filepath.IsAbs(server.SocketPath) {
+		t.Fatalf("expected absolute socket path, got %q", server.SocketPath)
+	}
+}
+
+func TestServerFromEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMUX_TMPDIR", tmpDir)
+
+	socketDir := defaultSocketDir()
+	cases := map[string]Server{
+		"": {},
+		filepath.Join(socketDir, "default") + ",1,0": {},
+		filepath.Join(socketDir, "work") + ",1,0":    {SocketName: "work"},
+		"/run/user/tmux.sock,42,3":                   {SocketPath: "/run/user/tmux.sock"},
+	}
+
+	for env, want := range cases {
+		t.Setenv("TMUX", env)
+
+		if got := ServerFromEnv(); got != want {
+			t.Fatalf("ServerFromEnv with TMUX=%q = %+v, want %+v", env, got, want)
+		}
+	}
+}
+
+func TestClientPrependsServerArgs(t *testing.T) {
+	fake := writeFakeTmux(t, `
+if [ "$1" = "-L" ] && [ "$2" = "work" ] && [ "$3" = "list-sessions" ]; then
+  echo alpha
+  exit 0
+fi
+echo "unexpected args: $*" >&2
+exit 1
+`)
+
+	client := NewClientForServer(fake, Server{SocketName: "work"})
+	if client.Server().SocketName != "work" {
+		t.Fatalf("unexpected server: %+v", client.Server())
+	}
+
+	got, err := client.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions error: %v", err)
+	}
+
+	if fmt.Sprint(got) != "[alpha]" {
+		t.Fatalf("unexpected sessions: %v", got)
+	}
+}